@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"volcengine-go-server/api/middleware"
+	"volcengine-go-server/config"
+	"volcengine-go-server/internal/core"
+	"volcengine-go-server/internal/models"
+	"volcengine-go-server/internal/service"
+	"volcengine-go-server/internal/util"
+	"volcengine-go-server/pkg/logger"
+)
+
+// BatchTaskItem 批量提交中的单个任务，type指定任务类型，其余字段与单个任务创建接口一致
+type BatchTaskItem struct {
+	Type string `json:"type" binding:"required,oneof=image text video"`
+	AITaskRequest
+}
+
+// BatchTaskRequest 批量提交任务请求
+type BatchTaskRequest struct {
+	Tasks []*BatchTaskItem `json:"tasks" binding:"required,min=1,dive"`
+}
+
+// 批量提交任务
+// 一次扣除全部积分，子任务通过一次InsertMany写入后并发入队，入队失败的子任务标记为失败并退还积分
+func (h *AIHandler) CreateBatch(c *gin.Context) {
+	user, ok := middleware.CurrentUser(c)
+	if !ok {
+		util.ForbiddenResponse(c, "无法创建任务", "请使用用户API密钥创建任务")
+		return
+	}
+
+	var req BatchTaskRequest
+	if errors := util.ValidateRequest(c, &req); len(errors) > 0 {
+		util.ValidationErrorResponse(c, errors)
+		return
+	}
+	if len(req.Tasks) > config.MaxBatchSize {
+		util.BadRequestResponse(c, "批量任务数量超出限制", fmt.Sprintf("单次最多提交%d个任务", config.MaxBatchSize))
+		return
+	}
+
+	// 任一子任务参数无效时整批拒绝，不扣除积分
+	now := time.Now()
+	for i, item := range req.Tasks {
+		item.UserID = user.ID
+		item.UserTier = user.Tier
+		msg, detail := item.check(now)
+		var capability models.ModelCapability
+		if msg == "" {
+			capability, msg, detail = h.checkModel(AITaskType(item.Type), &item.AITaskRequest)
+		}
+		if msg != "" {
+			util.BadRequestResponse(c, fmt.Sprintf("tasks[%d]: %s", i, msg), detail)
+			return
+		}
+		if !checkCallbackURL(c, fmt.Sprintf("tasks[%d]: ", i), item.CallbackURL) {
+			return
+		}
+		if errs := service.ValidateModelInput(capability, item.modelInput()); len(errs) > 0 {
+			for j := range errs {
+				errs[j].Field = fmt.Sprintf("tasks[%d].%s", i, errs[j].Field)
+			}
+			util.ValidationErrorResponse(c, errs)
+			return
+		}
+	}
+
+	h.withIdempotency(c, user.ID, idempotencyRequestHash("batch", &req), func() {
+		h.handleBatchCreation(c, user.ID, &req)
+	})
+}
+
+// handleBatchCreation 扣除积分、创建批量任务并入队全部子任务
+func (h *AIHandler) handleBatchCreation(c *gin.Context, userID string, req *BatchTaskRequest) {
+	ctx := c.Request.Context()
+
+	// 上传的原图先转存，任一原图无效时整批拒绝，不扣除积分
+	for i := range req.Tasks {
+		if err := h.storeSourceImage(ctx, &req.Tasks[i].AITaskRequest); err != nil {
+			respondSourceImageError(c, fmt.Sprintf("tasks[%d]: ", i), err)
+			return
+		}
+	}
+
+	inputs := make([]*models.TaskInput, len(req.Tasks))
+	var totalCost int64
+	for i, item := range req.Tasks {
+		input := newTaskInput(AITaskType(item.Type), &item.AITaskRequest)
+		input.Cost = service.CalculateTaskCost(input.Type, input.Model, input.N)
+		totalCost += input.Cost
+		inputs[i] = input
+	}
+
+	if err := h.creditService.Charge(ctx, userID, totalCost); err != nil {
+		if errors.Is(err, service.ErrInsufficientCredits) {
+			util.ErrorResponse(c, http.StatusPaymentRequired, "积分不足", fmt.Sprintf("本批任务共需要%d积分", totalCost))
+		} else {
+			util.InternalServerErrorResponse(c, "扣除积分失败", err.Error())
+		}
+		return
+	}
+
+	batch, tasks, err := h.batchService.CreateBatch(ctx, userID, inputs)
+	if err != nil {
+		h.creditService.Refund(ctx, userID, totalCost)
+		util.InternalServerErrorResponse(c, "创建批量任务失败", err.Error())
+		return
+	}
+
+	items := make([]*core.QueueItem, len(tasks))
+	for i, task := range tasks {
+		item := req.Tasks[i]
+		queueTaskType, payload := newTaskPayload(AITaskType(item.Type), &item.AITaskRequest, task)
+		items[i] = &core.QueueItem{
+			TaskType: queueTaskType,
+			Payload:  payload,
+			Opts:     item.queueOptions(),
+		}
+	}
+	enqueueErrs := h.queueService.EnqueueTasks(ctx, items)
+
+	results := make([]gin.H, len(tasks))
+	failed := 0
+	for i, task := range tasks {
+		result := withSchedule(gin.H{
+			"index":   i,
+			"task_id": task.ID,
+			"type":    task.Type,
+			"status":  task.Status,
+			"cost":    task.Cost,
+		}, task)
+
+		if enqueueErrs[i] != nil {
+			// 入队失败的子任务标记为失败，积分随失败回写退还
+			errorMsg := "任务入队失败: " + enqueueErrs[i].Error()
+			if err := h.taskService.UpdateTaskError(ctx, task.ID, errorMsg); err != nil {
+				logger.GetLogger().Errorf("更新批量子任务状态失败: %s, %v", task.ID, err)
+			}
+			result["status"] = config.TaskStatusFailed
+			result["error"] = errorMsg
+			failed++
+		}
+		results[i] = result
+	}
+
+	util.CreatedResponse(c, gin.H{
+		"batch_id": batch.ID,
+		"total":    len(tasks),
+		"queued":   len(tasks) - failed,
+		"failed":   failed,
+		"cost":     batch.Cost,
+		"tasks":    results,
+	}, "批量任务创建成功")
+}
+
+// 查询批量任务的进度和全部子任务结果
+func (h *AIHandler) GetBatch(c *gin.Context) {
+	batchID := c.Param("batch_id")
+	ctx := c.Request.Context()
+
+	batch, err := h.batchService.GetBatch(ctx, batchID)
+	if err != nil {
+		if errors.Is(err, service.ErrBatchNotFound) {
+			util.NotFoundResponse(c, "批量任务不存在", "批量任务ID: "+batchID)
+		} else {
+			util.InternalServerErrorResponse(c, "获取批量任务失败", err.Error())
+		}
+		return
+	}
+	if !middleware.CanAccessUser(c, batch.UserID) {
+		util.NotFoundResponse(c, "批量任务不存在", "批量任务ID: "+batchID)
+		return
+	}
+
+	tasks, err := h.batchService.GetBatchTasks(ctx, batch)
+	if err != nil {
+		util.InternalServerErrorResponse(c, "获取批量子任务失败", err.Error())
+		return
+	}
+
+	results := make([]map[string]interface{}, len(tasks))
+	for i, task := range tasks {
+		results[i] = task.ResultData()
+	}
+
+	util.SuccessResponse(c, gin.H{
+		"batch_id":   batch.ID,
+		"created_at": batch.CreatedAt,
+		"cost":       batch.Cost,
+		"progress":   models.NewBatchProgress(tasks),
+		"tasks":      results,
+	}, "")
+}
+
+// check 校验单个子任务的优先级和定时参数，模型参数由调用方按模型能力描述校验
+func (item *BatchTaskItem) check(now time.Time) (string, string) {
+	if !config.IsValidTaskPriority(item.Priority) {
+		return "priority参数无效", "支持的优先级: high, normal, low"
+	}
+	if msg := item.validate(now); msg != "" {
+		return "定时参数无效", msg
+	}
+	return "", ""
+}
@@ -1,10 +1,16 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"volcengine-go-server/api/middleware"
 	"volcengine-go-server/config"
 	"volcengine-go-server/internal/core"
 	"volcengine-go-server/internal/models"
@@ -14,14 +20,22 @@ import (
 
 // 通用AI任务请求结构
 type AITaskRequest struct {
-	Prompt   string `json:"prompt"`                   // 改为可选，图生视频时可以为空
-	Model    string `json:"model" binding:"required"` // 设为必填字段
-	UserID   string `json:"user_id" binding:"required"`
+	Prompt   string `json:"prompt"`                      // 改为可选，图生视频时可以为空
+	Model    string `json:"model" binding:"required"`    // 设为必填字段
+	UserID   string `json:"-"`                           // 由API密钥认证结果填充，不接受请求体传入
 	Provider string `json:"provider" binding:"required"` // 设为必填字段
 
 	// 图像和视频生成共用字段
 	AspectRatio string `json:"aspect_ratio,omitempty"` // 宽高比例
 
+	// 图像生成特有字段
+	N int `json:"n,omitempty"` // 生成图片数量，默认1
+
+	// 图像编辑特有字段，原图通过链接或base64数据（支持data URI）二选一提供
+	SourceImageURL  string  `json:"source_image_url,omitempty"`  // 原图链接
+	SourceImageData string  `json:"source_image_data,omitempty"` // 原图数据，创建任务时转存为链接
+	Strength        float64 `json:"strength,omitempty"`          // 编辑强度，取值[0, 1]，默认0.5
+
 	// 图生视频特有字段
 	ImageURLs []string `json:"image_urls,omitempty"` // 图片链接数组，用于图生视频
 
@@ -31,7 +45,18 @@ type AITaskRequest struct {
 
 	// 视频生成特有字段
 	Duration int   `json:"duration,omitempty"`
-	Seed     int64 `json:"seed,omitempty"` // 随机种子
+	Seed     int64 `json:"seed,omitempty"` // 随机种子，图像编辑共用
+
+	// 回调通知（可选）：任务完成或失败时POST结果到该地址
+	CallbackURL    string `json:"callback_url,omitempty" binding:"omitempty,url,max=2048"`
+	CallbackSecret string `json:"callback_secret,omitempty" binding:"omitempty,max=256"` // 用于HMAC-SHA256签名
+
+	// 队列优先级（可选）：high, normal, low，不填时按任务类型和用户等级路由，不能超过用户等级允许的最高队列
+	Priority string `json:"priority,omitempty"`
+	UserTier string `json:"-"` // 由API密钥认证结果填充
+
+	// 定时执行（可选）：run_at指定执行时间，delay_seconds指定延迟秒数，二者最多填一个
+	TaskSchedule
 }
 
 // AI任务类型
@@ -44,14 +69,38 @@ const (
 )
 
 type AIHandler struct {
-	taskService  *service.TaskService
-	queueService *core.TaskQueue
+	taskService    *service.TaskService
+	webhookService *service.WebhookService
+	creditService  *service.CreditService
+	idemService    *service.IdempotencyService
+	batchService   *service.BatchService
+	queueService   *core.TaskQueue
+	taskStream     *core.TaskStream
+	registry       *core.ServiceRegistry // 服务商能力描述，用于模型目录和请求校验
+	mediaService   *service.MediaService // 转存图像编辑上传的原图
 }
 
-func NewAIHandler(taskService *service.TaskService, queueService *core.TaskQueue) *AIHandler {
+func NewAIHandler(
+	taskService *service.TaskService,
+	webhookService *service.WebhookService,
+	creditService *service.CreditService,
+	idemService *service.IdempotencyService,
+	batchService *service.BatchService,
+	queueService *core.TaskQueue,
+	taskStream *core.TaskStream,
+	registry *core.ServiceRegistry,
+	mediaService *service.MediaService,
+) *AIHandler {
 	return &AIHandler{
-		taskService:  taskService,
-		queueService: queueService,
+		taskService:    taskService,
+		webhookService: webhookService,
+		creditService:  creditService,
+		idemService:    idemService,
+		batchService:   batchService,
+		queueService:   queueService,
+		taskStream:     taskStream,
+		registry:       registry,
+		mediaService:   mediaService,
 	}
 }
 
@@ -72,11 +121,33 @@ func (h *AIHandler) CreateVideoTask(c *gin.Context) {
 
 // 创建AI任务的通用方法
 func (h *AIHandler) createTask(c *gin.Context, taskType AITaskType) {
+	user, ok := middleware.CurrentUser(c)
+	if !ok {
+		util.ForbiddenResponse(c, "无法创建任务", "请使用用户API密钥创建任务")
+		return
+	}
+
 	var req AITaskRequest
 	if errors := util.ValidateRequest(c, &req); len(errors) > 0 {
 		util.ValidationErrorResponse(c, errors)
 		return
 	}
+	req.UserID = user.ID
+	req.UserTier = user.Tier
+
+	if !config.IsValidTaskPriority(req.Priority) {
+		util.BadRequestResponse(c, "priority参数无效", "支持的优先级: high, normal, low")
+		return
+	}
+
+	if !checkCallbackURL(c, "", req.CallbackURL) {
+		return
+	}
+
+	if msg := req.validate(time.Now()); msg != "" {
+		util.BadRequestResponse(c, "定时参数无效", msg)
+		return
+	}
 
 	// 验证model字段是否为空
 	if req.Model == "" {
@@ -90,162 +161,162 @@ func (h *AIHandler) createTask(c *gin.Context, taskType AITaskType) {
 		return
 	}
 
+	// 按模型声明的参数规则校验，参数无效的请求不会创建任务和入队
+	capability, msg, detail := h.checkModel(taskType, &req)
+	if msg != "" {
+		util.BadRequestResponse(c, msg, detail)
+		return
+	}
+	if errs := service.ValidateModelInput(capability, req.modelInput()); len(errs) > 0 {
+		util.ValidationErrorResponse(c, errs)
+		return
+	}
+
 	provider := req.Provider
 	model := req.Model
 
-	switch taskType {
-	case TaskTypeImage:
-		h.handleImageTaskCreation(c, &req, provider, model)
-	case TaskTypeText:
-		h.handleTextTaskCreation(c, &req, provider, model)
-	case TaskTypeVideo:
-		h.handleVideoTaskCreation(c, &req, provider, model)
-	default:
-		util.BadRequestResponse(c, "不支持的任务类型", "")
-	}
+	h.withIdempotency(c, req.UserID, idempotencyRequestHash(string(taskType), &req), func() {
+		switch taskType {
+		case TaskTypeImage:
+			h.handleImageTaskCreation(c, &req, provider, model)
+		case TaskTypeText:
+			h.handleTextTaskCreation(c, &req, provider, model)
+		case TaskTypeVideo:
+			h.handleVideoTaskCreation(c, &req, provider, model)
+		default:
+			util.BadRequestResponse(c, "不支持的任务类型", "")
+		}
+	})
 }
 
 // 处理图像任务创建的具体实现
 func (h *AIHandler) handleImageTaskCreation(c *gin.Context, req *AITaskRequest, provider, model string) {
-	// 图像生成必须有prompt
-	if req.Prompt == "" {
-		util.BadRequestResponse(c, "图像生成任务缺少prompt参数", "请提供图像生成的描述文本")
+	// 上传的原图先转存，任务记录和队列载荷中只保存链接
+	if err := h.storeSourceImage(c.Request.Context(), req); err != nil {
+		respondSourceImageError(c, "", err)
 		return
 	}
 
-	// 创建图像任务输入
-	input := &models.TaskInput{
-		Prompt:      req.Prompt,
-		UserID:      req.UserID,
-		Type:        "image",
-		Model:       model,
-		Provider:    provider,
-		AspectRatio: req.AspectRatio,
-	}
-
-	// 在任务系统中创建记录
-	task, err := h.taskService.CreateTask(c.Request.Context(), input)
-	if err != nil {
-		util.InternalServerErrorResponse(c, "创建图像任务记录失败", err.Error())
+	// 扣除积分并在任务系统中创建记录
+	task, ok := h.createChargedTask(c, newTaskInput(TaskTypeImage, req), "创建图像任务记录失败")
+	if !ok {
 		return
 	}
 
-	// 构建队列任务载荷
-	payload := &core.AITaskPayload{
-		TaskID:   task.ID,
-		UserID:   req.UserID,
-		Type:     string(TaskTypeImage) + "_generation",
-		Provider: provider,
-		Model:    model,
-		Input: map[string]interface{}{
-			"prompt":       req.Prompt,
-			"aspect_ratio": req.AspectRatio,
-		},
-	}
-
 	// 将任务放入Redis队列
-	if err := h.queueService.EnqueueTask(c.Request.Context(), core.TypeImageGeneration, payload); err != nil {
-		// 如果入队失败，删除已创建的任务记录
-		h.taskService.DeleteTask(c.Request.Context(), task.ID)
-		util.InternalServerErrorResponse(c, "任务入队失败", err.Error())
+	queueTaskType, payload := newTaskPayload(TaskTypeImage, req, task)
+	if !h.enqueueTask(c, queueTaskType, payload, req, task) {
 		return
 	}
 
-	util.CreatedResponse(c, gin.H{
+	responseData := withSchedule(gin.H{
 		"task_id":  task.ID,
-		"status":   config.TaskStatusPending,
+		"status":   task.Status,
 		"provider": provider,
 		"model":    model,
-	}, "图像生成任务创建成功")
+		"n":        task.N,
+		"cost":     task.Cost,
+	}, task)
+
+	if isImageEdit(req) {
+		responseData["task_type"] = "image_edit"
+		responseData["source_image_url"] = task.SourceImageURL
+		responseData["strength"] = task.Strength
+		responseData["seed"] = task.Seed
+	} else {
+		responseData["task_type"] = "text_to_image"
+	}
+
+	util.CreatedResponse(c, responseData, "图像生成任务创建成功")
 }
 
-// 处理文本任务创建的具体实现
-func (h *AIHandler) handleTextTaskCreation(c *gin.Context, req *AITaskRequest, provider, model string) {
-	// TODO: 实现文本任务创建逻辑
-	util.NotImplementedResponse(c, "文本生成功能暂未实现", "该功能正在开发中，敬请期待", gin.H{
-		"provider": provider,
-		"model":    model,
-		"prompt":   req.Prompt,
-	})
+// storeSourceImage 将请求中上传的原图转存到媒体存储，并以转存后的链接替换原图数据
+func (h *AIHandler) storeSourceImage(ctx context.Context, req *AITaskRequest) error {
+	if req.SourceImageData == "" {
+		return nil
+	}
+	asset, err := h.mediaService.StoreSourceImage(ctx, req.SourceImageData)
+	if err != nil {
+		return err
+	}
+	req.SourceImageURL = asset.URL
+	req.SourceImageData = ""
+	return nil
 }
 
-// 处理视频任务创建的具体实现
-func (h *AIHandler) handleVideoTaskCreation(c *gin.Context, req *AITaskRequest, provider, model string) {
-	// 根据模型类型判断是文生视频还是图生视频
-	isI2V := model == config.VolcengineJimengI2VModel
-
-	// 验证输入参数
-	if isI2V {
-		// 图生视频：必须有image_urls，prompt可选
-		if len(req.ImageURLs) == 0 {
-			util.BadRequestResponse(c, "图生视频任务缺少image_urls参数", "请提供至少一个图片链接")
-			return
-		}
-	} else {
-		// 文生视频：必须有prompt
-		if req.Prompt == "" {
-			util.BadRequestResponse(c, "文生视频任务缺少prompt参数", "请提供视频生成的描述文本")
-			return
-		}
+// checkCallbackURL 校验回调地址只能是指向公网的https地址，校验失败时已写入错误响应
+func checkCallbackURL(c *gin.Context, prefix, callbackURL string) bool {
+	if callbackURL == "" {
+		return true
+	}
+	if err := service.ValidateCallbackURL(c.Request.Context(), callbackURL); err != nil {
+		util.BadRequestResponse(c, prefix+"callback_url参数无效", err.Error())
+		return false
 	}
+	return true
+}
 
-	// 创建视频任务输入
-	input := &models.TaskInput{
-		Prompt:      req.Prompt,
-		UserID:      req.UserID,
-		Type:        "video",
-		Model:       model,
-		Provider:    provider,
-		Seed:        req.Seed,
-		AspectRatio: req.AspectRatio,
+// respondSourceImageError 原图数据无效时返回400，存储失败时返回500
+func respondSourceImageError(c *gin.Context, prefix string, err error) {
+	if errors.Is(err, service.ErrInvalidSourceImage) {
+		util.BadRequestResponse(c, prefix+"source_image_data参数无效", err.Error())
+		return
 	}
+	util.InternalServerErrorResponse(c, prefix+"保存原图失败", err.Error())
+}
 
-	// 在任务系统中创建记录
-	task, err := h.taskService.CreateTask(c.Request.Context(), input)
-	if err != nil {
-		util.InternalServerErrorResponse(c, "创建视频任务记录失败", err.Error())
+// 处理文本任务创建的具体实现
+func (h *AIHandler) handleTextTaskCreation(c *gin.Context, req *AITaskRequest, provider, model string) {
+	// 扣除积分并在任务系统中创建记录
+	task, ok := h.createChargedTask(c, newTaskInput(TaskTypeText, req), "创建文本任务记录失败")
+	if !ok {
 		return
 	}
 
-	// 构建队列任务载荷
-	payload := &core.AITaskPayload{
-		TaskID:   task.ID,
-		UserID:   req.UserID,
-		Type:     string(TaskTypeVideo) + "_generation",
-		Provider: provider,
-		Model:    model,
-		Input: map[string]interface{}{
-			"prompt":       req.Prompt,
-			"seed":         task.Seed,
-			"aspect_ratio": task.AspectRatio,
-		},
+	// 将任务放入Redis队列
+	queueTaskType, payload := newTaskPayload(TaskTypeText, req, task)
+	if !h.enqueueTask(c, queueTaskType, payload, req, task) {
+		return
 	}
 
-	// 如果是图生视频，添加image_urls到输入中
-	if isI2V {
-		payload.Input["image_urls"] = req.ImageURLs
+	util.CreatedResponse(c, withSchedule(gin.H{
+		"task_id":     task.ID,
+		"status":      task.Status,
+		"provider":    provider,
+		"model":       model,
+		"max_tokens":  task.MaxTokens,
+		"temperature": task.Temperature,
+		"cost":        task.Cost,
+	}, task), "文本生成任务创建成功")
+}
+
+// 处理视频任务创建的具体实现
+func (h *AIHandler) handleVideoTaskCreation(c *gin.Context, req *AITaskRequest, provider, model string) {
+	// 扣除积分并在任务系统中创建记录
+	task, ok := h.createChargedTask(c, newTaskInput(TaskTypeVideo, req), "创建视频任务记录失败")
+	if !ok {
+		return
 	}
 
 	// 将任务放入Redis队列
-	if err := h.queueService.EnqueueTask(c.Request.Context(), core.TypeVideoGeneration, payload); err != nil {
-		// 如果入队失败，删除已创建的任务记录
-		h.taskService.DeleteTask(c.Request.Context(), task.ID)
-		util.InternalServerErrorResponse(c, "任务入队失败", err.Error())
+	queueTaskType, payload := newTaskPayload(TaskTypeVideo, req, task)
+	if !h.enqueueTask(c, queueTaskType, payload, req, task) {
 		return
 	}
 
 	// 构建响应数据
-	responseData := gin.H{
+	responseData := withSchedule(gin.H{
 		"task_id":      task.ID,
-		"status":       config.TaskStatusPending,
+		"status":       task.Status,
 		"provider":     provider,
 		"model":        model,
 		"seed":         task.Seed,
 		"aspect_ratio": task.AspectRatio,
-	}
+		"cost":         task.Cost,
+	}, task)
 
 	// 根据任务类型添加特定字段
-	if isI2V {
+	if isImageToVideo(req) {
 		responseData["image_count"] = len(req.ImageURLs)
 		responseData["task_type"] = "image_to_video"
 	} else {
@@ -255,6 +326,130 @@ func (h *AIHandler) handleVideoTaskCreation(c *gin.Context, req *AITaskRequest,
 	util.CreatedResponse(c, responseData, "视频生成任务创建成功")
 }
 
+// modelInput 构建用于模型参数校验的输入，零值视为未填写，由任务创建时补全默认值
+func (req *AITaskRequest) modelInput() map[string]interface{} {
+	input := map[string]interface{}{
+		"prompt":       req.Prompt,
+		"aspect_ratio": req.AspectRatio,
+	}
+	if req.N != 0 {
+		input["n"] = req.N
+	}
+	if req.MaxTokens != 0 {
+		input["max_tokens"] = req.MaxTokens
+	}
+	if req.Temperature != 0 {
+		input["temperature"] = req.Temperature
+	}
+	if req.Seed != 0 {
+		input["seed"] = req.Seed
+	}
+	if len(req.ImageURLs) > 0 {
+		input["image_urls"] = req.ImageURLs
+	}
+	// 原图链接和原图数据按同一参数校验，数据在通过校验后才转存
+	if req.SourceImageURL != "" {
+		input["source_image_url"] = req.SourceImageURL
+	} else if req.SourceImageData != "" {
+		input["source_image_url"] = req.SourceImageData
+	}
+	if req.Strength != 0 {
+		input["strength"] = req.Strength
+	}
+	return input
+}
+
+// isImageToVideo 根据模型判断是图生视频还是文生视频
+func isImageToVideo(req *AITaskRequest) bool {
+	return req.Model == config.VolcengineJimengI2VModel
+}
+
+// isImageEdit 根据模型判断是图像编辑还是文生图
+func isImageEdit(req *AITaskRequest) bool {
+	return req.Model == config.VolcengineImageEditModel
+}
+
+// newTaskInput 根据请求构建任务输入
+func newTaskInput(taskType AITaskType, req *AITaskRequest) *models.TaskInput {
+	input := &models.TaskInput{
+		Prompt:   req.Prompt,
+		UserID:   req.UserID,
+		Type:     string(taskType),
+		Model:    req.Model,
+		Provider: req.Provider,
+
+		CallbackURL:    req.CallbackURL,
+		CallbackSecret: req.CallbackSecret,
+		RunAt:          req.scheduledAt(time.Now()),
+	}
+
+	switch taskType {
+	case TaskTypeImage:
+		input.AspectRatio = req.AspectRatio
+		input.N = req.N
+		if isImageEdit(req) {
+			input.N = 1 // 每次编辑只产出一张图片，按一张计费
+			input.SourceImageURL = req.SourceImageURL
+			input.Strength = req.Strength
+			input.Seed = req.Seed
+		}
+	case TaskTypeText:
+		input.MaxTokens = req.MaxTokens
+		input.Temperature = req.Temperature
+	case TaskTypeVideo:
+		input.Seed = req.Seed
+		input.AspectRatio = req.AspectRatio
+	}
+	return input
+}
+
+// newTaskPayload 根据请求和已创建的任务记录构建队列任务类型和载荷，参数使用任务记录中补全默认值后的值
+func newTaskPayload(taskType AITaskType, req *AITaskRequest, task *models.Task) (string, *core.AITaskPayload) {
+	payload := &core.AITaskPayload{
+		TaskID:   task.ID,
+		UserID:   req.UserID,
+		UserTier: req.UserTier,
+		Priority: req.Priority,
+		Type:     string(taskType) + "_generation",
+		Provider: req.Provider,
+		Model:    req.Model,
+	}
+
+	switch taskType {
+	case TaskTypeImage:
+		payload.Input = map[string]interface{}{
+			"prompt":       req.Prompt,
+			"aspect_ratio": req.AspectRatio,
+			"n":            task.N,
+		}
+		// 如果是图像编辑，添加原图和编辑参数到输入中
+		if isImageEdit(req) {
+			payload.Input["source_image_url"] = task.SourceImageURL
+			payload.Input["strength"] = task.Strength
+			payload.Input["seed"] = task.Seed
+		}
+		return core.TypeImageGeneration, payload
+	case TaskTypeText:
+		payload.Input = map[string]interface{}{
+			"prompt":      req.Prompt,
+			"max_tokens":  task.MaxTokens,
+			"temperature": task.Temperature,
+		}
+		return core.TypeTextGeneration, payload
+	default:
+		payload.Input = map[string]interface{}{
+			"prompt":       req.Prompt,
+			"seed":         task.Seed,
+			"aspect_ratio": task.AspectRatio,
+		}
+		// 如果是图生视频，添加image_urls到输入中
+		if isImageToVideo(req) {
+			payload.Input["image_urls"] = req.ImageURLs
+		}
+		return core.TypeVideoGeneration, payload
+	}
+}
+
 // 统一任务结果查询
 func (h *AIHandler) GetTaskResult(c *gin.Context) {
 	taskID := c.Param("task_id")
@@ -263,9 +458,8 @@ func (h *AIHandler) GetTaskResult(c *gin.Context) {
 		return
 	}
 
-	task, err := h.taskService.GetTask(c.Request.Context(), taskID)
-	if err != nil {
-		util.NotFoundResponse(c, "任务不存在", err.Error())
+	task, ok := h.getAuthorizedTask(c, taskID)
+	if !ok {
 		return
 	}
 
@@ -280,6 +474,16 @@ func (h *AIHandler) DeleteTask(c *gin.Context) {
 		return
 	}
 
+	if _, ok := h.getAuthorizedTask(c, taskID); !ok {
+		return
+	}
+
+	// 先从队列中移除或中止任务，避免Worker继续执行并回写已删除的记录
+	if err := h.queueService.CancelTask(c.Request.Context(), taskID); err != nil {
+		util.InternalServerErrorResponse(c, "取消队列任务失败", err.Error())
+		return
+	}
+
 	if err := h.taskService.DeleteTask(c.Request.Context(), taskID); err != nil {
 		util.NotFoundResponse(c, "任务不存在", err.Error())
 		return
@@ -288,9 +492,79 @@ func (h *AIHandler) DeleteTask(c *gin.Context) {
 	util.SuccessResponse(c, nil, "任务删除成功")
 }
 
+// 统一任务取消
+func (h *AIHandler) CancelTask(c *gin.Context) {
+	taskID := c.Param("task_id")
+	if taskID == "" {
+		util.BadRequestResponse(c, "任务ID不能为空", "")
+		return
+	}
+
+	if _, ok := h.getAuthorizedTask(c, taskID); !ok {
+		return
+	}
+
+	// 先更新数据库状态，Worker据此跳过执行并拒绝回写结果
+	cancelled, err := h.taskService.CancelTask(c.Request.Context(), taskID)
+	if err != nil {
+		util.InternalServerErrorResponse(c, "取消任务失败", err.Error())
+		return
+	}
+	if !cancelled {
+		util.ErrorResponse(c, http.StatusConflict, "任务已结束，无法取消", "只有定时、等待中或处理中的任务可以取消")
+		return
+	}
+
+	// 删除等待中的队列任务，或通知Worker中止执行中的任务
+	if err := h.queueService.CancelTask(c.Request.Context(), taskID); err != nil {
+		util.InternalServerErrorResponse(c, "取消队列任务失败", err.Error())
+		return
+	}
+
+	util.SuccessResponse(c, gin.H{
+		"task_id": taskID,
+		"status":  config.TaskStatusCancelled,
+	}, "任务取消成功")
+}
+
+// 查询任务回调投递历史
+func (h *AIHandler) GetTaskWebhookDeliveries(c *gin.Context) {
+	taskID := c.Param("task_id")
+	if taskID == "" {
+		util.BadRequestResponse(c, "任务ID不能为空", "")
+		return
+	}
+
+	task, ok := h.getAuthorizedTask(c, taskID)
+	if !ok {
+		return
+	}
+
+	deliveries, err := h.webhookService.GetTaskDeliveries(c.Request.Context(), taskID)
+	if err != nil {
+		util.InternalServerErrorResponse(c, "获取回调投递记录失败", err.Error())
+		return
+	}
+
+	util.SuccessResponse(c, gin.H{
+		"task_id":      taskID,
+		"callback_url": task.CallbackURL,
+		"deliveries":   deliveries,
+		"count":        len(deliveries),
+	}, "")
+}
+
 // 统一任务列表查询
+// 用户密钥只能查询自己的任务，管理员密钥需通过user_id指定用户
 func (h *AIHandler) GetUserTasks(c *gin.Context) {
 	userID := c.Query("user_id")
+	if user, ok := middleware.CurrentUser(c); ok {
+		if userID != "" && userID != user.ID {
+			util.ForbiddenResponse(c, "权限不足", "只能查询自己的任务")
+			return
+		}
+		userID = user.ID
+	}
 	if userID == "" {
 		util.BadRequestResponse(c, "用户ID不能为空", "")
 		return
@@ -300,7 +574,7 @@ func (h *AIHandler) GetUserTasks(c *gin.Context) {
 	taskType := c.Query("type")
 
 	// 解析分页参数
-	limit, offset := h.parsePaginationParams(c)
+	limit, offset := parsePaginationParams(c)
 
 	tasks, err := h.taskService.GetUserTasks(c.Request.Context(), userID, taskType, limit, offset)
 	if err != nil {
@@ -325,50 +599,76 @@ func (h *AIHandler) GetUserTasks(c *gin.Context) {
 	util.SuccessResponse(c, responseData, "")
 }
 
+// createChargedTask 扣除任务费用后创建任务记录，创建失败时退还积分
+// 扣费是带余额条件的原子更新，并发创建任务不会超出用户积分
+func (h *AIHandler) createChargedTask(c *gin.Context, input *models.TaskInput, failMsg string) (*models.Task, bool) {
+	ctx := c.Request.Context()
+
+	input.Cost = service.CalculateTaskCost(input.Type, input.Model, input.N)
+	if err := h.creditService.Charge(ctx, input.UserID, input.Cost); err != nil {
+		if errors.Is(err, service.ErrInsufficientCredits) {
+			util.ErrorResponse(c, http.StatusPaymentRequired, "积分不足", fmt.Sprintf("本次任务需要%d积分", input.Cost))
+		} else {
+			util.InternalServerErrorResponse(c, "扣除积分失败", err.Error())
+		}
+		return nil, false
+	}
+
+	task, err := h.taskService.CreateTask(ctx, input)
+	if err != nil {
+		h.creditService.Refund(ctx, input.UserID, input.Cost)
+		util.InternalServerErrorResponse(c, failMsg, err.Error())
+		return nil, false
+	}
+
+	return task, true
+}
+
+// getAuthorizedTask 获取当前请求有权访问的任务
+// 任务不属于当前用户时同样返回404，不暴露任务是否存在
+func (h *AIHandler) getAuthorizedTask(c *gin.Context, taskID string) (*models.Task, bool) {
+	task, err := h.taskService.GetTask(c.Request.Context(), taskID)
+	if err != nil {
+		util.NotFoundResponse(c, "任务不存在", err.Error())
+		return nil, false
+	}
+	if !middleware.CanAccessUser(c, task.UserID) {
+		util.NotFoundResponse(c, "任务不存在", "任务ID: "+taskID)
+		return nil, false
+	}
+	return task, true
+}
+
 // 统一的任务结果响应
 func (h *AIHandler) respondWithTaskResult(c *gin.Context, task *models.Task) {
-	responseData := gin.H{
-		"task_id": task.ID,
-		"type":    task.Type,
-		"status":  task.Status,
-		"created": task.Created,
-		"updated": task.Updated,
-	}
+	responseData := gin.H(task.ResultData())
 
-	// 根据任务类型添加特定字段
-	switch task.Type {
-	case models.TaskTypeImage:
-		if task.ImageURL != "" {
-			responseData["image_url"] = task.ImageURL
-		}
-		responseData["aspect_ratio"] = task.AspectRatio
-	case models.TaskTypeVideo:
-		if task.VideoURL != "" {
-			responseData["video_url"] = task.VideoURL
-		}
-		responseData["seed"] = task.Seed
-		responseData["aspect_ratio"] = task.AspectRatio
-	case models.TaskTypeText:
-		if task.TextResult != "" {
-			responseData["text_result"] = task.TextResult
-		}
-		responseData["max_tokens"] = task.MaxTokens
-		responseData["temperature"] = task.Temperature
+	// 管理员额外查看服务商侧任务信息，便于向服务商提交工单
+	admin := middleware.IsAdmin(c)
+	if admin {
+		responseData["provider_job"] = task.ProviderData()
 	}
 
 	switch task.Status {
 	case config.TaskStatusCompleted:
 		util.SuccessResponse(c, responseData, "任务完成")
 	case config.TaskStatusFailed:
-		responseData["error"] = task.Error
-		util.InternalServerErrorResponse(c, "任务执行失败", task.Error)
+		// 返回错误码，客户端据此区分参数错误、内容审核未通过等不可重试的失败
+		c.JSON(http.StatusInternalServerError, util.Response{
+			Success: false,
+			Data:    responseData,
+			Error:   "任务执行失败",
+			Message: task.Error,
+		})
+	case config.TaskStatusCancelled:
+		util.SuccessResponse(c, responseData, "任务已取消")
 	default:
 		util.AcceptedResponse(c, responseData, "任务处理中，请稍后查询")
 	}
 }
 
 // 解析分页参数的辅助方法
-func (h *AIHandler) parsePaginationParams(c *gin.Context) (limit, offset int) {
+func parsePaginationParams(c *gin.Context) (limit, offset int) {
 	limit = config.DefaultPageLimit
 	offset = config.DefaultPageOffset
 
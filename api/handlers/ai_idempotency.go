@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"volcengine-go-server/config"
+	"volcengine-go-server/internal/service"
+	"volcengine-go-server/internal/util"
+	"volcengine-go-server/pkg/logger"
+)
+
+// responseRecorder 在写出响应的同时保留一份副本，用于保存首次请求的结果
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// withIdempotency 按Idempotency-Key请求头处理任务创建
+// 首次请求成功后保存响应，相同key和请求内容的重放直接返回该响应，不会再次扣费和入队
+func (h *AIHandler) withIdempotency(c *gin.Context, userID, requestHash string, create func()) {
+	key := strings.TrimSpace(c.GetHeader(config.IdempotencyKeyHeader))
+	if key == "" {
+		create()
+		return
+	}
+	if len(key) > config.IdempotencyKeyMaxLength {
+		util.BadRequestResponse(c, "Idempotency-Key过长", fmt.Sprintf("最多%d个字符", config.IdempotencyKeyMaxLength))
+		return
+	}
+
+	ctx := c.Request.Context()
+	record, replay, err := h.idemService.Reserve(ctx, userID, key, requestHash)
+	switch {
+	case errors.Is(err, service.ErrIdempotencyKeyConflict):
+		util.ErrorResponse(c, http.StatusUnprocessableEntity, "幂等键冲突", "同一Idempotency-Key只能用于相同的请求内容")
+		return
+	case errors.Is(err, service.ErrIdempotencyKeyInProgress):
+		util.ErrorResponse(c, http.StatusConflict, "请求正在处理中", "请稍后使用相同的Idempotency-Key重试")
+		return
+	case err != nil:
+		util.InternalServerErrorResponse(c, "处理幂等键失败", err.Error())
+		return
+	}
+
+	if replay {
+		c.Header("Idempotent-Replayed", "true")
+		c.Data(record.StatusCode, "application/json; charset=utf-8", record.Response)
+		return
+	}
+
+	recorder := &responseRecorder{ResponseWriter: c.Writer}
+	c.Writer = recorder
+	create()
+	c.Writer = recorder.ResponseWriter
+
+	// 只保存成功的响应，失败时释放key，客户端修正后可用同一key重试
+	status := recorder.Status()
+	if status < 200 || status >= 300 {
+		if err := h.idemService.Release(ctx, record); err != nil {
+			logger.GetLogger().Warnf("释放幂等键失败: key=%s, %v", key, err)
+		}
+		return
+	}
+
+	body := recorder.body.Bytes()
+	if err := h.idemService.Complete(ctx, record, extractTaskID(body), status, body); err != nil {
+		logger.GetLogger().Errorf("保存幂等响应失败: key=%s, %v", key, err)
+	}
+}
+
+// idempotencyRequestHash 计算请求内容摘要，类型不同的请求也视为不同内容
+func idempotencyRequestHash(kind string, req interface{}) string {
+	data, _ := json.Marshal(struct {
+		Type    string      `json:"type"`
+		Request interface{} `json:"request"`
+	}{kind, req})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// extractTaskID 从任务创建响应中提取任务ID
+func extractTaskID(body []byte) string {
+	var resp struct {
+		Data struct {
+			TaskID string `json:"task_id"`
+		} `json:"data"`
+	}
+	json.Unmarshal(body, &resp)
+	return resp.Data.TaskID
+}
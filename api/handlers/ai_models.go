@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"volcengine-go-server/internal/core"
+	"volcengine-go-server/internal/models"
+	"volcengine-go-server/internal/util"
+)
+
+// ListModels 获取模型目录，可按type参数过滤任务类型
+func (h *AIHandler) ListModels(c *gin.Context) {
+	taskType := c.Query("type")
+
+	catalog := h.registry.Catalog()
+	if taskType != "" {
+		filtered := make([]models.ProviderCapability, 0, len(catalog))
+		for _, capability := range catalog {
+			var matched []models.ModelCapability
+			for _, m := range capability.Models {
+				if m.TaskType == taskType {
+					matched = append(matched, m)
+				}
+			}
+			if len(matched) > 0 {
+				filtered = append(filtered, models.ProviderCapability{Provider: capability.Provider, Models: matched})
+			}
+		}
+		catalog = filtered
+	}
+
+	util.SuccessResponse(c, gin.H{
+		"providers": catalog,
+	}, "")
+}
+
+// checkModel 校验服务商是否可用且支持该任务类型的模型，返回模型能力描述，校验失败时返回错误信息和详情
+func (h *AIHandler) checkModel(taskType AITaskType, req *AITaskRequest) (models.ModelCapability, string, string) {
+	return checkModelCapability(h.registry, string(taskType), req.Provider, req.Model)
+}
+
+// checkModelCapability 按服务注册器中的能力描述校验服务商和模型，任务、周期任务模板和流水线步骤共用
+func checkModelCapability(registry *core.ServiceRegistry, taskType, provider, model string) (models.ModelCapability, string, string) {
+	capability, ok := registry.GetCapabilities(provider)
+	if !ok {
+		var providers []string
+		for _, p := range registry.Catalog() {
+			providers = append(providers, p.Provider)
+		}
+		return models.ModelCapability{}, "不支持的服务提供商: " + provider, "可用的服务提供商: " + strings.Join(providers, ", ")
+	}
+
+	found, ok := capability.FindModel(taskType, model)
+	if !ok {
+		names := capability.ModelNames(taskType)
+		if len(names) == 0 {
+			return found, "不支持的模型: " + model, fmt.Sprintf("服务提供商%s不支持%s任务", provider, taskType)
+		}
+		return found, "不支持的模型: " + model, fmt.Sprintf("服务提供商%s支持的%s模型: %s", provider, taskType, strings.Join(names, ", "))
+	}
+	return found, "", ""
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hibiken/asynq"
+
+	"volcengine-go-server/config"
+	"volcengine-go-server/internal/core"
+	"volcengine-go-server/internal/models"
+	"volcengine-go-server/internal/util"
+)
+
+// TaskSchedule 定时执行参数，run_at和delay_seconds最多指定一个
+type TaskSchedule struct {
+	RunAt        *time.Time `json:"run_at,omitempty"`                                  // 计划执行时间（RFC3339）
+	DelaySeconds int        `json:"delay_seconds,omitempty" binding:"omitempty,min=0"` // 延迟执行秒数
+}
+
+// RescheduleTaskRequest 修改定时任务执行时间请求
+type RescheduleTaskRequest struct {
+	TaskSchedule
+}
+
+// IsScheduled 是否指定了定时执行
+func (s *TaskSchedule) IsScheduled() bool {
+	return s.RunAt != nil || s.DelaySeconds > 0
+}
+
+// scheduledAt 计算计划执行时间，未指定时返回nil
+func (s *TaskSchedule) scheduledAt(now time.Time) *time.Time {
+	if s.RunAt != nil {
+		return s.RunAt
+	}
+	if s.DelaySeconds > 0 {
+		runAt := now.Add(time.Duration(s.DelaySeconds) * time.Second)
+		return &runAt
+	}
+	return nil
+}
+
+// validate 校验定时执行参数，返回错误说明
+func (s *TaskSchedule) validate(now time.Time) string {
+	if s.RunAt != nil && s.DelaySeconds > 0 {
+		return "run_at和delay_seconds不能同时指定"
+	}
+
+	runAt := s.scheduledAt(now)
+	if runAt == nil {
+		return ""
+	}
+	if !runAt.After(now) {
+		return "run_at必须晚于当前时间"
+	}
+	if runAt.Sub(now) > config.MaxScheduleAhead {
+		return fmt.Sprintf("最多提前%d天安排任务", int(config.MaxScheduleAhead.Hours()/24))
+	}
+	return ""
+}
+
+// queueOptions 返回定时执行对应的入队选项
+func (s *TaskSchedule) queueOptions() []asynq.Option {
+	switch {
+	case s.RunAt != nil:
+		return []asynq.Option{asynq.ProcessAt(*s.RunAt)}
+	case s.DelaySeconds > 0:
+		return []asynq.Option{asynq.ProcessIn(time.Duration(s.DelaySeconds) * time.Second)}
+	}
+	return nil
+}
+
+// enqueueTask 按请求的执行时间选择入队方式，入队失败时删除任务记录并返回错误响应
+func (h *AIHandler) enqueueTask(c *gin.Context, queueTaskType string, payload *core.AITaskPayload, req *AITaskRequest, task *models.Task) bool {
+	ctx := c.Request.Context()
+
+	var err error
+	switch {
+	case req.RunAt != nil:
+		err = h.queueService.EnqueueScheduledTask(ctx, queueTaskType, payload, *req.RunAt)
+	case req.DelaySeconds > 0:
+		err = h.queueService.EnqueueDelayedTask(ctx, queueTaskType, payload, time.Duration(req.DelaySeconds)*time.Second)
+	default:
+		err = h.queueService.EnqueueTask(ctx, queueTaskType, payload)
+	}
+
+	if err != nil {
+		// 如果入队失败，删除已创建的任务记录
+		h.taskService.DeleteTask(ctx, task.ID)
+		util.InternalServerErrorResponse(c, "任务入队失败", err.Error())
+		return false
+	}
+	return true
+}
+
+// 修改定时任务的执行时间
+func (h *AIHandler) RescheduleTask(c *gin.Context) {
+	taskID := c.Param("task_id")
+	if taskID == "" {
+		util.BadRequestResponse(c, "任务ID不能为空", "")
+		return
+	}
+
+	var req RescheduleTaskRequest
+	if validationErrors := util.ValidateRequest(c, &req); len(validationErrors) > 0 {
+		util.ValidationErrorResponse(c, validationErrors)
+		return
+	}
+
+	now := time.Now()
+	if !req.IsScheduled() {
+		util.BadRequestResponse(c, "定时参数无效", "请指定run_at或delay_seconds")
+		return
+	}
+	if msg := req.validate(now); msg != "" {
+		util.BadRequestResponse(c, "定时参数无效", msg)
+		return
+	}
+	runAt := *req.scheduledAt(now)
+
+	task, ok := h.getAuthorizedTask(c, taskID)
+	if !ok {
+		return
+	}
+	if task.Status != config.TaskStatusScheduled {
+		util.ErrorResponse(c, http.StatusConflict, "任务无法重新安排", "只有尚未执行的定时任务可以修改执行时间，当前状态: "+task.Status)
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.queueService.RescheduleTask(ctx, taskID, runAt); err != nil {
+		switch {
+		case errors.Is(err, core.ErrQueueTaskState), errors.Is(err, asynq.ErrTaskNotFound):
+			util.ErrorResponse(c, http.StatusConflict, "任务无法重新安排", "任务已开始执行")
+		default:
+			util.InternalServerErrorResponse(c, "重新安排任务失败", err.Error())
+		}
+		return
+	}
+
+	if _, err := h.taskService.RescheduleTask(ctx, taskID, runAt); err != nil {
+		util.InternalServerErrorResponse(c, "更新任务执行时间失败", err.Error())
+		return
+	}
+
+	util.SuccessResponse(c, gin.H{
+		"task_id": taskID,
+		"status":  config.TaskStatusScheduled,
+		"run_at":  runAt,
+	}, "任务执行时间已更新")
+}
+
+// withSchedule 在任务创建响应中添加定时信息
+func withSchedule(data gin.H, task *models.Task) gin.H {
+	if task.RunAt != nil {
+		data["run_at"] = task.RunAt
+	}
+	return data
+}
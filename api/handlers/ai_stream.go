@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"io"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"volcengine-go-server/config"
+	"volcengine-go-server/internal/core"
+	"volcengine-go-server/internal/models"
+	"volcengine-go-server/internal/util"
+)
+
+// StreamTaskResult 以SSE方式实时推送文本任务的生成结果
+// 事件类型：delta(增量文本)、done(完整文本)、error(最终失败原因)、
+// retrying(本次执行失败，任务将重试)、reset(重新开始生成，丢弃已收到的文本)
+func (h *AIHandler) StreamTaskResult(c *gin.Context) {
+	taskID := c.Param("task_id")
+	if taskID == "" {
+		util.BadRequestResponse(c, "任务ID不能为空", "")
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	task, ok := h.getAuthorizedTask(c, taskID)
+	if !ok {
+		return
+	}
+
+	if task.Type != models.TaskTypeText {
+		util.BadRequestResponse(c, "仅文本任务支持流式输出", "请使用 /task/result 接口查询该任务结果")
+		return
+	}
+
+	// 先订阅再读取缓冲区，保证不会遗漏两者之间发布的增量
+	sub, err := h.taskStream.Subscribe(ctx, taskID)
+	if err != nil {
+		util.InternalServerErrorResponse(c, "订阅任务流失败", err.Error())
+		return
+	}
+	defer sub.Close()
+
+	setSSEHeaders(c)
+
+	// 订阅生效后重新读取任务，已结束的任务直接返回最终结果
+	if task, err = h.taskService.GetTask(ctx, taskID); err == nil && writeTerminalEvent(c, task) {
+		return
+	}
+
+	// 补发已生成的部分文本
+	sent := 0
+	if buffered, err := h.taskStream.GetBuffer(ctx, taskID); err == nil && buffered != "" {
+		c.SSEvent(core.StreamEventDelta, gin.H{"content": buffered})
+		sent = len(buffered)
+	}
+	c.Writer.Flush()
+
+	ticker := time.NewTicker(config.TaskStreamHeartbeatInterval)
+	defer ticker.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Done():
+			return false
+
+		case event, ok := <-sub.Events():
+			if !ok {
+				return false
+			}
+			switch event.Type {
+			case core.StreamEventDelta:
+				end := event.Offset + len(event.Content)
+				// 跳过缓冲区中已经补发过的部分
+				if end <= sent {
+					return true
+				}
+				content := event.Content
+				if event.Offset < sent {
+					content = content[sent-event.Offset:]
+				}
+				c.SSEvent(core.StreamEventDelta, gin.H{"content": content})
+				sent = end
+				return true
+			case core.StreamEventDone:
+				c.SSEvent(core.StreamEventDone, gin.H{"text_result": event.Content})
+				return false
+			case core.StreamEventError:
+				c.SSEvent(core.StreamEventError, gin.H{"error": event.Error, "error_code": event.ErrorCode})
+				return false
+			case core.StreamEventRetrying:
+				c.SSEvent(core.StreamEventRetrying, gin.H{"error": event.Error})
+				return true
+			case core.StreamEventReset:
+				c.SSEvent(core.StreamEventReset, gin.H{})
+				sent = 0
+				return true
+			}
+			return true
+
+		case <-ticker.C:
+			// 兜底检查任务状态，防止Worker异常退出后客户端一直等待
+			if latest, err := h.taskService.GetTask(ctx, taskID); err == nil && writeTerminalEvent(c, latest) {
+				return false
+			}
+			_, err := w.Write([]byte(": ping\n\n"))
+			return err == nil
+		}
+	})
+}
+
+// setSSEHeaders 设置SSE响应头
+func setSSEHeaders(c *gin.Context) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 关闭Nginx缓冲
+	c.Status(200)
+}
+
+// writeTerminalEvent 任务已结束时写出最终事件，返回是否已结束
+func writeTerminalEvent(c *gin.Context, task *models.Task) bool {
+	switch task.Status {
+	case config.TaskStatusCompleted:
+		c.SSEvent(core.StreamEventDone, gin.H{"text_result": task.TextResult})
+	case config.TaskStatusFailed:
+		c.SSEvent(core.StreamEventError, gin.H{"error": task.Error, "error_code": task.ErrorCode})
+	case config.TaskStatusCancelled:
+		c.SSEvent(core.StreamEventError, gin.H{"error": "任务已取消"})
+	default:
+		return false
+	}
+	c.Writer.Flush()
+	return true
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"volcengine-go-server/api/middleware"
+	"volcengine-go-server/config"
+	"volcengine-go-server/internal/core"
+	"volcengine-go-server/internal/models"
+	"volcengine-go-server/internal/service"
+	"volcengine-go-server/internal/util"
+	"volcengine-go-server/pkg/logger"
+)
+
+// PipelineRequest 创建流水线请求
+// 步骤的prompt和image_urls中可以使用 {{prev.output}} 或 {{steps.N.output}} 引用之前步骤的输出
+type PipelineRequest struct {
+	Steps []models.PipelineStep `json:"steps" binding:"required,min=1"`
+}
+
+// PipelineHandler 流水线接口，流水线属于创建它的用户
+type PipelineHandler struct {
+	pipelineService *service.PipelineService
+	creditService   *service.CreditService
+	pipelineRunner  *core.PipelineRunner
+	registry        *core.ServiceRegistry // 服务商能力描述，用于校验步骤参数
+}
+
+func NewPipelineHandler(pipelineService *service.PipelineService, creditService *service.CreditService, pipelineRunner *core.PipelineRunner, registry *core.ServiceRegistry) *PipelineHandler {
+	return &PipelineHandler{
+		pipelineService: pipelineService,
+		creditService:   creditService,
+		pipelineRunner:  pipelineRunner,
+		registry:        registry,
+	}
+}
+
+// 创建流水线
+// 创建时扣除全部步骤的积分，流水线提前结束时退还尚未执行的步骤积分
+func (h *PipelineHandler) CreatePipeline(c *gin.Context) {
+	user, ok := middleware.CurrentUser(c)
+	if !ok {
+		util.ForbiddenResponse(c, "无法创建流水线", "请使用用户API密钥创建流水线")
+		return
+	}
+
+	var req PipelineRequest
+	if errors := util.ValidateRequest(c, &req); len(errors) > 0 {
+		util.ValidationErrorResponse(c, errors)
+		return
+	}
+
+	pipeline, err := service.NewPipeline(user.ID, req.Steps)
+	if err != nil {
+		util.BadRequestResponse(c, "流水线定义无效", err.Error())
+		return
+	}
+
+	// 按模型声明的参数规则校验各步骤，任一步骤无效时不扣费
+	for i, step := range pipeline.Steps {
+		capability, msg, detail := checkModelCapability(h.registry, step.Type, step.Provider, step.Model)
+		if msg != "" {
+			util.BadRequestResponse(c, fmt.Sprintf("steps[%d]: %s", i, msg), detail)
+			return
+		}
+		if errs := service.ValidateModelInput(capability, service.StepValidationInput(step)); len(errs) > 0 {
+			for j := range errs {
+				errs[j].Field = fmt.Sprintf("steps[%d].%s", i, errs[j].Field)
+			}
+			util.ValidationErrorResponse(c, errs)
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	if err := h.creditService.Charge(ctx, user.ID, pipeline.Cost); err != nil {
+		if errors.Is(err, service.ErrInsufficientCredits) {
+			util.ErrorResponse(c, http.StatusPaymentRequired, "积分不足", fmt.Sprintf("本流水线共需要%d积分", pipeline.Cost))
+		} else {
+			util.InternalServerErrorResponse(c, "扣除积分失败", err.Error())
+		}
+		return
+	}
+
+	if err := h.pipelineService.CreatePipeline(ctx, pipeline); err != nil {
+		h.creditService.Refund(ctx, user.ID, pipeline.Cost)
+		util.InternalServerErrorResponse(c, "创建流水线失败", err.Error())
+		return
+	}
+
+	if err := h.pipelineRunner.StartPipeline(ctx, pipeline.ID); err != nil {
+		// 入队失败时流水线标记为失败并退还积分
+		errorMsg := "流水线入队失败: " + err.Error()
+		if _, err := h.pipelineService.FinishPipeline(ctx, pipeline.ID, config.TaskStatusFailed, errorMsg, -1, ""); err != nil {
+			logger.GetLogger().Errorf("更新流水线状态失败: %s, %v", pipeline.ID, err)
+		}
+		h.creditService.Refund(ctx, user.ID, pipeline.Cost)
+		util.InternalServerErrorResponse(c, "创建流水线失败", errorMsg)
+		return
+	}
+
+	util.CreatedResponse(c, pipeline, "流水线创建成功")
+}
+
+// 获取当前用户的流水线列表
+func (h *PipelineHandler) ListPipelines(c *gin.Context) {
+	user, ok := middleware.CurrentUser(c)
+	if !ok {
+		util.ForbiddenResponse(c, "无法查询流水线", "请使用用户API密钥查询流水线")
+		return
+	}
+
+	limit, offset := parsePaginationParams(c)
+	pipelines, err := h.pipelineService.ListPipelines(c.Request.Context(), user.ID, limit, offset)
+	if err != nil {
+		util.InternalServerErrorResponse(c, "获取流水线列表失败", err.Error())
+		return
+	}
+
+	util.SuccessResponse(c, gin.H{
+		"pipelines": pipelines,
+		"count":     len(pipelines),
+		"limit":     limit,
+		"offset":    offset,
+	}, "")
+}
+
+// 获取流水线详情和各步骤状态
+func (h *PipelineHandler) GetPipeline(c *gin.Context) {
+	pipeline, ok := h.getAccessiblePipeline(c)
+	if !ok {
+		return
+	}
+
+	util.SuccessResponse(c, pipeline, "")
+}
+
+// 取消流水线，执行中的步骤任务一并取消
+func (h *PipelineHandler) CancelPipeline(c *gin.Context) {
+	pipeline, ok := h.getAccessiblePipeline(c)
+	if !ok {
+		return
+	}
+
+	cancelled, err := h.pipelineRunner.CancelPipeline(c.Request.Context(), pipeline)
+	if err != nil {
+		util.InternalServerErrorResponse(c, "取消流水线失败", err.Error())
+		return
+	}
+	if !cancelled {
+		util.ErrorResponse(c, http.StatusConflict, "流水线已结束，无法取消", "只有等待中或执行中的流水线可以取消")
+		return
+	}
+
+	util.SuccessResponse(c, gin.H{
+		"pipeline_id": pipeline.ID,
+		"status":      config.TaskStatusCancelled,
+	}, "流水线已取消")
+}
+
+// getAccessiblePipeline 获取当前调用方可访问的流水线，失败时已写入错误响应
+func (h *PipelineHandler) getAccessiblePipeline(c *gin.Context) (*models.Pipeline, bool) {
+	pipelineID := c.Param("pipeline_id")
+	pipeline, err := h.pipelineService.GetPipeline(c.Request.Context(), pipelineID)
+	if err != nil {
+		if errors.Is(err, service.ErrPipelineNotFound) {
+			util.NotFoundResponse(c, "流水线不存在", "流水线ID: "+pipelineID)
+		} else {
+			util.InternalServerErrorResponse(c, "获取流水线失败", err.Error())
+		}
+		return nil, false
+	}
+	if !middleware.CanAccessUser(c, pipeline.UserID) {
+		util.NotFoundResponse(c, "流水线不存在", "流水线ID: "+pipelineID)
+		return nil, false
+	}
+	return pipeline, true
+}
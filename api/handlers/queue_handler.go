@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hibiken/asynq"
+
+	"volcengine-go-server/config"
+	"volcengine-go-server/internal/core"
+	"volcengine-go-server/internal/util"
+)
+
+// QueueHandler 队列管理接口，仅管理员可用
+type QueueHandler struct {
+	queueService *core.TaskQueue
+}
+
+func NewQueueHandler(queueService *core.TaskQueue) *QueueHandler {
+	return &QueueHandler{
+		queueService: queueService,
+	}
+}
+
+// 获取所有队列的统计信息
+func (h *QueueHandler) GetQueueStats(c *gin.Context) {
+	stats, err := h.queueService.GetQueueStats(c.Request.Context())
+	if err != nil {
+		util.InternalServerErrorResponse(c, "获取队列统计失败", err.Error())
+		return
+	}
+
+	util.SuccessResponse(c, stats, "")
+}
+
+// 按状态列出队列中的任务
+func (h *QueueHandler) ListQueueTasks(c *gin.Context) {
+	queue, ok := h.getQueue(c)
+	if !ok {
+		return
+	}
+
+	state := c.DefaultQuery("state", asynq.TaskStatePending.String())
+	page, size := parseQueuePagination(c)
+
+	tasks, err := h.queueService.ListQueueTasks(c.Request.Context(), queue, state, page, size)
+	if err != nil {
+		if errors.Is(err, core.ErrInvalidTaskState) {
+			util.BadRequestResponse(c, "state参数无效", "支持的状态: pending, active, scheduled, retry, archived, completed")
+			return
+		}
+		util.InternalServerErrorResponse(c, "获取队列任务失败", err.Error())
+		return
+	}
+
+	util.SuccessResponse(c, gin.H{
+		"queue": queue,
+		"state": state,
+		"tasks": tasks,
+		"page":  page,
+		"size":  size,
+		"count": len(tasks),
+	}, "")
+}
+
+// 立即执行等待重试、定时或已归档的任务
+func (h *QueueHandler) RetryQueueTask(c *gin.Context) {
+	h.handleTaskAction(c, h.queueService.RunQueueTask, "任务已重新入队")
+}
+
+// 从队列删除任务
+func (h *QueueHandler) DeleteQueueTask(c *gin.Context) {
+	h.handleTaskAction(c, h.queueService.DeleteQueueTask, "任务已删除")
+}
+
+// 归档任务
+func (h *QueueHandler) ArchiveQueueTask(c *gin.Context) {
+	h.handleTaskAction(c, h.queueService.ArchiveQueueTask, "任务已归档")
+}
+
+// handleTaskAction 执行单个队列任务的管理操作并统一处理错误
+func (h *QueueHandler) handleTaskAction(c *gin.Context, action func(ctx context.Context, queue, id string) error, message string) {
+	queue, ok := h.getQueue(c)
+	if !ok {
+		return
+	}
+	taskID := c.Param("task_id")
+
+	err := action(c.Request.Context(), queue, taskID)
+	switch {
+	case err == nil:
+		util.SuccessResponse(c, gin.H{"queue": queue, "task_id": taskID}, message)
+	case errors.Is(err, asynq.ErrTaskNotFound), errors.Is(err, asynq.ErrQueueNotFound):
+		util.NotFoundResponse(c, "队列任务不存在", "任务ID: "+taskID)
+	case errors.Is(err, core.ErrQueueTaskState):
+		util.ErrorResponse(c, http.StatusConflict, "操作失败", err.Error())
+	default:
+		util.InternalServerErrorResponse(c, "操作失败", err.Error())
+	}
+}
+
+// getQueue 读取并校验路径中的队列名称
+func (h *QueueHandler) getQueue(c *gin.Context) (string, bool) {
+	queue := c.Param("queue")
+	if !core.IsValidQueue(queue) {
+		util.NotFoundResponse(c, "队列不存在", "支持的队列: critical, default, low")
+		return "", false
+	}
+	return queue, true
+}
+
+// parseQueuePagination 解析分页参数，page从1开始
+func parseQueuePagination(c *gin.Context) (page, size int) {
+	page, size = 1, config.DefaultPageLimit
+
+	if p, err := strconv.Atoi(c.Query("page")); err == nil && p > 0 {
+		page = p
+	}
+	if s, err := strconv.Atoi(c.Query("size")); err == nil && s > 0 {
+		size = s
+	}
+	if size > config.MaxPageLimit {
+		size = config.MaxPageLimit
+	}
+	return page, size
+}
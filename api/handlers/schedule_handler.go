@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"volcengine-go-server/api/middleware"
+	"volcengine-go-server/internal/core"
+	"volcengine-go-server/internal/models"
+	"volcengine-go-server/internal/service"
+	"volcengine-go-server/internal/util"
+)
+
+// ScheduleRequest 创建或更新周期任务请求，更新时整体替换模板
+type ScheduleRequest struct {
+	Name     string `json:"name" binding:"max=100"`
+	Cron     string `json:"cron" binding:"required"` // 标准5段cron表达式，如 "0 8 * * *"
+	Timezone string `json:"timezone,omitempty"`      // IANA时区，如 "Asia/Shanghai"，默认UTC
+	Enabled  *bool  `json:"enabled,omitempty"`       // 默认启用
+
+	// 任务模板
+	Type        string `json:"type" binding:"required,oneof=image text video"`
+	Provider    string `json:"provider" binding:"required"`
+	Model       string `json:"model" binding:"required"`
+	Prompt      string `json:"prompt" binding:"required"`
+	AspectRatio string `json:"aspect_ratio,omitempty"`
+	N           int    `json:"n,omitempty"`
+
+	// 回调通知（可选）
+	CallbackURL    string `json:"callback_url,omitempty" binding:"omitempty,url,max=2048"`
+	CallbackSecret string `json:"callback_secret,omitempty" binding:"omitempty,max=256"`
+}
+
+// ScheduleHandler 周期任务接口，周期任务属于创建它的用户
+type ScheduleHandler struct {
+	scheduleService *service.ScheduleService
+	registry        *core.ServiceRegistry // 服务商能力描述，用于校验任务模板
+}
+
+func NewScheduleHandler(scheduleService *service.ScheduleService, registry *core.ServiceRegistry) *ScheduleHandler {
+	return &ScheduleHandler{
+		scheduleService: scheduleService,
+		registry:        registry,
+	}
+}
+
+// 创建周期任务
+func (h *ScheduleHandler) CreateSchedule(c *gin.Context) {
+	user, ok := middleware.CurrentUser(c)
+	if !ok {
+		util.ForbiddenResponse(c, "无法创建周期任务", "请使用用户API密钥创建周期任务")
+		return
+	}
+
+	var req ScheduleRequest
+	if errors := util.ValidateRequest(c, &req); len(errors) > 0 {
+		util.ValidationErrorResponse(c, errors)
+		return
+	}
+	if !checkCallbackURL(c, "", req.CallbackURL) || !h.checkTemplate(c, &req) {
+		return
+	}
+
+	schedule := &models.Schedule{UserID: user.ID}
+	req.applyTo(schedule)
+
+	if err := h.scheduleService.CreateSchedule(c.Request.Context(), schedule); err != nil {
+		h.respondWithScheduleError(c, err, "创建周期任务失败")
+		return
+	}
+
+	util.CreatedResponse(c, schedule, "周期任务创建成功")
+}
+
+// 获取当前用户的周期任务列表
+func (h *ScheduleHandler) ListSchedules(c *gin.Context) {
+	user, ok := middleware.CurrentUser(c)
+	if !ok {
+		util.ForbiddenResponse(c, "无法查询周期任务", "请使用用户API密钥查询周期任务")
+		return
+	}
+
+	schedules, err := h.scheduleService.ListSchedules(c.Request.Context(), user.ID)
+	if err != nil {
+		util.InternalServerErrorResponse(c, "获取周期任务列表失败", err.Error())
+		return
+	}
+
+	util.SuccessResponse(c, gin.H{
+		"schedules": schedules,
+		"count":     len(schedules),
+	}, "")
+}
+
+// 获取周期任务详情
+func (h *ScheduleHandler) GetSchedule(c *gin.Context) {
+	schedule, ok := h.getOwnedSchedule(c)
+	if !ok {
+		return
+	}
+
+	util.SuccessResponse(c, schedule, "")
+}
+
+// 更新周期任务，修改在Worker下次同步配置后生效
+func (h *ScheduleHandler) UpdateSchedule(c *gin.Context) {
+	schedule, ok := h.getOwnedSchedule(c)
+	if !ok {
+		return
+	}
+
+	var req ScheduleRequest
+	if errors := util.ValidateRequest(c, &req); len(errors) > 0 {
+		util.ValidationErrorResponse(c, errors)
+		return
+	}
+	if !checkCallbackURL(c, "", req.CallbackURL) || !h.checkTemplate(c, &req) {
+		return
+	}
+	req.applyTo(schedule)
+
+	if err := h.scheduleService.UpdateSchedule(c.Request.Context(), schedule); err != nil {
+		h.respondWithScheduleError(c, err, "更新周期任务失败")
+		return
+	}
+
+	util.SuccessResponse(c, schedule, "周期任务更新成功")
+}
+
+// 删除周期任务，已创建的任务不受影响
+func (h *ScheduleHandler) DeleteSchedule(c *gin.Context) {
+	schedule, ok := h.getOwnedSchedule(c)
+	if !ok {
+		return
+	}
+
+	deleted, err := h.scheduleService.DeleteSchedule(c.Request.Context(), schedule.UserID, schedule.ID)
+	if err != nil {
+		util.InternalServerErrorResponse(c, "删除周期任务失败", err.Error())
+		return
+	}
+	if !deleted {
+		util.NotFoundResponse(c, "周期任务不存在", "周期任务ID: "+schedule.ID)
+		return
+	}
+
+	util.SuccessResponse(c, nil, "周期任务删除成功")
+}
+
+// getOwnedSchedule 获取当前用户的周期任务，失败时已写入错误响应
+func (h *ScheduleHandler) getOwnedSchedule(c *gin.Context) (*models.Schedule, bool) {
+	user, ok := middleware.CurrentUser(c)
+	if !ok {
+		util.ForbiddenResponse(c, "无法访问周期任务", "请使用用户API密钥访问周期任务")
+		return nil, false
+	}
+
+	scheduleID := c.Param("schedule_id")
+	schedule, err := h.scheduleService.GetSchedule(c.Request.Context(), user.ID, scheduleID)
+	if err != nil {
+		if errors.Is(err, service.ErrScheduleNotFound) {
+			util.NotFoundResponse(c, "周期任务不存在", "周期任务ID: "+scheduleID)
+		} else {
+			util.InternalServerErrorResponse(c, "获取周期任务失败", err.Error())
+		}
+		return nil, false
+	}
+	return schedule, true
+}
+
+// respondWithScheduleError 将周期任务服务的错误转换为响应
+func (h *ScheduleHandler) respondWithScheduleError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidSchedule):
+		util.BadRequestResponse(c, message, err.Error())
+	case errors.Is(err, service.ErrScheduleLimitExceeded):
+		util.ErrorResponse(c, http.StatusConflict, message, err.Error())
+	default:
+		util.InternalServerErrorResponse(c, message, err.Error())
+	}
+}
+
+// checkTemplate 按模型声明的参数规则校验任务模板，避免无效模板在每次触发时扣费后失败，校验失败时已写入错误响应
+func (h *ScheduleHandler) checkTemplate(c *gin.Context, r *ScheduleRequest) bool {
+	capability, msg, detail := checkModelCapability(h.registry, r.Type, r.Provider, r.Model)
+	if msg != "" {
+		util.BadRequestResponse(c, msg, detail)
+		return false
+	}
+	if errs := service.ValidateModelInput(capability, r.modelInput()); len(errs) > 0 {
+		util.ValidationErrorResponse(c, errs)
+		return false
+	}
+	return true
+}
+
+// modelInput 构建用于模型参数校验的模板输入，零值视为未填写
+func (r *ScheduleRequest) modelInput() map[string]interface{} {
+	input := map[string]interface{}{
+		"prompt":       r.Prompt,
+		"aspect_ratio": r.AspectRatio,
+	}
+	if r.N != 0 {
+		input["n"] = r.N
+	}
+	return input
+}
+
+// applyTo 将请求中的配置写入周期任务模板
+func (r *ScheduleRequest) applyTo(schedule *models.Schedule) {
+	schedule.Name = r.Name
+	schedule.Cron = r.Cron
+	schedule.Timezone = r.Timezone
+	schedule.Enabled = r.Enabled == nil || *r.Enabled
+	schedule.Type = r.Type
+	schedule.Provider = r.Provider
+	schedule.Model = r.Model
+	schedule.Prompt = r.Prompt
+	schedule.AspectRatio = r.AspectRatio
+	schedule.N = r.N
+	schedule.CallbackURL = r.CallbackURL
+	schedule.CallbackSecret = r.CallbackSecret
+}
@@ -3,27 +3,49 @@ package handlers
 import (
 	"github.com/gin-gonic/gin"
 
+	"volcengine-go-server/api/middleware"
+	"volcengine-go-server/config"
 	"volcengine-go-server/internal/models"
 	"volcengine-go-server/internal/service"
 	"volcengine-go-server/internal/util"
 )
 
 type UserHandler struct {
-	userService *service.UserService
+	userService   *service.UserService
+	apiKeyService *service.APIKeyService
+	creditService *service.CreditService
 }
 
-func NewUserHandler(userService *service.UserService) *UserHandler {
-	return &UserHandler{userService: userService}
+func NewUserHandler(
+	userService *service.UserService,
+	apiKeyService *service.APIKeyService,
+	creditService *service.CreditService,
+) *UserHandler {
+	return &UserHandler{
+		userService:   userService,
+		apiKeyService: apiKeyService,
+		creditService: creditService,
+	}
 }
 
 type CreateUserRequest struct {
 	Email string `json:"email" binding:"required,email,max=100"`
 	Name  string `json:"name" binding:"required,min=2,max=50"`
+	Tier  string `json:"tier" binding:"omitempty,oneof=free pro"` // 默认free
 }
 
 type UpdateUserRequest struct {
 	Email string `json:"email" binding:"omitempty,email,max=100"`
 	Name  string `json:"name" binding:"omitempty,min=2,max=50"`
+	Tier  string `json:"tier" binding:"omitempty,oneof=free pro"` // 仅管理员可修改
+}
+
+type CreateAPIKeyRequest struct {
+	Name string `json:"name" binding:"required,min=1,max=50"`
+}
+
+type AddCreditsRequest struct {
+	Amount int64 `json:"amount" binding:"required"` // 不能为0，负数表示扣减
 }
 
 // 创建用户
@@ -43,10 +65,17 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 		return
 	}
 
+	tier := req.Tier
+	if tier == "" {
+		tier = config.UserTierFree
+	}
+
 	// 创建用户对象
 	user := &models.User{
-		Email: req.Email,
-		Name:  req.Name,
+		Email:   req.Email,
+		Name:    req.Name,
+		Credits: config.DefaultUserCredits,
+		Tier:    tier,
 	}
 
 	err = h.userService.CreateUser(c.Request.Context(), user)
@@ -65,6 +94,10 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 		util.BadRequestResponse(c, "用户ID不能为空", "")
 		return
 	}
+	if !middleware.CanAccessUser(c, userID) {
+		util.ForbiddenResponse(c, "权限不足", "只能访问自己的用户信息")
+		return
+	}
 
 	user, err := h.userService.GetUserByID(c.Request.Context(), userID)
 	if err != nil {
@@ -99,6 +132,10 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		util.BadRequestResponse(c, "用户ID不能为空", "")
 		return
 	}
+	if !middleware.CanAccessUser(c, userID) {
+		util.ForbiddenResponse(c, "权限不足", "只能访问自己的用户信息")
+		return
+	}
 
 	var req UpdateUserRequest
 
@@ -107,6 +144,10 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		util.ValidationErrorResponse(c, errors)
 		return
 	}
+	if req.Tier != "" && !middleware.IsAdmin(c) {
+		util.ForbiddenResponse(c, "权限不足", "只有管理员可以修改用户等级")
+		return
+	}
 
 	// 获取现有用户信息
 	user, err := h.userService.GetUserByID(c.Request.Context(), userID)
@@ -122,6 +163,9 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 	if req.Name != "" {
 		user.Name = req.Name
 	}
+	if req.Tier != "" {
+		user.Tier = req.Tier
+	}
 
 	err = h.userService.UpdateUser(c.Request.Context(), user)
 	if err != nil {
@@ -139,6 +183,10 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 		util.BadRequestResponse(c, "用户ID不能为空", "")
 		return
 	}
+	if !middleware.CanAccessUser(c, userID) {
+		util.ForbiddenResponse(c, "权限不足", "只能访问自己的用户信息")
+		return
+	}
 
 	err := h.userService.DeleteUser(c.Request.Context(), userID)
 	if err != nil {
@@ -148,3 +196,116 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 
 	util.SuccessResponse(c, nil, "用户删除成功")
 }
+
+// 创建API密钥，明文密钥只在本次响应中返回
+func (h *UserHandler) CreateAPIKey(c *gin.Context) {
+	userID := c.Param("id")
+	if !middleware.CanAccessUser(c, userID) {
+		util.ForbiddenResponse(c, "权限不足", "只能管理自己的API密钥")
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if errors := util.ValidateRequest(c, &req); len(errors) > 0 {
+		util.ValidationErrorResponse(c, errors)
+		return
+	}
+
+	key, rawKey, err := h.apiKeyService.CreateAPIKey(c.Request.Context(), userID, req.Name)
+	if err != nil {
+		util.NotFoundResponse(c, "创建API密钥失败", err.Error())
+		return
+	}
+
+	util.CreatedResponse(c, gin.H{
+		"api_key": rawKey,
+		"key":     key,
+	}, "API密钥创建成功，请妥善保存，密钥不会再次显示")
+}
+
+// 获取API密钥列表
+func (h *UserHandler) ListAPIKeys(c *gin.Context) {
+	userID := c.Param("id")
+	if !middleware.CanAccessUser(c, userID) {
+		util.ForbiddenResponse(c, "权限不足", "只能管理自己的API密钥")
+		return
+	}
+
+	keys, err := h.apiKeyService.ListAPIKeys(c.Request.Context(), userID)
+	if err != nil {
+		util.InternalServerErrorResponse(c, "获取API密钥列表失败", err.Error())
+		return
+	}
+
+	util.SuccessResponse(c, gin.H{
+		"keys":  keys,
+		"count": len(keys),
+	}, "")
+}
+
+// 吊销API密钥
+func (h *UserHandler) RevokeAPIKey(c *gin.Context) {
+	userID := c.Param("id")
+	if !middleware.CanAccessUser(c, userID) {
+		util.ForbiddenResponse(c, "权限不足", "只能管理自己的API密钥")
+		return
+	}
+
+	revoked, err := h.apiKeyService.RevokeAPIKey(c.Request.Context(), userID, c.Param("key_id"))
+	if err != nil {
+		util.InternalServerErrorResponse(c, "吊销API密钥失败", err.Error())
+		return
+	}
+	if !revoked {
+		util.NotFoundResponse(c, "API密钥不存在", "密钥不存在或已被吊销")
+		return
+	}
+
+	util.SuccessResponse(c, nil, "API密钥已吊销")
+}
+
+// 查询用户积分用量，period可选 day、month（默认）、all
+func (h *UserHandler) GetUserUsage(c *gin.Context) {
+	userID := c.Param("id")
+	if !middleware.CanAccessUser(c, userID) {
+		util.ForbiddenResponse(c, "权限不足", "只能查询自己的用量")
+		return
+	}
+
+	period := c.DefaultQuery("period", config.UsagePeriodMonth)
+	if period != config.UsagePeriodDay && period != config.UsagePeriodMonth && period != config.UsagePeriodAll {
+		util.BadRequestResponse(c, "不支持的统计周期", "period可选值: day, month, all")
+		return
+	}
+
+	summary, err := h.creditService.GetUsage(c.Request.Context(), userID, period)
+	if err != nil {
+		util.NotFoundResponse(c, "获取用量失败", err.Error())
+		return
+	}
+
+	util.SuccessResponse(c, summary, "")
+}
+
+// 调整用户积分（管理员）
+func (h *UserHandler) AddCredits(c *gin.Context) {
+	userID := c.Param("id")
+
+	var req AddCreditsRequest
+	if errors := util.ValidateRequest(c, &req); len(errors) > 0 {
+		util.ValidationErrorResponse(c, errors)
+		return
+	}
+
+	user, err := h.creditService.AddCredits(c.Request.Context(), userID, req.Amount)
+	if err != nil {
+		util.NotFoundResponse(c, "调整积分失败", err.Error())
+		return
+	}
+
+	util.SuccessResponse(c, gin.H{
+		"user_id": user.ID,
+		"amount":  req.Amount,
+		"credits": user.Credits,
+	}, "积分调整成功")
+}
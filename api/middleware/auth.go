@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"errors"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"volcengine-go-server/internal/models"
+	"volcengine-go-server/internal/service"
+	"volcengine-go-server/internal/util"
+)
+
+// 认证信息在gin.Context中的键名
+const (
+	contextKeyUser     = "auth_user"
+	contextKeyAPIKeyID = "auth_api_key_id"
+	contextKeyIsAdmin  = "auth_is_admin"
+)
+
+// APIKeyAuth API密钥认证中间件
+// 支持 Authorization: Bearer <key> 和 X-API-Key: <key> 两种方式
+// 用户密钥解析为对应的models.User；管理员密钥不绑定用户，只能访问管理接口或代为查询
+func APIKeyAuth(apiKeyService *service.APIKeyService, adminAPIKey string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rawKey := extractAPIKey(c)
+		if rawKey == "" {
+			util.UnauthorizedResponse(c, "缺少API密钥", "请通过 Authorization: Bearer <key> 或 X-API-Key 请求头提供API密钥")
+			c.Abort()
+			return
+		}
+
+		if adminAPIKey != "" && subtle.ConstantTimeCompare([]byte(rawKey), []byte(adminAPIKey)) == 1 {
+			c.Set(contextKeyIsAdmin, true)
+			c.Next()
+			return
+		}
+
+		user, key, err := apiKeyService.Authenticate(c.Request.Context(), rawKey)
+		if err != nil {
+			if errors.Is(err, service.ErrInvalidAPIKey) {
+				util.UnauthorizedResponse(c, "API密钥无效", "密钥不存在或已被吊销")
+			} else {
+				util.InternalServerErrorResponse(c, "API密钥校验失败", err.Error())
+			}
+			c.Abort()
+			return
+		}
+
+		c.Set(contextKeyUser, user)
+		c.Set(contextKeyAPIKeyID, key.ID)
+		c.Next()
+	}
+}
+
+// RequireAdmin 要求使用管理员密钥访问
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !IsAdmin(c) {
+			util.ForbiddenResponse(c, "权限不足", "该接口需要管理员密钥")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// CurrentUser 获取当前请求认证的用户，管理员密钥请求返回false
+func CurrentUser(c *gin.Context) (*models.User, bool) {
+	value, exists := c.Get(contextKeyUser)
+	if !exists {
+		return nil, false
+	}
+	user, ok := value.(*models.User)
+	return user, ok
+}
+
+// CurrentAPIKeyID 获取当前请求使用的API密钥ID
+func CurrentAPIKeyID(c *gin.Context) string {
+	return c.GetString(contextKeyAPIKeyID)
+}
+
+// IsAdmin 当前请求是否使用管理员密钥
+func IsAdmin(c *gin.Context) bool {
+	return c.GetBool(contextKeyIsAdmin)
+}
+
+// CanAccessUser 当前请求是否有权访问指定用户的数据（本人或管理员）
+func CanAccessUser(c *gin.Context, userID string) bool {
+	if IsAdmin(c) {
+		return true
+	}
+	user, ok := CurrentUser(c)
+	return ok && user.ID == userID
+}
+
+// extractAPIKey 从请求头中提取API密钥
+func extractAPIKey(c *gin.Context) string {
+	if auth := c.GetHeader("Authorization"); auth != "" {
+		if token, ok := strings.CutPrefix(auth, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+	return strings.TrimSpace(c.GetHeader("X-API-Key"))
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"volcengine-go-server/api/handlers"
+	"volcengine-go-server/api/middleware"
 	"volcengine-go-server/internal/util"
 )
 
@@ -11,6 +12,10 @@ func SetupRoutes(
 	r *gin.Engine,
 	aiHandler *handlers.AIHandler,
 	userHandler *handlers.UserHandler,
+	queueHandler *handlers.QueueHandler,
+	scheduleHandler *handlers.ScheduleHandler,
+	pipelineHandler *handlers.PipelineHandler,
+	authMiddleware gin.HandlerFunc,
 ) {
 	// 健康检查
 	r.GET("/health", func(c *gin.Context) {
@@ -19,31 +24,74 @@ func SetupRoutes(
 		}, "服务正常运行")
 	})
 
-	// API版本分组
-	v1 := r.Group("/api/v1")
+	// API版本分组，所有接口都需要API密钥认证
+	v1 := r.Group("/api/v1", authMiddleware)
 	{
 		// 用户管理
 		users := v1.Group("/users")
 		{
-			users.POST("", userHandler.CreateUser)
+			users.POST("", middleware.RequireAdmin(), userHandler.CreateUser)
 			users.GET("/:id", userHandler.GetUser)
-			users.GET("", userHandler.GetUserByEmail) // ?email=xxx
+			users.GET("", middleware.RequireAdmin(), userHandler.GetUserByEmail) // ?email=xxx
 			users.PUT("/:id", userHandler.UpdateUser)
 			users.DELETE("/:id", userHandler.DeleteUser)
+
+			// API密钥管理（本人或管理员）
+			users.POST("/:id/api-keys", userHandler.CreateAPIKey)
+			users.GET("/:id/api-keys", userHandler.ListAPIKeys)
+			users.DELETE("/:id/api-keys/:key_id", userHandler.RevokeAPIKey)
+
+			// 积分与用量
+			users.GET("/:id/usage", userHandler.GetUserUsage)
+			users.POST("/:id/credits", middleware.RequireAdmin(), userHandler.AddCredits)
 		}
 
 		// AI服务
 		ai := v1.Group("/ai")
 		{
+			// 模型目录 - 各服务商支持的模型和参数限制
+			ai.GET("/models", aiHandler.ListModels)
+
 			// AI任务创建 - 类型特定接口
 			ai.POST("/image/task", aiHandler.CreateImageTask) // 创建图像生成任务
-			ai.POST("/text/task", aiHandler.CreateTextTask)   // 创建文本生成任务 (TODO: 待实现)
+			ai.POST("/text/task", aiHandler.CreateTextTask)   // 创建文本生成任务
 			ai.POST("/video/task", aiHandler.CreateVideoTask) // 创建视频生成任务
 
 			// 统一任务管理 - 通用接口
-			ai.GET("/task/result/:task_id", aiHandler.GetTaskResult) // 查询任务结果（通用）
-			ai.DELETE("/task/:task_id", aiHandler.DeleteTask)        // 删除任务（通用）
-			ai.GET("/tasks", aiHandler.GetUserTasks)                 // 获取用户任务列表（通用，支持类型过滤）
+			ai.GET("/task/result/:task_id", aiHandler.GetTaskResult)              // 查询任务结果（通用）
+			ai.GET("/task/:task_id/stream", aiHandler.StreamTaskResult)           // 流式获取文本任务结果（SSE）
+			ai.POST("/task/:task_id/cancel", aiHandler.CancelTask)                // 取消任务（通用）
+			ai.POST("/task/:task_id/reschedule", aiHandler.RescheduleTask)        // 修改定时任务执行时间
+			ai.GET("/task/:task_id/webhooks", aiHandler.GetTaskWebhookDeliveries) // 查询任务回调投递历史
+			ai.DELETE("/task/:task_id", aiHandler.DeleteTask)                     // 删除任务（通用）
+			ai.GET("/tasks", aiHandler.GetUserTasks)                              // 获取用户任务列表（通用，支持类型过滤）
+
+			// 批量任务
+			ai.POST("/batch", aiHandler.CreateBatch)       // 批量提交任务
+			ai.GET("/batch/:batch_id", aiHandler.GetBatch) // 查询批量任务进度和结果
+
+			// 周期任务 - 按cron表达式定期创建任务
+			ai.POST("/schedules", scheduleHandler.CreateSchedule)
+			ai.GET("/schedules", scheduleHandler.ListSchedules)
+			ai.GET("/schedules/:schedule_id", scheduleHandler.GetSchedule)
+			ai.PUT("/schedules/:schedule_id", scheduleHandler.UpdateSchedule)
+			ai.DELETE("/schedules/:schedule_id", scheduleHandler.DeleteSchedule)
+
+			// 流水线 - 多个步骤依次执行，后续步骤引用之前步骤的输出
+			ai.POST("/pipelines", pipelineHandler.CreatePipeline)
+			ai.GET("/pipelines", pipelineHandler.ListPipelines)
+			ai.GET("/pipelines/:pipeline_id", pipelineHandler.GetPipeline)
+			ai.POST("/pipelines/:pipeline_id/cancel", pipelineHandler.CancelPipeline)
+		}
+
+		// 运维管理（仅管理员）
+		admin := v1.Group("/admin", middleware.RequireAdmin())
+		{
+			admin.GET("/queues", queueHandler.GetQueueStats)                                   // 各队列统计
+			admin.GET("/queues/:queue/tasks", queueHandler.ListQueueTasks)                     // 按状态列出队列任务
+			admin.POST("/queues/:queue/tasks/:task_id/retry", queueHandler.RetryQueueTask)     // 立即重新执行任务
+			admin.POST("/queues/:queue/tasks/:task_id/archive", queueHandler.ArchiveQueueTask) // 归档任务
+			admin.DELETE("/queues/:queue/tasks/:task_id", queueHandler.DeleteQueueTask)        // 删除任务
 		}
 	}
 
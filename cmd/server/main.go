@@ -17,8 +17,11 @@ import (
 	"volcengine-go-server/api/routes"
 	"volcengine-go-server/config"
 	"volcengine-go-server/internal/core"
+	"volcengine-go-server/internal/provider"
 	"volcengine-go-server/internal/repository"
 	"volcengine-go-server/internal/service"
+	"volcengine-go-server/internal/service/volcengine"
+	"volcengine-go-server/internal/storage"
 	"volcengine-go-server/pkg/logger"
 )
 
@@ -68,17 +71,53 @@ func main() {
 	// 初始化基础服务（API服务器只需要这些）
 	userService := service.NewUserService(db)
 	taskService := service.NewTaskService(db)
+	webhookService := service.NewWebhookService(db)
+	apiKeyService := service.NewAPIKeyService(db)
+	creditService := service.NewCreditService(db)
+	idemService := service.NewIdempotencyService(db)
+	scheduleService := service.NewScheduleService(db)
+	batchService := service.NewBatchService(db)
+	pipelineService := service.NewPipelineService(db)
+
+	// 初始化媒体存储：图像编辑上传的原图转存后以链接交给服务商，需与Worker使用同一存储
+	mediaStorage, err := storage.New(cfg.Storage)
+	if err != nil {
+		log.Fatal("初始化媒体存储失败: ", err)
+	}
+	mediaService := service.NewMediaService(mediaStorage)
 
-	// 创建空的服务注册器（API服务器不需要注册任何提供商）
+	// 创建服务注册器：API服务器不处理任务，不注册分发器，只注册Worker上可用服务商的能力描述，
+	// 用于模型目录接口和创建任务前校验服务商和模型
 	serviceRegistry := core.NewServiceRegistry()
-	// 注意：API服务器不注册任何AI服务提供商，因为它不处理任务
+	serviceRegistry.RegisterCapabilities(volcengine.Capabilities())
+	if cfg.AI.OpenAIAPIKey != "" {
+		serviceRegistry.RegisterCapabilities(provider.OpenAICapabilities())
+	}
 
 	// 初始化队列客户端（只用于发送任务到队列）
 	queueClient := core.NewTaskQueue(cfg.Redis.URL, taskService, serviceRegistry)
 
+	// 初始化流水线执行器（用于入队流水线步骤和取消流水线），用户取消步骤任务时推进流水线
+	// 管理员删除或归档流水线队列任务时由执行器结束流水线
+	pipelineRunner := core.NewPipelineRunner(queueClient, pipelineService, taskService, creditService, userService)
+	taskService.AddNotifier(pipelineRunner)
+	queueClient.SetPipelineRunner(pipelineRunner)
+
+	// 管理员删除或归档队列任务、批量任务入队失败等在API服务器上结束的任务同样需要回调，投递由Worker执行
+	taskService.AddNotifier(core.NewWebhookDispatcher(queueClient, taskService, webhookService))
+
+	// 初始化任务流订阅通道（用于SSE转发Worker发布的增量结果）
+	taskStream := core.NewTaskStream(cfg.Redis.URL)
+	defer taskStream.Close()
+	// 在API服务器上结束的文本任务（如取消、管理员删除队列任务）同样通知流式订阅者
+	taskService.AddNotifier(taskStream)
+
 	// 初始化处理器
-	aiHandler := handlers.NewAIHandler(taskService, queueClient)
-	userHandler := handlers.NewUserHandler(userService)
+	aiHandler := handlers.NewAIHandler(taskService, webhookService, creditService, idemService, batchService, queueClient, taskStream, serviceRegistry, mediaService)
+	userHandler := handlers.NewUserHandler(userService, apiKeyService, creditService)
+	queueHandler := handlers.NewQueueHandler(queueClient)
+	scheduleHandler := handlers.NewScheduleHandler(scheduleService, serviceRegistry)
+	pipelineHandler := handlers.NewPipelineHandler(pipelineService, creditService, pipelineRunner, serviceRegistry)
 
 	// 设置Gin模式
 	if cfg.Environment == "production" {
@@ -101,8 +140,16 @@ func main() {
 	r.Use(middleware.Recovery())
 	r.Use(middleware.RateLimiterMiddleware())
 
+	// 本地存储的转存结果由API服务器直接提供访问
+	if cfg.Storage.Driver == config.StorageDriverLocal {
+		r.Static(config.MediaRoutePrefix, cfg.Storage.LocalDir)
+	}
+
 	// 设置路由
-	routes.SetupRoutes(r, aiHandler, userHandler)
+	if cfg.Auth.AdminAPIKey == "" {
+		log.Warn("未配置ADMIN_API_KEY，无法通过接口创建用户和签发API密钥")
+	}
+	routes.SetupRoutes(r, aiHandler, userHandler, queueHandler, scheduleHandler, pipelineHandler, middleware.APIKeyAuth(apiKeyService, cfg.Auth.AdminAPIKey))
 
 	// 创建HTTP服务器
 	srv := &http.Server{
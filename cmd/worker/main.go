@@ -12,9 +12,11 @@ import (
 
 	"volcengine-go-server/config"
 	"volcengine-go-server/internal/core"
+	"volcengine-go-server/internal/provider"
 	"volcengine-go-server/internal/repository"
 	"volcengine-go-server/internal/service"
 	"volcengine-go-server/internal/service/volcengine"
+	"volcengine-go-server/internal/storage"
 	"volcengine-go-server/pkg/logger"
 )
 
@@ -62,10 +64,26 @@ func main() {
 
 	// 初始化服务层
 	taskService := service.NewTaskService(db)
+
+	// 初始化媒体存储：图像和视频结果在任务完成前转存，避免服务商的临时地址过期
+	mediaStorage, err := storage.New(cfg.Storage)
+	if err != nil {
+		logrus.Fatal("初始化媒体存储失败: ", err)
+	}
+	taskService.SetMediaService(service.NewMediaService(mediaStorage))
+	logrus.Infof("媒体存储驱动: %s", cfg.Storage.Driver)
+
 	volcengineService := volcengine.NewVolcengineService(cfg.AI, taskService)
 
-	// 创建OpenAI服务（示例，如果需要的话）
-	// openaiService := service.NewOpenAIService("your-openai-api-key", taskService)
+	// 创建任务流发布通道，文本生成的增量结果通过Redis发布给API服务器
+	taskStream := core.NewTaskStream(cfg.Redis.URL)
+	defer taskStream.Close()
+	volcengineService.SetStreamPublisher(taskStream)
+
+	// 创建服务商调用限流器，令牌桶和熔断状态保存在Redis中，所有Worker进程共享
+	providerLimiter := core.NewProviderLimiter(cfg.Redis.URL)
+	defer providerLimiter.Close()
+	volcengineService.SetCallLimiter(providerLimiter)
 
 	// 创建服务注册器
 	serviceRegistry := core.NewServiceRegistry()
@@ -74,15 +92,54 @@ func main() {
 	volcengineProvider := volcengine.NewProvider(volcengineService, taskService)
 	serviceRegistry.RegisterDispatcher(volcengineProvider)
 
-	// 创建并注册OpenAI任务分发器（示例）
-	// openaiProvider := provider.NewOpenAIProvider(openaiService, taskService)
-	// serviceRegistry.RegisterDispatcher(openaiProvider)
+	// 配置了OpenAI API Key时创建并注册OpenAI任务分发器
+	if cfg.AI.OpenAIAPIKey != "" {
+		openaiService := service.NewOpenAIService(cfg.AI, taskService)
+		openaiService.SetCallLimiter(providerLimiter)
+		serviceRegistry.RegisterDispatcher(provider.NewOpenAIProvider(openaiService, taskService))
+		logrus.Infof("OpenAI接口地址: %s", cfg.AI.OpenAIBaseURL)
+	}
 
 	logrus.Infof("已注册AI服务分发器: %v", getRegisteredProviders(serviceRegistry))
 
 	// 初始化队列（使用服务注册器）
 	queueClient := core.NewTaskQueue(cfg.Redis.URL, taskService, serviceRegistry)
 
+	// 视频任务提交后立即释放Worker，由队列中的状态检查任务按自适应间隔查询结果
+	volcengineService.SetStatusCheckScheduler(queueClient)
+
+	// 文本任务将重试时发布retrying事件，最终失败或取消时作为任务结束通知发布error事件
+	queueClient.SetTaskStream(taskStream)
+	taskService.AddNotifier(taskStream)
+
+	// 创建任务回调投递器：任务结束时入队回调，由Worker执行投递和重试
+	webhookService := service.NewWebhookService(db)
+	webhookDispatcher := core.NewWebhookDispatcher(queueClient, taskService, webhookService)
+	taskService.AddNotifier(webhookDispatcher)
+	queueClient.RegisterHandler(core.TypeWebhookDelivery, webhookDispatcher.HandleDelivery)
+
+	// 创建周期任务执行器：调度器按cron表达式入队触发任务，由Worker按模板创建生成任务
+	scheduleRunner := core.NewScheduleRunner(
+		queueClient,
+		service.NewScheduleService(db),
+		taskService,
+		service.NewCreditService(db),
+		service.NewUserService(db),
+	)
+	queueClient.RegisterHandler(core.TypeScheduleTrigger, scheduleRunner.HandleTrigger)
+
+	// 创建流水线执行器：步骤任务结束时入队推进任务，由Worker记录输出并启动下一步骤
+	pipelineRunner := core.NewPipelineRunner(
+		queueClient,
+		service.NewPipelineService(db),
+		taskService,
+		service.NewCreditService(db),
+		service.NewUserService(db),
+	)
+	taskService.AddNotifier(pipelineRunner)
+	queueClient.RegisterHandler(core.TypePipelineStep, pipelineRunner.HandleStep)
+	queueClient.RegisterHandler(core.TypePipelineAdvance, pipelineRunner.HandleAdvance)
+
 	// 创建上下文用于优雅关闭
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -95,6 +152,11 @@ func main() {
 	// 启动队列工作器
 	go queueClient.StartWorker(ctx)
 
+	// 启动周期任务调度器
+	if err := scheduleRunner.Start(); err != nil {
+		logrus.Fatal("启动周期任务调度器失败: ", err)
+	}
+
 	// 优雅关闭
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -104,6 +166,9 @@ func main() {
 	// 取消上下文，停止日志管理器和队列工作器
 	cancel()
 
+	// 停止周期任务调度器，不再入队新的触发任务
+	scheduleRunner.Shutdown()
+
 	// 等待一段时间让工作器完成当前任务
 	time.Sleep(2 * time.Second)
 
@@ -12,6 +12,8 @@ type Config struct {
 	Database    DatabaseConfig
 	Redis       RedisConfig
 	AI          AIConfig
+	Storage     StorageConfig
+	Auth        AuthConfig
 }
 
 type DatabaseConfig struct {
@@ -28,11 +30,47 @@ type AIConfig struct {
 	VolcengineAccessKey string // Access Key ID (备用)
 	VolcengineSecretKey string // Secret Access Key (备用)
 	Timeout             string // 请求超时时间
+
+	// OpenAI兼容接口配置，API Key为空时不注册OpenAI分发器
+	OpenAIAPIKey  string // OpenAI API Key
+	OpenAIBaseURL string // 接口地址，可指向本地部署的OpenAI兼容服务
+}
+
+// AuthConfig 认证配置
+type AuthConfig struct {
+	// 管理员密钥，用于创建用户、签发API密钥等管理操作，为空时禁用管理员认证
+	AdminAPIKey string
+}
+
+// StorageConfig 生成结果转存配置
+type StorageConfig struct {
+	Driver        string // 存储驱动: local 或 s3
+	PublicBaseURL string // 对外访问地址前缀，为空时S3使用 endpoint/bucket
+
+	// 本地文件系统存储
+	LocalDir string
+
+	// S3兼容对象存储（AWS S3、火山引擎TOS、MinIO等）
+	S3Endpoint     string
+	S3Region       string
+	S3Bucket       string
+	S3AccessKey    string
+	S3SecretKey    string
+	S3UsePathStyle bool // 使用 endpoint/bucket/key 形式的路径访问，MinIO需要开启
 }
 
 func New() *Config {
+	port := getEnv("PORT", "8080")
+	storageDriver := getEnv("STORAGE_DRIVER", StorageDriverLocal)
+
+	// 本地存储默认由API服务器提供静态访问，S3默认使用对象存储地址
+	defaultPublicBaseURL := ""
+	if storageDriver == StorageDriverLocal {
+		defaultPublicBaseURL = "http://localhost:" + port + MediaRoutePrefix
+	}
+
 	return &Config{
-		Port:        getEnv("PORT", "8080"),
+		Port:        port,
 		Environment: getEnv("ENVIRONMENT", "development"),
 		LogLevel:    getEnv("LOG_LEVEL", "info"),
 		Database: DatabaseConfig{
@@ -46,6 +84,22 @@ func New() *Config {
 			VolcengineAccessKey: getEnv("VOLCENGINE_ACCESS_KEY", ""),
 			VolcengineSecretKey: getEnv("VOLCENGINE_SECRET_KEY", ""),
 			Timeout:             getEnv("AI_TIMEOUT", "30s"),
+			OpenAIAPIKey:        getEnv("OPENAI_API_KEY", ""),
+			OpenAIBaseURL:       getEnv("OPENAI_BASE_URL", "https://api.openai.com/v1"),
+		},
+		Storage: StorageConfig{
+			Driver:         storageDriver,
+			PublicBaseURL:  getEnv("STORAGE_PUBLIC_BASE_URL", defaultPublicBaseURL),
+			LocalDir:       getEnv("STORAGE_LOCAL_DIR", "./data/media"),
+			S3Endpoint:     getEnv("S3_ENDPOINT", ""),
+			S3Region:       getEnv("S3_REGION", "us-east-1"),
+			S3Bucket:       getEnv("S3_BUCKET", ""),
+			S3AccessKey:    getEnv("S3_ACCESS_KEY", ""),
+			S3SecretKey:    getEnv("S3_SECRET_KEY", ""),
+			S3UsePathStyle: getEnv("S3_USE_PATH_STYLE", "true") == "true",
+		},
+		Auth: AuthConfig{
+			AdminAPIKey: getEnv("ADMIN_API_KEY", ""),
 		},
 	}
 }
@@ -61,6 +115,18 @@ func (c *Config) Validate() error {
 	if c.Redis.URL == "" {
 		return fmt.Errorf("REDIS_URL is required")
 	}
+	switch c.Storage.Driver {
+	case StorageDriverLocal:
+		if c.Storage.LocalDir == "" {
+			return fmt.Errorf("STORAGE_LOCAL_DIR is required")
+		}
+	case StorageDriverS3:
+		if c.Storage.S3Endpoint == "" || c.Storage.S3Bucket == "" {
+			return fmt.Errorf("S3_ENDPOINT and S3_BUCKET are required when STORAGE_DRIVER=s3")
+		}
+	default:
+		return fmt.Errorf("unsupported STORAGE_DRIVER: %s", c.Storage.Driver)
+	}
 	return nil
 }
 
@@ -1,15 +1,24 @@
 package config
 
+import "time"
+
 // AI模型常量
 const (
 	// 火山引擎豆包模型
 	VolcengineImageModel = "doubao-seedream-3-0-t2i-250415"
+	VolcengineTextModel  = "doubao-1-5-pro-32k-250115"
 
 	// 火山引擎即梦AI模型
 	VolcengineJimengImageModel = "jimeng_high_aes_general_v21_L" // 即梦AI图生模型
 	VolcengineJimengVideoModel = "jimeng_vgfm_t2v_l20"           // 即梦AI文生视频模型
 	VolcengineJimengI2VModel   = "jimeng_vgfm_i2v_l20"           // 即梦AI图生视频模型
 
+	// 火山引擎图像编辑模型
+	VolcengineImageEditModel = "byteedit_v2.0" // SeedEdit指令编辑模型，按编辑指令修改原图
+
+	// OpenAI模型
+	OpenAIImageModel = "dall-e-3"
+	OpenAITextModel  = "gpt-4o-mini"
 )
 
 // 图像尺寸常量 - 火山引擎支持的尺寸
@@ -25,6 +34,19 @@ const (
 	DefaultImageSize = ImageSize1x1
 )
 
+// OpenAI图像尺寸常量 - DALL-E 3支持的尺寸
+const (
+	OpenAIImageSizeSquare    = "1024x1024" // 1:1 比例
+	OpenAIImageSizePortrait  = "1024x1792" // 竖图
+	OpenAIImageSizeLandscape = "1792x1024" // 横图
+)
+
+// 图像生成数量常量
+const (
+	DefaultImageN = 1 // 默认生成数量
+	MaxImageN     = 4 // 单个任务最多生成数量
+)
+
 // 即梦AI视频尺寸比例常量
 const (
 	VideoAspectRatio16x9    = "16:9" // 1280×720 (默认)
@@ -36,6 +58,19 @@ const (
 	DefaultVideoAspectRatio = VideoAspectRatio16x9
 )
 
+// 文本生成默认参数
+const (
+	DefaultTextMaxTokens   = 4096 // 默认最大输出token数
+	DefaultTextTemperature = 0.7  // 默认温度参数
+)
+
+// 图像编辑默认参数
+const (
+	DefaultImageEditStrength = 0.5      // 编辑强度，取值[0, 1]，越大越贴近编辑指令，越小越接近原图
+	DefaultImageEditSeed     = -1       // 随机种子，-1表示随机生成
+	SourceImageMaxBytes      = 10 << 20 // 上传原图的最大字节数
+)
+
 // 视频生成默认参数
 const (
 	DefaultVideoSeed = -1 // 随机种子，-1表示随机生成
@@ -50,10 +85,33 @@ const (
 
 // 任务状态常量
 const (
+	TaskStatusScheduled  = "scheduled" // 定时任务，到达执行时间前不会被Worker处理
 	TaskStatusPending    = "pending"
 	TaskStatusProcessing = "processing"
 	TaskStatusCompleted  = "completed"
 	TaskStatusFailed     = "failed"
+	TaskStatusCancelled  = "cancelled"
+)
+
+// 任务状态机配置常量
+const (
+	TaskHistoryMaxEntries     = 50 // 任务最多保留的状态变更记录数
+	TaskTransitionMaxAttempts = 3  // 状态比较交换因并发修改失败时的最大尝试次数
+
+	TaskProgressSubmitted     = 10 // 异步任务提交到服务商后的进度
+	TaskProgressMaxEstimate   = 95 // 完成前估算进度的上限
+	TaskProgressExpectedPolls = 12 // 异步任务通常完成前的状态检查次数，用于估算进度
+)
+
+// 任务错误码常量，任务失败时与错误信息一起返回给客户端
+const (
+	TaskErrorInvalidInput  = "invalid_input"            // 请求参数无效，重试不会成功
+	TaskErrorContentPolicy = "content_policy_violation" // 输入或输出内容未通过服务商审核
+	TaskErrorAuthFailed    = "auth_failed"              // 服务商鉴权失败或无权限
+	TaskErrorQuotaExceeded = "quota_exceeded"           // 服务商额度用尽或账户欠费
+	TaskErrorTransient     = "transient_error"          // 网络错误、服务商限流或服务端错误，可以重试
+	TaskErrorTimeout       = "timeout"                  // 服务商异步任务超时未完成
+	TaskErrorInternal      = "internal_error"           // 未分类的内部错误
 )
 
 // 默认提供商
@@ -68,3 +126,111 @@ const (
 	QueueDefaultWeight  = 3
 	QueueLowWeight      = 1
 )
+
+// 服务商异步任务配置常量
+const (
+	StatusCheckMaxAttempts   = 60       // 最大检查次数，与阻塞轮询的最大重试次数一致（约25分钟）
+	ProviderResponseMaxBytes = 16 << 10 // 任务上保存的服务商原始响应最大字节数
+)
+
+// 生成任务重试配置常量
+const (
+	GenerationMaxRetry = 3 // 可重试错误的最大重试次数，最后一次仍失败时才标记任务失败
+)
+
+// 服务商熔断配置常量，按服务商和模型分别统计
+// 进程内的健康状态（故障转移）和Redis中的共享熔断（接口调用）使用同一组阈值和冷却时间
+const (
+	ProviderHealthWindow            = 20               // 健康状态计算错误率的最近调用次数
+	ProviderBreakerMinSamples       = 10               // 健康状态按错误率熔断所需的最少调用次数
+	ProviderBreakerErrorRate        = 0.5              // 健康状态熔断的错误率阈值
+	ProviderBreakerFailureThreshold = 5                // 连续失败（共享熔断为时间窗口内过载）达到该次数时熔断
+	ProviderBreakerFailureWindow    = time.Minute      // 共享熔断统计过载次数的时间窗口
+	ProviderBreakerCooldown         = 30 * time.Second // 熔断后允许试探调用前的等待时间
+)
+
+// 服务商接口限流配置常量，令牌桶保存在Redis中，所有Worker进程共享
+const (
+	ProviderRateLimitMaxWait = 3 * time.Second // 令牌不足时最多等待的时间，超过时任务延迟后重新执行
+)
+
+// OpenAI接口配置常量
+const (
+	DefaultOpenAITimeout = 30 * time.Second // AI_TIMEOUT无效时使用的单次请求超时
+)
+
+// 流式输出配置常量
+const (
+	TaskStreamBufferTTL         = time.Hour        // 流式文本缓冲区保留时间
+	TaskStreamHeartbeatInterval = 15 * time.Second // SSE心跳及任务状态兜底检查间隔
+)
+
+// 任务回调配置常量
+const (
+	WebhookMaxRetry       = 8                // 最大重试次数
+	WebhookTimeout        = 10 * time.Second // 单次回调请求超时
+	WebhookRetryBaseDelay = 10 * time.Second // 首次重试间隔，之后按2倍递增
+	WebhookMaxRetryDelay  = time.Hour        // 最大重试间隔
+)
+
+// 定时任务配置常量
+const (
+	MaxScheduleAhead = 30 * 24 * time.Hour // 定时任务最多提前安排的时间
+)
+
+// 周期任务配置常量
+const (
+	MaxSchedulesPerUser     = 20               // 每个用户最多创建的周期任务数量
+	ScheduleMinInterval     = 10 * time.Minute // 相邻两次执行的最小间隔
+	ScheduleSyncInterval    = time.Minute      // Worker从数据库同步周期任务配置的间隔
+	ScheduleDuplicateWindow = 5 * time.Minute  // 同一周期任务在该时间内只执行一次，避免多个Worker的调度器重复触发
+)
+
+// 批量任务配置常量
+const (
+	MaxBatchSize            = 500 // 单次批量提交的最大任务数
+	BatchEnqueueConcurrency = 16  // 批量入队的并发数
+)
+
+// 流水线配置常量
+const (
+	MaxPipelineSteps = 5 // 单个流水线最多的步骤数
+)
+
+// 积分配置常量
+const (
+	DefaultUserCredits = 100 // 新用户初始积分
+)
+
+// 用量统计周期
+const (
+	UsagePeriodDay   = "day"
+	UsagePeriodMonth = "month"
+	UsagePeriodAll   = "all"
+)
+
+// 幂等配置常量
+const (
+	IdempotencyKeyHeader    = "Idempotency-Key"
+	IdempotencyKeyMaxLength = 255
+	IdempotencyKeyTTL       = 24 * time.Hour // 幂等记录保留时间
+	IdempotencyLockTimeout  = time.Minute    // 首次请求超过该时间仍未完成时允许重新处理
+)
+
+// API密钥配置常量
+const (
+	APIKeyPrefix           = "sk-"       // 密钥前缀
+	APIKeyRandomBytes      = 24          // 密钥随机部分字节数
+	APIKeyDisplayPrefixLen = 10          // 列表中展示的密钥前缀长度
+	APIKeyLastUsedInterval = time.Minute // 最近使用时间的更新间隔，避免每个请求都写库
+)
+
+// 媒体转存配置常量
+const (
+	StorageDriverLocal = "local"
+	StorageDriverS3    = "s3"
+
+	MediaRoutePrefix      = "/media"        // 本地存储的静态文件访问路径
+	MediaDownloadTimeout  = 5 * time.Minute // 下载生成结果的超时时间
+	MediaMaxDownloadBytes = 512 << 20       // 单个生成结果的最大字节数
+)
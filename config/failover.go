@@ -0,0 +1,47 @@
+package config
+
+// FailoverCandidate 故障转移候选，由服务商和模型组成
+type FailoverCandidate struct {
+	Provider string
+	Model    string
+}
+
+// FailoverPolicies 各任务类型可相互替代的服务商和模型，按优先级排列
+// 请求的服务商和模型在列表中时，失败后依次尝试列表中的其他候选；不在列表中的模型不做故障转移
+var FailoverPolicies = map[string][]FailoverCandidate{
+	"image": {
+		{Provider: DefaultAIProvider, Model: VolcengineImageModel},
+		{Provider: DefaultAIProvider, Model: VolcengineJimengImageModel},
+	},
+	"text": {
+		{Provider: DefaultAIProvider, Model: VolcengineTextModel},
+	},
+	"video": {
+		{Provider: DefaultAIProvider, Model: VolcengineJimengVideoModel},
+	},
+}
+
+// FailoverCandidates 获取任务的候选服务商和模型，第一个为请求的服务商和模型
+func FailoverCandidates(taskType, provider, model string) []FailoverCandidate {
+	primary := FailoverCandidate{Provider: provider, Model: model}
+	candidates := []FailoverCandidate{primary}
+
+	policy := FailoverPolicies[taskType]
+	inPolicy := false
+	for _, candidate := range policy {
+		if candidate == primary {
+			inPolicy = true
+			break
+		}
+	}
+	if !inPolicy {
+		return candidates
+	}
+
+	for _, candidate := range policy {
+		if candidate != primary {
+			candidates = append(candidates, candidate)
+		}
+	}
+	return candidates
+}
@@ -0,0 +1,27 @@
+package config
+
+// 积分计费配置：图像按张计费，视频和文本按任务计费
+// 键为模型名称，未配置的模型使用任务类型的默认价格
+var ModelCreditPrices = map[string]int64{
+	VolcengineImageModel:       4,
+	VolcengineJimengImageModel: 3,
+	VolcengineImageEditModel:   4,
+	VolcengineJimengVideoModel: 50,
+	VolcengineJimengI2VModel:   50,
+	VolcengineTextModel:        1,
+}
+
+// DefaultTaskCreditPrices 各任务类型的默认价格
+var DefaultTaskCreditPrices = map[string]int64{
+	"image": 5,
+	"video": 60,
+	"text":  1,
+}
+
+// GetCreditPrice 获取模型的单价
+func GetCreditPrice(taskType, model string) int64 {
+	if price, ok := ModelCreditPrices[model]; ok {
+		return price
+	}
+	return DefaultTaskCreditPrices[taskType]
+}
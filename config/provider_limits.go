@@ -0,0 +1,28 @@
+package config
+
+// ProviderRateLimit 服务商接口的令牌桶配置，所有Worker进程共享
+type ProviderRateLimit struct {
+	Rate  float64 // 每秒补充的令牌数
+	Burst int     // 令牌桶容量，允许的突发调用次数
+}
+
+// DefaultProviderRateLimit 未单独配置的服务商和模型使用的限流配置
+var DefaultProviderRateLimit = ProviderRateLimit{Rate: 5, Burst: 10}
+
+// ProviderRateLimits 按服务商和模型覆盖限流配置，键为 "provider/model"
+// 即梦AI的视频结果查询与提交共用同一个令牌桶
+var ProviderRateLimits = map[string]ProviderRateLimit{
+	DefaultAIProvider + "/" + VolcengineImageModel:       {Rate: 5, Burst: 10},
+	DefaultAIProvider + "/" + VolcengineJimengImageModel: {Rate: 1, Burst: 2},
+	DefaultAIProvider + "/" + VolcengineImageEditModel:   {Rate: 1, Burst: 2},
+	DefaultAIProvider + "/" + VolcengineJimengVideoModel: {Rate: 2, Burst: 5},
+	DefaultAIProvider + "/" + VolcengineJimengI2VModel:   {Rate: 2, Burst: 5},
+}
+
+// ProviderRateLimitFor 获取服务商和模型的限流配置
+func ProviderRateLimitFor(provider, model string) ProviderRateLimit {
+	if limit, ok := ProviderRateLimits[provider+"/"+model]; ok {
+		return limit
+	}
+	return DefaultProviderRateLimit
+}
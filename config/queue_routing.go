@@ -0,0 +1,94 @@
+package config
+
+// 队列名称，Worker按权重从各队列取任务
+const (
+	QueueCritical = "critical"
+	QueueDefault  = "default"
+	QueueLow      = "low"
+)
+
+// 用户等级
+const (
+	UserTierFree = "free"
+	UserTierPro  = "pro"
+)
+
+// 任务显式优先级
+const (
+	TaskPriorityHigh   = "high"
+	TaskPriorityNormal = "normal"
+	TaskPriorityLow    = "low"
+)
+
+// queueLevels 队列从低到高排列，用于按级数提升或限制队列
+var queueLevels = []string{QueueLow, QueueDefault, QueueCritical}
+
+// 队列路由配置：先按任务类型和模型确定基础队列，再按用户等级提升，最后受用户等级的最高队列限制
+// 文本任务耗时短优先处理，视频任务耗时长放入低优先级队列，避免占满Worker
+var TaskTypeQueues = map[string]string{
+	"text":  QueueCritical,
+	"image": QueueDefault,
+	"video": QueueLow,
+}
+
+// ModelQueues 按模型覆盖任务类型的基础队列，键为模型名称
+var ModelQueues = map[string]string{}
+
+// TierQueueBoost 各用户等级在基础队列上提升的级数
+var TierQueueBoost = map[string]int{
+	UserTierFree: 0,
+	UserTierPro:  1,
+}
+
+// TierMaxQueues 各用户等级可进入的最高队列，显式priority同样受此限制
+var TierMaxQueues = map[string]string{
+	UserTierFree: QueueDefault,
+	UserTierPro:  QueueCritical,
+}
+
+// PriorityQueues 显式priority对应的队列
+var PriorityQueues = map[string]string{
+	TaskPriorityHigh:   QueueCritical,
+	TaskPriorityNormal: QueueDefault,
+	TaskPriorityLow:    QueueLow,
+}
+
+// IsValidTaskPriority 检查显式优先级是否合法，空值表示按路由配置选择
+func IsValidTaskPriority(priority string) bool {
+	_, ok := PriorityQueues[priority]
+	return priority == "" || ok
+}
+
+// SelectQueue 根据任务类型、模型、用户等级和显式优先级选择队列
+// 未知的用户等级按免费用户处理
+func SelectQueue(taskType, model, tier, priority string) string {
+	if _, ok := TierMaxQueues[tier]; !ok {
+		tier = UserTierFree
+	}
+
+	var level int
+	if queue, ok := PriorityQueues[priority]; ok {
+		level = queueLevel(queue)
+	} else {
+		queue, ok := ModelQueues[model]
+		if !ok {
+			queue = TaskTypeQueues[taskType]
+		}
+		level = queueLevel(queue) + TierQueueBoost[tier]
+	}
+
+	if max := queueLevel(TierMaxQueues[tier]); level > max {
+		level = max
+	}
+	return queueLevels[level]
+}
+
+// queueLevel 获取队列的级别，未知队列按默认队列处理
+func queueLevel(queue string) int {
+	for i, name := range queueLevels {
+		if name == queue {
+			return i
+		}
+	}
+	return queueLevel(QueueDefault)
+}
@@ -0,0 +1,40 @@
+package config
+
+import "testing"
+
+func TestSelectQueue(t *testing.T) {
+	tests := []struct {
+		name     string
+		taskType string
+		tier     string
+		priority string
+		want     string
+	}{
+		{"免费用户视频", "video", UserTierFree, "", QueueLow},
+		{"免费用户图像", "image", UserTierFree, "", QueueDefault},
+		{"免费用户文本受等级限制", "text", UserTierFree, "", QueueDefault},
+		{"付费用户图像提升", "image", UserTierPro, "", QueueCritical},
+		{"付费用户视频提升", "video", UserTierPro, "", QueueDefault},
+		{"未知等级按免费处理", "image", "unknown", "", QueueDefault},
+		{"免费用户显式high受限", "image", UserTierFree, TaskPriorityHigh, QueueDefault},
+		{"付费用户显式high", "video", UserTierPro, TaskPriorityHigh, QueueCritical},
+		{"显式low降级", "image", UserTierPro, TaskPriorityLow, QueueLow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SelectQueue(tt.taskType, "", tt.tier, tt.priority); got != tt.want {
+				t.Errorf("SelectQueue(%s, %s, %s) = %s, 期望 %s", tt.taskType, tt.tier, tt.priority, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectQueueModelOverride(t *testing.T) {
+	ModelQueues["test-model"] = QueueLow
+	defer delete(ModelQueues, "test-model")
+
+	if got := SelectQueue("text", "test-model", UserTierFree, ""); got != QueueLow {
+		t.Errorf("模型覆盖后队列 = %s, 期望 %s", got, QueueLow)
+	}
+}
@@ -2,6 +2,8 @@ package core
 
 import (
 	"context"
+
+	"volcengine-go-server/internal/models"
 )
 
 // AITaskDispatcher AI任务分发器接口 - Provider层职责
@@ -10,6 +12,9 @@ type AITaskDispatcher interface {
 	// 获取分发器名称
 	GetProviderName() string
 
+	// 获取服务商能力描述：支持的模型、任务类型和参数限制
+	GetCapabilities() models.ProviderCapability
+
 	// 分发图像生成任务到具体的Service
 	DispatchImageTask(ctx context.Context, taskID string, model string, input map[string]interface{}) error
 
@@ -20,6 +25,13 @@ type AITaskDispatcher interface {
 	DispatchVideoTask(ctx context.Context, taskID string, model string, input map[string]interface{}) error
 }
 
+// VideoStatusChecker 视频任务状态检查接口，由提交后异步生成的分发器实现
+// 每次只查询一次服务商结果，由任务队列按自适应间隔重新入队
+type VideoStatusChecker interface {
+	// 查询视频任务状态，完成时由实现方保存结果，返回任务是否已结束
+	CheckVideoTask(ctx context.Context, taskID, model, providerTaskID string) (bool, error)
+}
+
 // AIImageService AI图像生成服务接口 - Service层职责
 // Service负责具体的API调用和业务逻辑实现
 type AIImageService interface {
@@ -0,0 +1,362 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hibiken/asynq"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"volcengine-go-server/config"
+	"volcengine-go-server/internal/models"
+	"volcengine-go-server/internal/service"
+	"volcengine-go-server/pkg/logger"
+)
+
+// PipelineStepPayload 流水线步骤启动载荷
+type PipelineStepPayload struct {
+	PipelineID string `json:"pipeline_id"`
+	Step       int    `json:"step"`
+}
+
+// PipelineAdvancePayload 流水线步骤任务结束后的推进载荷
+type PipelineAdvancePayload struct {
+	PipelineID string `json:"pipeline_id"`
+	Step       int    `json:"step"`
+	TaskID     string `json:"task_id"`
+}
+
+// PipelineRunner 流水线执行器
+// 每个步骤创建一个普通任务并入队，由生成任务处理器通过AITaskDispatcher执行；
+// 步骤任务结束时通过TaskNotifier入队推进任务，由Worker记录输出并启动下一步骤
+type PipelineRunner struct {
+	queue           *TaskQueue
+	pipelineService *service.PipelineService
+	taskService     *service.TaskService
+	creditService   *service.CreditService
+	userService     *service.UserService
+	log             *logrus.Logger
+}
+
+// NewPipelineRunner 创建流水线执行器
+func NewPipelineRunner(
+	queue *TaskQueue,
+	pipelineService *service.PipelineService,
+	taskService *service.TaskService,
+	creditService *service.CreditService,
+	userService *service.UserService,
+) *PipelineRunner {
+	return &PipelineRunner{
+		queue:           queue,
+		pipelineService: pipelineService,
+		taskService:     taskService,
+		creditService:   creditService,
+		userService:     userService,
+		log:             logger.GetLogger(),
+	}
+}
+
+// StartPipeline 入队流水线的第一个步骤
+func (r *PipelineRunner) StartPipeline(ctx context.Context, pipelineID string) error {
+	return r.enqueueStep(ctx, pipelineID, 0)
+}
+
+// enqueueStep 入队步骤启动任务，同一步骤只入队一次
+func (r *PipelineRunner) enqueueStep(ctx context.Context, pipelineID string, step int) error {
+	data, err := json.Marshal(&PipelineStepPayload{PipelineID: pipelineID, Step: step})
+	if err != nil {
+		return err
+	}
+
+	task := asynq.NewTask(TypePipelineStep, data,
+		asynq.TaskID(fmt.Sprintf("pipeline:%s:%d", pipelineID, step)),
+		asynq.Queue(QueueDefault),
+	)
+	if _, err := r.queue.client.Enqueue(task); err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+		return err
+	}
+	return nil
+}
+
+// HandleStep 步骤启动处理器，占用步骤后创建步骤任务并入队
+// 重试时复用占用步骤时分配的任务ID，不会重复创建任务
+func (r *PipelineRunner) HandleStep(ctx context.Context, t *asynq.Task) error {
+	var payload PipelineStepPayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		return fmt.Errorf("解析流水线步骤载荷失败: %v: %w", err, asynq.SkipRetry)
+	}
+
+	pipeline, err := r.getPipeline(ctx, payload.PipelineID, payload.Step)
+	if err != nil || pipeline == nil {
+		return err
+	}
+
+	if pipeline.Steps[payload.Step].Status == config.TaskStatusPending {
+		taskID := primitive.NewObjectID().Hex()
+		if _, err := r.pipelineService.StartStep(ctx, pipeline.ID, payload.Step, taskID); err != nil {
+			return err
+		}
+		// 重新读取，以占用结果为准
+		if pipeline, err = r.getPipeline(ctx, payload.PipelineID, payload.Step); err != nil || pipeline == nil {
+			return err
+		}
+	}
+
+	if pipeline.IsFinished() || pipeline.Steps[payload.Step].Status != config.TaskStatusProcessing {
+		r.log.Infof("流水线步骤已结束或未开始，跳过: pipelineID=%s, step=%d", pipeline.ID, payload.Step)
+		return nil
+	}
+	return r.ensureStepTask(ctx, pipeline, payload.Step)
+}
+
+// ensureStepTask 创建步骤任务并入队，任务已存在且仍在等待中时重新入队
+func (r *PipelineRunner) ensureStepTask(ctx context.Context, pipeline *models.Pipeline, index int) error {
+	step := pipeline.Steps[index]
+
+	input, imageURLs, err := service.StepInput(pipeline, index)
+	if err != nil {
+		// 模板无法渲染时步骤任务尚未创建，退还当前步骤和之后步骤的积分
+		r.failPipeline(ctx, pipeline, index, err.Error(), step.Cost)
+		return nil
+	}
+
+	user, err := r.userService.GetUserByID(ctx, pipeline.UserID)
+	if err != nil {
+		return fmt.Errorf("获取用户失败: %w", err)
+	}
+
+	task, err := r.taskService.GetTask(ctx, step.TaskID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		task, err = r.taskService.CreateTask(ctx, input)
+	}
+	if err != nil {
+		return err
+	}
+	if task.Status != config.TaskStatusPending {
+		return nil
+	}
+
+	queueTaskType, taskPayload := newGenerationPayload(task, user.Tier)
+	if len(imageURLs) > 0 {
+		taskPayload.Input["image_urls"] = imageURLs
+	}
+	if err := r.queue.EnqueueTask(ctx, queueTaskType, taskPayload); err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+		return fmt.Errorf("步骤任务入队失败: %w", err)
+	}
+
+	// 创建任务期间流水线可能已被取消，此时取消刚创建的任务
+	if latest, err := r.pipelineService.GetPipeline(ctx, pipeline.ID); err == nil && latest.IsFinished() {
+		r.cancelStepTask(ctx, task.ID)
+		return nil
+	}
+
+	r.log.Infof("流水线步骤任务已入队: pipelineID=%s, step=%d, taskID=%s", pipeline.ID, index, task.ID)
+	return nil
+}
+
+// NotifyTaskFinished 实现service.TaskNotifier接口，流水线步骤任务结束时入队推进任务
+func (r *PipelineRunner) NotifyTaskFinished(ctx context.Context, task *models.Task) error {
+	if task.PipelineID == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(&PipelineAdvancePayload{
+		PipelineID: task.PipelineID,
+		Step:       task.PipelineStep,
+		TaskID:     task.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	// 失败的任务可能被重试后再次失败，推进任务不去重，由处理器根据当前状态判断
+	queueTask := asynq.NewTask(TypePipelineAdvance, data, asynq.Queue(QueueDefault))
+	if _, err := r.queue.client.Enqueue(queueTask); err != nil {
+		return err
+	}
+	return nil
+}
+
+// HandleAdvance 推进任务处理器，步骤任务完成时记录输出并启动下一步骤，失败或取消时结束流水线
+func (r *PipelineRunner) HandleAdvance(ctx context.Context, t *asynq.Task) error {
+	var payload PipelineAdvancePayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		return fmt.Errorf("解析流水线推进载荷失败: %v: %w", err, asynq.SkipRetry)
+	}
+
+	pipeline, err := r.getPipeline(ctx, payload.PipelineID, payload.Step)
+	if err != nil || pipeline == nil {
+		return err
+	}
+	if pipeline.IsFinished() || pipeline.Steps[payload.Step].TaskID != payload.TaskID {
+		return nil
+	}
+
+	// 以任务当前状态为准，推进任务入队后任务可能已被重试
+	task, err := r.taskService.GetTask(ctx, payload.TaskID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			r.failPipeline(ctx, pipeline, payload.Step, "步骤任务已删除", 0)
+			return nil
+		}
+		return err
+	}
+
+	switch task.Status {
+	case config.TaskStatusCompleted:
+		return r.completeStep(ctx, pipeline, payload.Step, task)
+	case config.TaskStatusCancelled:
+		r.failPipeline(ctx, pipeline, payload.Step, "步骤任务已取消", 0)
+		return nil
+	case config.TaskStatusFailed:
+		// 任务只在不可重试或最后一次重试失败后才标记为失败
+		r.failPipeline(ctx, pipeline, payload.Step, "步骤任务失败: "+task.Error, 0)
+		return nil
+	default:
+		return nil
+	}
+}
+
+// completeStep 记录步骤输出，最后一个步骤完成时结束流水线，否则入队下一步骤
+func (r *PipelineRunner) completeStep(ctx context.Context, pipeline *models.Pipeline, index int, task *models.Task) error {
+	output := task.PrimaryOutput()
+	if output == "" {
+		r.failPipeline(ctx, pipeline, index, "步骤任务没有输出", 0)
+		return nil
+	}
+
+	if _, err := r.pipelineService.CompleteStep(ctx, pipeline.ID, index, output); err != nil {
+		return err
+	}
+	pipeline, err := r.getPipeline(ctx, pipeline.ID, index)
+	if err != nil || pipeline == nil {
+		return err
+	}
+	if pipeline.IsFinished() || pipeline.Steps[index].Status != config.TaskStatusCompleted {
+		return nil
+	}
+
+	if index == len(pipeline.Steps)-1 {
+		if _, err := r.pipelineService.FinishPipeline(ctx, pipeline.ID, config.TaskStatusCompleted, "", -1, ""); err != nil {
+			return err
+		}
+		r.log.Infof("流水线已完成: %s", pipeline.ID)
+		return nil
+	}
+	return r.enqueueStep(ctx, pipeline.ID, index+1)
+}
+
+// failPipeline 将流水线和步骤标记为失败，退还尚未开始的步骤积分和额外的refund积分
+func (r *PipelineRunner) failPipeline(ctx context.Context, pipeline *models.Pipeline, index int, errorMsg string, refund int64) {
+	finished, err := r.pipelineService.FinishPipeline(ctx, pipeline.ID, config.TaskStatusFailed, errorMsg, index, config.TaskStatusFailed)
+	if err != nil {
+		r.log.Errorf("更新流水线失败状态失败: %s, %v", pipeline.ID, err)
+		return
+	}
+	if !finished {
+		return
+	}
+
+	r.refund(ctx, pipeline, pipeline.PendingStepsCost()+refund)
+	r.log.Warnf("流水线执行失败: pipelineID=%s, step=%d, %s", pipeline.ID, index, errorMsg)
+}
+
+// CancelPipeline 取消流水线及执行中的步骤任务，退还尚未开始的步骤积分
+// 返回false表示流水线已结束，无法取消
+func (r *PipelineRunner) CancelPipeline(ctx context.Context, pipeline *models.Pipeline) (bool, error) {
+	step, stepStatus := -1, ""
+	current := pipeline.Steps[pipeline.CurrentStep]
+	if pipeline.Status == config.TaskStatusProcessing && current.Status == config.TaskStatusProcessing {
+		step, stepStatus = pipeline.CurrentStep, config.TaskStatusCancelled
+	}
+
+	cancelled, err := r.pipelineService.FinishPipeline(ctx, pipeline.ID, config.TaskStatusCancelled, "", step, stepStatus)
+	if err != nil || !cancelled {
+		return cancelled, err
+	}
+
+	// 步骤任务的积分随任务取消退还；任务尚未创建时由步骤启动处理器取消
+	if step >= 0 {
+		r.cancelStepTask(ctx, current.TaskID)
+	}
+	r.refund(ctx, pipeline, pipeline.PendingStepsCost())
+	return true, nil
+}
+
+// FailStep 步骤启动或推进任务被管理员从队列删除或归档时结束流水线，避免流水线停留在执行中
+// taskID非空时只处理仍属于该步骤的推进任务；执行中的步骤任务随之取消并退还积分，
+// 步骤已占用但任务尚未创建时退还该步骤的积分
+func (r *PipelineRunner) FailStep(ctx context.Context, pipelineID string, index int, taskID, errorMsg string) error {
+	pipeline, err := r.getPipeline(ctx, pipelineID, index)
+	if err != nil || pipeline == nil || pipeline.IsFinished() {
+		return err
+	}
+	step := pipeline.Steps[index]
+	if taskID != "" && step.TaskID != taskID {
+		return nil
+	}
+
+	var refund int64
+	var task *models.Task
+	if step.Status == config.TaskStatusProcessing {
+		task, err = r.taskService.GetTask(ctx, step.TaskID)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			refund = step.Cost
+		} else if err != nil {
+			return err
+		}
+	}
+
+	r.failPipeline(ctx, pipeline, index, errorMsg, refund)
+	if task != nil {
+		r.cancelStepTask(ctx, task.ID)
+	}
+	return nil
+}
+
+// cancelStepTask 取消步骤任务并从队列中移除
+func (r *PipelineRunner) cancelStepTask(ctx context.Context, taskID string) {
+	cancelled, err := r.taskService.CancelTask(ctx, taskID)
+	if err != nil {
+		r.log.Errorf("取消流水线步骤任务失败: %s, %v", taskID, err)
+		return
+	}
+	if !cancelled {
+		return
+	}
+	if err := r.queue.CancelTask(ctx, taskID); err != nil {
+		r.log.Warnf("从队列取消流水线步骤任务失败: %s, %v", taskID, err)
+	}
+}
+
+// refund 退还流水线积分
+func (r *PipelineRunner) refund(ctx context.Context, pipeline *models.Pipeline, amount int64) {
+	if amount <= 0 {
+		return
+	}
+	if err := r.creditService.Refund(ctx, pipeline.UserID, amount); err != nil {
+		r.log.Errorf("退还流水线积分失败: pipelineID=%s, credits=%d, %v", pipeline.ID, amount, err)
+		return
+	}
+	r.log.Infof("流水线积分已退还: pipelineID=%s, userID=%s, credits=%d", pipeline.ID, pipeline.UserID, amount)
+}
+
+// getPipeline 获取流水线并校验步骤序号，流水线已删除时返回nil
+func (r *PipelineRunner) getPipeline(ctx context.Context, id string, step int) (*models.Pipeline, error) {
+	pipeline, err := r.pipelineService.GetPipeline(ctx, id)
+	if err != nil {
+		if errors.Is(err, service.ErrPipelineNotFound) {
+			r.log.Infof("流水线不存在，跳过: %s", id)
+			return nil, nil
+		}
+		return nil, err
+	}
+	if step < 0 || step >= len(pipeline.Steps) {
+		return nil, fmt.Errorf("流水线步骤序号无效: %s, %d: %w", id, step, asynq.SkipRetry)
+	}
+	return pipeline, nil
+}
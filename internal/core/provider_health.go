@@ -0,0 +1,183 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/sirupsen/logrus"
+
+	"volcengine-go-server/config"
+	"volcengine-go-server/pkg/logger"
+)
+
+// ErrProviderUnavailable 服务商和模型处于熔断状态，暂不调用
+var ErrProviderUnavailable = errors.New("服务商暂不可用")
+
+// RetryableError 分发器可返回实现该接口的错误，声明错误是否可以重试或切换到其他候选
+// 未实现该接口的错误按可重试处理
+type RetryableError interface {
+	Retryable() bool
+}
+
+// IsRetryableError 判断分发错误是否可以重试或切换到其他候选
+func IsRetryableError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, asynq.SkipRetry) {
+		return false
+	}
+	var retryable RetryableError
+	if errors.As(err, &retryable) {
+		return retryable.Retryable()
+	}
+	return true
+}
+
+// 熔断状态
+const (
+	BreakerClosed   = "closed"    // 正常调用
+	BreakerOpen     = "open"      // 熔断中，冷却期内不调用
+	BreakerHalfOpen = "half_open" // 冷却期结束，只允许一次试探调用
+)
+
+// ProviderHealth 分发器健康状态，按服务商和模型统计最近的调用结果并维护熔断状态
+// 状态只保存在当前Worker进程内，用于故障转移时选择候选：统计整次分发（含结果转存和保存）的可重试失败，
+// 熔断时直接切换到其他候选而不是延迟任务。ProviderLimiter的共享熔断只统计单次接口调用的过载信号，
+// 熔断时延迟任务；两者判定的失败不同，因此分别维护状态，阈值和冷却时间共用config中的同一组常量
+
+type ProviderHealth struct {
+	mu      sync.Mutex
+	entries map[string]*providerHealthEntry
+	now     func() time.Time
+	log     *logrus.Logger
+}
+
+// providerHealthEntry 单个服务商和模型的健康状态
+type providerHealthEntry struct {
+	results             []bool // 最近的调用结果，true表示失败
+	next                int
+	consecutiveFailures int
+	state               string
+	openedAt            time.Time
+	probeStartedAt      time.Time
+}
+
+// NewProviderHealth 创建分发器健康状态
+func NewProviderHealth() *ProviderHealth {
+	return &ProviderHealth{
+		entries: make(map[string]*providerHealthEntry),
+		now:     time.Now,
+		log:     logger.GetLogger(),
+	}
+}
+
+// Allow 检查是否可以调用服务商和模型，冷却期结束后放行一次试探调用
+// 试探调用超过冷却时间仍未返回结果时允许再次试探
+func (h *ProviderHealth) Allow(provider, model string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	entry := h.entry(provider, model)
+	now := h.now()
+	switch entry.state {
+	case BreakerOpen:
+		if now.Sub(entry.openedAt) < config.ProviderBreakerCooldown {
+			return false
+		}
+		entry.state = BreakerHalfOpen
+		entry.probeStartedAt = now
+		return true
+	case BreakerHalfOpen:
+		if now.Sub(entry.probeStartedAt) < config.ProviderBreakerCooldown {
+			return false
+		}
+		entry.probeStartedAt = now
+		return true
+	default:
+		return true
+	}
+}
+
+// RecordSuccess 记录一次成功调用，试探调用成功时恢复正常
+func (h *ProviderHealth) RecordSuccess(provider, model string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	entry := h.entry(provider, model)
+	entry.record(false)
+	entry.consecutiveFailures = 0
+	if entry.state != BreakerClosed {
+		entry.state = BreakerClosed
+		entry.results = entry.results[:0]
+		entry.next = 0
+		h.log.Infof("服务商恢复正常: provider=%s, model=%s", provider, model)
+	}
+}
+
+// RecordFailure 记录一次失败调用，连续失败或错误率超过阈值时熔断，试探调用失败时重新熔断
+func (h *ProviderHealth) RecordFailure(provider, model string, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	entry := h.entry(provider, model)
+	entry.record(true)
+	entry.consecutiveFailures++
+
+	trip := entry.state == BreakerHalfOpen ||
+		entry.consecutiveFailures >= config.ProviderBreakerFailureThreshold ||
+		(len(entry.results) >= config.ProviderBreakerMinSamples && entry.errorRate() >= config.ProviderBreakerErrorRate)
+	if entry.state != BreakerOpen && trip {
+		entry.state = BreakerOpen
+		entry.openedAt = h.now()
+		h.log.Warnf("服务商熔断: provider=%s, model=%s, 错误率=%.2f, 连续失败=%d, 最后错误: %v",
+			provider, model, entry.errorRate(), entry.consecutiveFailures, err)
+	}
+}
+
+// State 获取服务商和模型的熔断状态
+func (h *ProviderHealth) State(provider, model string) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.entry(provider, model).state
+}
+
+// entry 获取服务商和模型的健康状态，不存在时创建，调用方需持有锁
+func (h *ProviderHealth) entry(provider, model string) *providerHealthEntry {
+	key := fmt.Sprintf("%s/%s", provider, model)
+	entry, ok := h.entries[key]
+	if !ok {
+		entry = &providerHealthEntry{
+			results: make([]bool, 0, config.ProviderHealthWindow),
+			state:   BreakerClosed,
+		}
+		h.entries[key] = entry
+	}
+	return entry
+}
+
+// record 记录一次调用结果，超过窗口大小时覆盖最早的结果
+func (e *providerHealthEntry) record(failed bool) {
+	if len(e.results) < config.ProviderHealthWindow {
+		e.results = append(e.results, failed)
+		return
+	}
+	e.results[e.next] = failed
+	e.next = (e.next + 1) % config.ProviderHealthWindow
+}
+
+// errorRate 最近调用的错误率
+func (e *providerHealthEntry) errorRate() float64 {
+	if len(e.results) == 0 {
+		return 0
+	}
+	failures := 0
+	for _, failed := range e.results {
+		if failed {
+			failures++
+		}
+	}
+	return float64(failures) / float64(len(e.results))
+}
@@ -0,0 +1,89 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"volcengine-go-server/config"
+)
+
+func TestProviderHealthBreaker(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	health := NewProviderHealth()
+	health.now = func() time.Time { return now }
+	errUpstream := errors.New("upstream 503")
+
+	for i := 0; i < config.ProviderBreakerFailureThreshold-1; i++ {
+		health.RecordFailure("volcengine", "m1", errUpstream)
+	}
+	if state := health.State("volcengine", "m1"); state != BreakerClosed {
+		t.Fatalf("连续失败未达阈值时状态 = %s, 期望 %s", state, BreakerClosed)
+	}
+
+	health.RecordFailure("volcengine", "m1", errUpstream)
+	if health.Allow("volcengine", "m1") {
+		t.Fatal("连续失败达到阈值后应熔断")
+	}
+	if !health.Allow("volcengine", "m2") {
+		t.Fatal("其他模型不应受影响")
+	}
+
+	// 冷却期结束后只放行一次试探调用
+	now = now.Add(config.ProviderBreakerCooldown)
+	if !health.Allow("volcengine", "m1") {
+		t.Fatal("冷却期结束后应放行试探调用")
+	}
+	if health.Allow("volcengine", "m1") {
+		t.Fatal("试探调用返回前不应放行其他调用")
+	}
+
+	// 试探失败重新熔断
+	health.RecordFailure("volcengine", "m1", errUpstream)
+	if state := health.State("volcengine", "m1"); state != BreakerOpen {
+		t.Fatalf("试探失败后状态 = %s, 期望 %s", state, BreakerOpen)
+	}
+
+	// 试探成功恢复正常
+	now = now.Add(config.ProviderBreakerCooldown)
+	health.Allow("volcengine", "m1")
+	health.RecordSuccess("volcengine", "m1")
+	if state := health.State("volcengine", "m1"); state != BreakerClosed {
+		t.Fatalf("试探成功后状态 = %s, 期望 %s", state, BreakerClosed)
+	}
+}
+
+func TestProviderHealthErrorRate(t *testing.T) {
+	health := NewProviderHealth()
+	errUpstream := errors.New("upstream 503")
+
+	// 交替成功失败，连续失败次数不会达到阈值，错误率达到阈值后熔断
+	for i := 0; i < config.ProviderBreakerMinSamples; i++ {
+		if health.State("volcengine", "m1") == BreakerOpen {
+			t.Fatalf("调用次数不足%d次时不应熔断", config.ProviderBreakerMinSamples)
+		}
+		if i%2 == 0 {
+			health.RecordSuccess("volcengine", "m1")
+		} else {
+			health.RecordFailure("volcengine", "m1", errUpstream)
+		}
+	}
+	if state := health.State("volcengine", "m1"); state != BreakerOpen {
+		t.Fatalf("错误率达到阈值后状态 = %s, 期望 %s", state, BreakerOpen)
+	}
+}
+
+type permanentError struct{}
+
+func (permanentError) Error() string   { return "invalid input" }
+func (permanentError) Retryable() bool { return false }
+
+func TestIsRetryableError(t *testing.T) {
+	if !IsRetryableError(errors.New("timeout")) {
+		t.Error("普通错误应按可重试处理")
+	}
+	if IsRetryableError(fmt.Errorf("分发失败: %w", permanentError{})) {
+		t.Error("声明不可重试的错误不应重试")
+	}
+}
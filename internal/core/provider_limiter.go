@@ -0,0 +1,199 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+
+	"volcengine-go-server/config"
+	"volcengine-go-server/pkg/logger"
+)
+
+// ProviderBackoffError 服务商熔断或限流，任务应在Delay后重新执行而不是标记为失败
+type ProviderBackoffError struct {
+	Provider string
+	Model    string
+	Reason   string
+	Delay    time.Duration
+}
+
+func (e *ProviderBackoffError) Error() string {
+	return fmt.Sprintf("%s: %s/%s %s，%s后重试", ErrProviderUnavailable, e.Provider, e.Model, e.Reason, e.Delay)
+}
+
+func (e *ProviderBackoffError) Unwrap() error {
+	return ErrProviderUnavailable
+}
+
+// RetryAfter 任务重新执行前的等待时间
+func (e *ProviderBackoffError) RetryAfter() time.Duration {
+	return e.Delay
+}
+
+// BackoffError 需要延迟后重新执行的错误，Service层通过同名方法声明，避免依赖core包
+type BackoffError interface {
+	RetryAfter() time.Duration
+}
+
+// backoffDelay 获取错误要求的延迟时间，不是延迟错误时返回false
+func backoffDelay(err error) (time.Duration, bool) {
+	var backoff BackoffError
+	if errors.As(err, &backoff) {
+		return backoff.RetryAfter(), true
+	}
+	return 0, false
+}
+
+// tokenBucketScript 原子地补充并获取一个令牌，返回获取令牌前需要等待的毫秒数，0表示已获取
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local data = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(data[1]) or burst
+local ts = tonumber(data[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate / 1000)
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+else
+	wait = math.ceil((1 - tokens) * 1000 / rate)
+end
+redis.call('HSET', KEYS[1], 'tokens', tokens, 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst * 1000 / rate) + 1000)
+return wait
+`)
+
+// ProviderLimiter 服务商接口调用的限流和熔断，状态保存在Redis中，所有Worker进程共享
+// 按服务商和模型分别维护令牌桶和熔断状态
+// 熔断只统计限流、服务端错误和网络错误等过载信号，在每次接口调用前拦截，保护服务商不被所有Worker同时压垮；
+// 与ProviderHealth的区别见其说明
+
+type ProviderLimiter struct {
+	client redis.UniversalClient
+	log    *logrus.Logger
+}
+
+// NewProviderLimiter 创建服务商调用限流器（与TaskQueue共用同一个Redis）
+func NewProviderLimiter(redisURL string) *ProviderLimiter {
+	opt, err := asynq.ParseRedisURI(redisURL)
+	if err != nil {
+		logger.GetLogger().Fatal("解析Redis URL失败: ", err)
+	}
+
+	client, ok := opt.MakeRedisClient().(redis.UniversalClient)
+	if !ok {
+		logger.GetLogger().Fatal("创建Redis客户端失败: 不支持的连接类型")
+	}
+
+	return &ProviderLimiter{
+		client: client,
+		log:    logger.GetLogger(),
+	}
+}
+
+// Close 关闭Redis连接
+func (l *ProviderLimiter) Close() error {
+	return l.client.Close()
+}
+
+// Acquire 调用服务商接口前检查熔断状态并获取令牌
+// 熔断中或需要等待的时间超过config.ProviderRateLimitMaxWait时返回ProviderBackoffError
+// Redis不可用时放行调用，避免限流器故障导致所有任务失败
+func (l *ProviderLimiter) Acquire(ctx context.Context, provider, model string) error {
+	if err := l.checkBreaker(ctx, provider, model); err != nil {
+		return err
+	}
+
+	limit := config.ProviderRateLimitFor(provider, model)
+	for {
+		wait, err := tokenBucketScript.Run(ctx, l.client, []string{limiterKey("bucket", provider, model)},
+			limit.Rate, limit.Burst, time.Now().UnixMilli()).Int64()
+		if err != nil {
+			l.log.Warnf("获取服务商调用令牌失败，直接放行: %s/%s, %v", provider, model, err)
+			return nil
+		}
+		if wait == 0 {
+			return nil
+		}
+
+		delay := time.Duration(wait) * time.Millisecond
+		if delay > config.ProviderRateLimitMaxWait {
+			return &ProviderBackoffError{Provider: provider, Model: model, Reason: "调用频率超过限制", Delay: delay}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// checkBreaker 检查熔断状态，冷却期结束后只放行一次试探调用
+func (l *ProviderLimiter) checkBreaker(ctx context.Context, provider, model string) error {
+	ttl, err := l.client.PTTL(ctx, limiterKey("open", provider, model)).Result()
+	if err != nil {
+		l.log.Warnf("查询服务商熔断状态失败，直接放行: %s/%s, %v", provider, model, err)
+		return nil
+	}
+	if ttl > 0 {
+		return &ProviderBackoffError{Provider: provider, Model: model, Reason: "熔断中", Delay: ttl}
+	}
+
+	tripped, err := l.client.Exists(ctx, limiterKey("tripped", provider, model)).Result()
+	if err != nil || tripped == 0 {
+		return nil
+	}
+	probing, err := l.client.SetNX(ctx, limiterKey("probe", provider, model), 1, config.ProviderBreakerCooldown).Result()
+	if err != nil || probing {
+		return nil
+	}
+	return &ProviderBackoffError{Provider: provider, Model: model, Reason: "等待试探调用结果", Delay: config.ProviderBreakerCooldown}
+}
+
+// RecordResult 记录服务商接口调用结果，overloaded表示服务商限流、服务端错误或网络错误
+// 时间窗口内连续过载达到阈值或试探调用失败时熔断，调用成功时恢复
+func (l *ProviderLimiter) RecordResult(ctx context.Context, provider, model string, overloaded bool) {
+	failuresKey := limiterKey("failures", provider, model)
+	trippedKey := limiterKey("tripped", provider, model)
+	probeKey := limiterKey("probe", provider, model)
+
+	if !overloaded {
+		if err := l.client.Del(ctx, failuresKey, trippedKey, probeKey).Err(); err != nil {
+			l.log.Warnf("重置服务商熔断状态失败: %s/%s, %v", provider, model, err)
+		}
+		return
+	}
+
+	pipe := l.client.TxPipeline()
+	failures := pipe.Incr(ctx, failuresKey)
+	pipe.Expire(ctx, failuresKey, config.ProviderBreakerFailureWindow)
+	tripped := pipe.Exists(ctx, trippedKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		l.log.Warnf("记录服务商调用失败次数失败: %s/%s, %v", provider, model, err)
+		return
+	}
+	if failures.Val() < config.ProviderBreakerFailureThreshold && tripped.Val() == 0 {
+		return
+	}
+
+	pipe = l.client.TxPipeline()
+	pipe.Set(ctx, limiterKey("open", provider, model), 1, config.ProviderBreakerCooldown)
+	pipe.Set(ctx, trippedKey, 1, 0)
+	pipe.Del(ctx, failuresKey, probeKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		l.log.Errorf("服务商熔断失败: %s/%s, %v", provider, model, err)
+		return
+	}
+	l.log.Warnf("服务商接口熔断: %s/%s, 冷却时间: %s", provider, model, config.ProviderBreakerCooldown)
+}
+
+// limiterKey 构建限流和熔断状态的Redis键
+func limiterKey(kind, provider, model string) string {
+	return fmt.Sprintf("provider_limiter:%s:%s:%s", kind, provider, model)
+}
@@ -0,0 +1,139 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"volcengine-go-server/config"
+	"volcengine-go-server/pkg/logger"
+)
+
+// newTestLimiter 创建连接内存Redis的限流器
+func newTestLimiter(t *testing.T) (*ProviderLimiter, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return &ProviderLimiter{client: client, log: logger.GetLogger()}, mr
+}
+
+func TestBackoffDelay(t *testing.T) {
+	backoff := &ProviderBackoffError{Provider: "volcengine", Model: "m1", Reason: "熔断中", Delay: 5 * time.Second}
+	wrapped := fmt.Errorf("图像生成失败: %w", backoff)
+
+	delay, ok := backoffDelay(wrapped)
+	if !ok || delay != 5*time.Second {
+		t.Fatalf("backoffDelay() = %v, %v, 期望 5s, true", delay, ok)
+	}
+	if !errors.Is(wrapped, ErrProviderUnavailable) {
+		t.Fatal("延迟错误应包装ErrProviderUnavailable")
+	}
+	if !IsRetryableError(wrapped) {
+		t.Fatal("延迟错误应可重试")
+	}
+	if _, ok := backoffDelay(errors.New("upstream 503")); ok {
+		t.Fatal("普通错误不应被识别为延迟错误")
+	}
+}
+
+func TestTokenBucketScript(t *testing.T) {
+	limiter, _ := newTestLimiter(t)
+	ctx := context.Background()
+	key := []string{limiterKey("bucket", "volcengine", "m1")}
+	take := func(now int64) int64 {
+		wait, err := tokenBucketScript.Run(ctx, limiter.client, key, 2, 3, now).Int64()
+		if err != nil {
+			t.Fatalf("执行令牌桶脚本失败: %v", err)
+		}
+		return wait
+	}
+
+	// 初始为满桶，突发容量内立即获取
+	now := int64(1700000000000)
+	for i := 0; i < 3; i++ {
+		if wait := take(now); wait != 0 {
+			t.Fatalf("第%d次获取等待 = %dms, 期望 0", i+1, wait)
+		}
+	}
+	// 令牌耗尽，按每秒2个的速率需要等待500ms
+	if wait := take(now); wait != 500 {
+		t.Fatalf("令牌耗尽时等待 = %dms, 期望 500", wait)
+	}
+	// 等待期间补充的令牌可以获取
+	if wait := take(now + 500); wait != 0 {
+		t.Fatalf("补充令牌后等待 = %dms, 期望 0", wait)
+	}
+	// 长时间未调用时令牌数不超过突发容量
+	now += 60000
+	for i := 0; i < 3; i++ {
+		take(now)
+	}
+	if wait := take(now); wait == 0 {
+		t.Fatal("令牌数不应超过突发容量")
+	}
+}
+
+func TestProviderLimiterBreaker(t *testing.T) {
+	limiter, mr := newTestLimiter(t)
+	ctx := context.Background()
+	acquire := func() *ProviderBackoffError {
+		err := limiter.Acquire(ctx, "volcengine", "m1")
+		if err == nil {
+			return nil
+		}
+		var backoff *ProviderBackoffError
+		if !errors.As(err, &backoff) {
+			t.Fatalf("Acquire() = %v, 期望ProviderBackoffError", err)
+		}
+		return backoff
+	}
+
+	for i := 0; i < config.ProviderBreakerFailureThreshold-1; i++ {
+		limiter.RecordResult(ctx, "volcengine", "m1", true)
+	}
+	if err := acquire(); err != nil {
+		t.Fatalf("过载次数未达阈值时不应熔断: %v", err)
+	}
+
+	// 达到阈值后熔断，其他模型不受影响
+	limiter.RecordResult(ctx, "volcengine", "m1", true)
+	if err := acquire(); err == nil || err.Reason != "熔断中" {
+		t.Fatalf("过载次数达到阈值后 Acquire() = %v, 期望熔断", err)
+	}
+	if err := limiter.Acquire(ctx, "volcengine", "m2"); err != nil {
+		t.Fatalf("其他模型不应受影响: %v", err)
+	}
+
+	// 冷却期结束后只放行一次试探调用
+	mr.FastForward(config.ProviderBreakerCooldown)
+	if err := acquire(); err != nil {
+		t.Fatalf("冷却期结束后应放行试探调用: %v", err)
+	}
+	if err := acquire(); err == nil || err.Reason != "等待试探调用结果" {
+		t.Fatalf("试探调用返回前 Acquire() = %v, 期望拒绝", err)
+	}
+
+	// 试探失败立即重新熔断
+	limiter.RecordResult(ctx, "volcengine", "m1", true)
+	if err := acquire(); err == nil || err.Reason != "熔断中" {
+		t.Fatalf("试探失败后 Acquire() = %v, 期望熔断", err)
+	}
+
+	// 试探成功恢复正常，之后单次过载不再熔断
+	mr.FastForward(config.ProviderBreakerCooldown)
+	if err := acquire(); err != nil {
+		t.Fatalf("冷却期结束后应放行试探调用: %v", err)
+	}
+	limiter.RecordResult(ctx, "volcengine", "m1", false)
+	limiter.RecordResult(ctx, "volcengine", "m1", true)
+	for i := 0; i < 2; i++ {
+		if err := acquire(); err != nil {
+			t.Fatalf("试探成功后应恢复正常: %v", err)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// ErrQueueTaskState 队列任务当前状态不支持该操作
+var ErrQueueTaskState = errors.New("队列任务当前状态不支持该操作")
+
+// ErrInvalidTaskState 不支持的队列任务状态
+var ErrInvalidTaskState = errors.New("不支持的队列任务状态")
+
+// QueueTaskInfo 队列中的任务信息
+type QueueTaskInfo struct {
+	ID            string      `json:"id"`
+	Queue         string      `json:"queue"`
+	Type          string      `json:"type"`
+	State         string      `json:"state"`
+	Payload       interface{} `json:"payload"`
+	MaxRetry      int         `json:"max_retry"`
+	Retried       int         `json:"retried"`
+	LastErr       string      `json:"last_error,omitempty"`
+	LastFailedAt  *time.Time  `json:"last_failed_at,omitempty"`
+	NextProcessAt *time.Time  `json:"next_process_at,omitempty"`
+}
+
+// IsValidQueue 检查队列名称是否为系统使用的队列
+func IsValidQueue(queue string) bool {
+	for _, name := range queueNames {
+		if name == queue {
+			return true
+		}
+	}
+	return false
+}
+
+// ListQueueTasks 按状态分页列出队列中的任务，page从1开始
+func (r *TaskQueue) ListQueueTasks(ctx context.Context, queue, state string, page, size int) ([]*QueueTaskInfo, error) {
+	inspector := asynq.NewInspector(r.opt)
+	defer inspector.Close()
+
+	opts := []asynq.ListOption{asynq.Page(page), asynq.PageSize(size)}
+
+	var infos []*asynq.TaskInfo
+	var err error
+	switch state {
+	case asynq.TaskStatePending.String():
+		infos, err = inspector.ListPendingTasks(queue, opts...)
+	case asynq.TaskStateActive.String():
+		infos, err = inspector.ListActiveTasks(queue, opts...)
+	case asynq.TaskStateScheduled.String():
+		infos, err = inspector.ListScheduledTasks(queue, opts...)
+	case asynq.TaskStateRetry.String():
+		infos, err = inspector.ListRetryTasks(queue, opts...)
+	case asynq.TaskStateArchived.String():
+		infos, err = inspector.ListArchivedTasks(queue, opts...)
+	case asynq.TaskStateCompleted.String():
+		infos, err = inspector.ListCompletedTasks(queue, opts...)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTaskState, state)
+	}
+	if err != nil {
+		// 还没有任务进入过的队列视为空队列
+		if errors.Is(err, asynq.ErrQueueNotFound) {
+			return []*QueueTaskInfo{}, nil
+		}
+		return nil, err
+	}
+
+	tasks := make([]*QueueTaskInfo, 0, len(infos))
+	for _, info := range infos {
+		tasks = append(tasks, newQueueTaskInfo(info))
+	}
+	return tasks, nil
+}
+
+// RunQueueTask 立即执行等待重试、定时或已归档的任务
+func (r *TaskQueue) RunQueueTask(ctx context.Context, queue, id string) error {
+	inspector := asynq.NewInspector(r.opt)
+	defer inspector.Close()
+
+	info, err := checkQueueTaskState(inspector, queue, id,
+		asynq.TaskStateScheduled, asynq.TaskStateRetry, asynq.TaskStateArchived)
+	if err != nil {
+		return err
+	}
+
+	r.log.Infof("管理员立即执行队列任务: queue=%s, id=%s", queue, id)
+	// 先更新业务任务状态，避免Worker在状态更新前取到任务
+	if info.State == asynq.TaskStateArchived {
+		r.requeueQueueTask(ctx, info)
+	}
+	return inspector.RunTask(queue, id)
+}
+
+// DeleteQueueTask 从队列删除任务，执行中的任务需先取消
+// 生成或状态检查任务被删除后业务任务不会再结束，标记为失败并退还积分；流水线任务被删除时结束流水线
+func (r *TaskQueue) DeleteQueueTask(ctx context.Context, queue, id string) error {
+	inspector := asynq.NewInspector(r.opt)
+	defer inspector.Close()
+
+	info, err := checkQueueTaskState(inspector, queue, id,
+		asynq.TaskStatePending, asynq.TaskStateScheduled, asynq.TaskStateRetry,
+		asynq.TaskStateArchived, asynq.TaskStateCompleted)
+	if err != nil {
+		return err
+	}
+
+	if err := inspector.DeleteTask(queue, id); err != nil {
+		return err
+	}
+
+	r.log.Infof("管理员删除队列任务: queue=%s, id=%s, state=%s", queue, id, info.State)
+	if info.State != asynq.TaskStateCompleted {
+		r.failQueueTask(ctx, info, "任务已被管理员从队列中删除")
+	}
+	return nil
+}
+
+// ArchiveQueueTask 归档等待中、定时或等待重试的任务，归档后可通过RunQueueTask重新执行
+func (r *TaskQueue) ArchiveQueueTask(ctx context.Context, queue, id string) error {
+	inspector := asynq.NewInspector(r.opt)
+	defer inspector.Close()
+
+	info, err := checkQueueTaskState(inspector, queue, id,
+		asynq.TaskStatePending, asynq.TaskStateScheduled, asynq.TaskStateRetry)
+	if err != nil {
+		return err
+	}
+
+	if err := inspector.ArchiveTask(queue, id); err != nil {
+		return err
+	}
+
+	r.log.Infof("管理员归档队列任务: queue=%s, id=%s", queue, id)
+	r.failQueueTask(ctx, info, "任务已被管理员归档")
+	return nil
+}
+
+// checkQueueTaskState 检查队列任务是否处于允许的状态
+func checkQueueTaskState(inspector *asynq.Inspector, queue, id string, allowed ...asynq.TaskState) (*asynq.TaskInfo, error) {
+	info, err := inspector.GetTaskInfo(queue, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, state := range allowed {
+		if info.State == state {
+			return info, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrQueueTaskState, info.State)
+}
+
+// failQueueTask 队列任务不会再执行时结束对应的业务任务或流水线
+// 生成任务和状态检查任务将业务任务标记为失败，之后被重新执行并成功时，会按正常流程更新结果并重新扣除积分；
+// 流水线步骤启动和推进任务将流水线标记为失败，流水线结束后重新执行这些任务不会再推进流水线
+func (r *TaskQueue) failQueueTask(ctx context.Context, info *asynq.TaskInfo, errorMsg string) {
+	switch info.Type {
+	case TypePipelineStep:
+		var payload PipelineStepPayload
+		if err := json.Unmarshal(info.Payload, &payload); err == nil {
+			r.failPipelineStep(ctx, payload.PipelineID, payload.Step, "", errorMsg)
+		}
+		return
+	case TypePipelineAdvance:
+		var payload PipelineAdvancePayload
+		if err := json.Unmarshal(info.Payload, &payload); err == nil {
+			r.failPipelineStep(ctx, payload.PipelineID, payload.Step, payload.TaskID, errorMsg)
+		}
+		return
+	}
+
+	taskID := queueBusinessTaskID(info)
+	if taskID == "" {
+		return
+	}
+	if err := r.taskService.UpdateTaskError(ctx, taskID, errorMsg); err != nil {
+		r.log.Errorf("更新任务状态失败: %s, %v", taskID, err)
+	}
+}
+
+// failPipelineStep 结束流水线任务所属的流水线，未设置流水线执行器时只记录日志
+func (r *TaskQueue) failPipelineStep(ctx context.Context, pipelineID string, step int, taskID, errorMsg string) {
+	if r.pipelines == nil {
+		r.log.Warnf("未设置流水线执行器，流水线状态未更新: pipelineID=%s, step=%d", pipelineID, step)
+		return
+	}
+	if err := r.pipelines.FailStep(ctx, pipelineID, step, taskID, errorMsg); err != nil {
+		r.log.Errorf("更新流水线状态失败: pipelineID=%s, step=%d, %v", pipelineID, step, err)
+	}
+}
+
+// requeueQueueTask 已归档的生成或状态检查任务被重新执行时，将失败的业务任务转回执行前的状态
+// 生成任务转回等待中；状态检查任务继续查询已提交给服务商的任务，转回处理中
+// 任务之后成功完成时会重新扣除失败时退还的积分
+func (r *TaskQueue) requeueQueueTask(ctx context.Context, info *asynq.TaskInfo) {
+	taskID := queueBusinessTaskID(info)
+	if taskID == "" {
+		return
+	}
+	if err := r.taskService.RequeueTask(ctx, taskID); err != nil {
+		r.log.Warnf("重新执行的任务状态未更新: %s, %v", taskID, err)
+		return
+	}
+	if info.Type != TypeStatusCheck {
+		return
+	}
+
+	task, err := r.taskService.GetTask(ctx, taskID)
+	if err == nil {
+		err = r.taskService.StartTask(ctx, taskID, task.LastAttempt())
+	}
+	if err != nil {
+		r.log.Warnf("重新执行的状态检查任务未转为处理中: %s, %v", taskID, err)
+	}
+}
+
+// queueBusinessTaskID 解析生成任务和状态检查任务载荷中的业务任务ID，其他类型返回空
+func queueBusinessTaskID(info *asynq.TaskInfo) string {
+	var payload struct {
+		TaskID string `json:"task_id"`
+	}
+	if !strings.HasSuffix(info.Type, "_generation") && info.Type != TypeStatusCheck {
+		return ""
+	}
+	if err := json.Unmarshal(info.Payload, &payload); err != nil {
+		return ""
+	}
+	return payload.TaskID
+}
+
+// newQueueTaskInfo 转换队列任务信息，按任务类型解析载荷
+func newQueueTaskInfo(info *asynq.TaskInfo) *QueueTaskInfo {
+	task := &QueueTaskInfo{
+		ID:       info.ID,
+		Queue:    info.Queue,
+		Type:     info.Type,
+		State:    info.State.String(),
+		Payload:  decodeQueuePayload(info.Type, info.Payload),
+		MaxRetry: info.MaxRetry,
+		Retried:  info.Retried,
+		LastErr:  info.LastErr,
+	}
+	if !info.LastFailedAt.IsZero() {
+		task.LastFailedAt = &info.LastFailedAt
+	}
+	if !info.NextProcessAt.IsZero() {
+		task.NextProcessAt = &info.NextProcessAt
+	}
+	return task
+}
+
+// decodeQueuePayload 解析队列任务载荷，未知类型原样返回
+func decodeQueuePayload(taskType string, data []byte) interface{} {
+	var payload interface{}
+	switch taskType {
+	case TypeTextGeneration, TypeImageGeneration, TypeVideoGeneration:
+		payload = &AITaskPayload{}
+	case TypeStatusCheck:
+		payload = &StatusCheckPayload{}
+	case TypeWebhookDelivery:
+		payload = &WebhookPayload{}
+	case TypeScheduleTrigger:
+		payload = &SchedulePayload{}
+	case TypePipelineStep:
+		payload = &PipelineStepPayload{}
+	case TypePipelineAdvance:
+		payload = &PipelineAdvancePayload{}
+	default:
+		if json.Valid(data) {
+			return json.RawMessage(data)
+		}
+		return string(data)
+	}
+
+	if err := json.Unmarshal(data, payload); err != nil {
+		return string(data)
+	}
+	return payload
+}
@@ -0,0 +1,34 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestQueueBusinessTaskID(t *testing.T) {
+	generation, _ := json.Marshal(&AITaskPayload{TaskID: "task-1"})
+	statusCheck, _ := json.Marshal(&StatusCheckPayload{TaskID: "task-2", Provider: "volcengine"})
+	pipelineStep, _ := json.Marshal(&PipelineStepPayload{PipelineID: "pipeline-1"})
+
+	tests := []struct {
+		name     string
+		taskType string
+		payload  []byte
+		want     string
+	}{
+		{"生成任务", TypeVideoGeneration, generation, "task-1"},
+		{"状态检查任务", TypeStatusCheck, statusCheck, "task-2"},
+		{"流水线任务", TypePipelineStep, pipelineStep, ""},
+		{"载荷无法解析", TypeStatusCheck, []byte("{"), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := &asynq.TaskInfo{Type: tt.taskType, Payload: tt.payload}
+			if got := queueBusinessTaskID(info); got != tt.want {
+				t.Errorf("queueBusinessTaskID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
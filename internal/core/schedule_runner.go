@@ -0,0 +1,229 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/sirupsen/logrus"
+
+	"volcengine-go-server/config"
+	"volcengine-go-server/internal/models"
+	"volcengine-go-server/internal/service"
+	"volcengine-go-server/pkg/logger"
+)
+
+// SchedulePayload 周期任务触发载荷
+type SchedulePayload struct {
+	ScheduleID string `json:"schedule_id"`
+}
+
+// ScheduleRunner 周期任务执行器
+// 调度器定期从数据库同步启用的周期任务，每次触发时入队触发任务，由Worker按模板创建并入队生成任务
+type ScheduleRunner struct {
+	queue           *TaskQueue
+	scheduleService *service.ScheduleService
+	taskService     *service.TaskService
+	creditService   *service.CreditService
+	userService     *service.UserService
+	manager         *asynq.PeriodicTaskManager
+	log             *logrus.Logger
+}
+
+// NewScheduleRunner 创建周期任务执行器
+func NewScheduleRunner(
+	queue *TaskQueue,
+	scheduleService *service.ScheduleService,
+	taskService *service.TaskService,
+	creditService *service.CreditService,
+	userService *service.UserService,
+) *ScheduleRunner {
+	return &ScheduleRunner{
+		queue:           queue,
+		scheduleService: scheduleService,
+		taskService:     taskService,
+		creditService:   creditService,
+		userService:     userService,
+		log:             logger.GetLogger(),
+	}
+}
+
+// Start 启动周期任务调度器，每隔config.ScheduleSyncInterval从数据库同步一次配置
+// 多个Worker同时运行调度器时，同一次触发只会创建一个任务
+func (r *ScheduleRunner) Start() error {
+	manager, err := asynq.NewPeriodicTaskManager(asynq.PeriodicTaskManagerOpts{
+		RedisConnOpt:               r.queue.opt,
+		PeriodicTaskConfigProvider: r,
+		SchedulerOpts:              &asynq.SchedulerOpts{Logger: r.log},
+		SyncInterval:               config.ScheduleSyncInterval,
+	})
+	if err != nil {
+		return err
+	}
+	if err := manager.Start(); err != nil {
+		return err
+	}
+
+	r.manager = manager
+	return nil
+}
+
+// Shutdown 停止周期任务调度器
+func (r *ScheduleRunner) Shutdown() {
+	if r.manager != nil {
+		r.manager.Shutdown()
+	}
+}
+
+// GetConfigs 实现asynq.PeriodicTaskConfigProvider接口，返回所有启用的周期任务
+func (r *ScheduleRunner) GetConfigs() ([]*asynq.PeriodicTaskConfig, error) {
+	schedules, err := r.scheduleService.GetEnabledSchedules(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	configs := make([]*asynq.PeriodicTaskConfig, 0, len(schedules))
+	for _, schedule := range schedules {
+		// 配置无效的周期任务不注册，避免影响其他周期任务的同步
+		if err := service.ValidateSchedule(schedule); err != nil {
+			r.log.Warnf("跳过无效的周期任务: %s, %v", schedule.ID, err)
+			continue
+		}
+
+		data, err := json.Marshal(&SchedulePayload{ScheduleID: schedule.ID})
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, &asynq.PeriodicTaskConfig{
+			Cronspec: schedule.CronSpec(),
+			Task:     asynq.NewTask(TypeScheduleTrigger, data),
+			// 错过的触发不补执行，失败原因记录在周期任务中
+			Opts: []asynq.Option{asynq.Queue(QueueDefault), asynq.MaxRetry(0)},
+		})
+	}
+	return configs, nil
+}
+
+// HandleTrigger 周期任务触发处理器，按模板扣除积分、创建任务并入队
+func (r *ScheduleRunner) HandleTrigger(ctx context.Context, t *asynq.Task) error {
+	var payload SchedulePayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		return fmt.Errorf("解析周期任务载荷失败: %v: %w", err, asynq.SkipRetry)
+	}
+
+	// 调度器同步配置前，已删除或停用的周期任务仍可能触发
+	schedule, err := r.scheduleService.GetScheduleByID(ctx, payload.ScheduleID)
+	if err != nil {
+		if errors.Is(err, service.ErrScheduleNotFound) {
+			r.log.Infof("周期任务已删除，跳过触发: %s", payload.ScheduleID)
+			return nil
+		}
+		return err
+	}
+	if !schedule.Enabled {
+		r.log.Infof("周期任务已停用，跳过触发: %s", schedule.ID)
+		return nil
+	}
+
+	claimed, err := r.scheduleService.ClaimRun(ctx, schedule.ID, time.Now())
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		r.log.Infof("周期任务本次触发已执行，跳过: %s", schedule.ID)
+		return nil
+	}
+
+	taskID, runErr := r.createTask(ctx, schedule)
+	if err := r.scheduleService.RecordRunResult(ctx, schedule.ID, taskID, runErr); err != nil {
+		r.log.Errorf("记录周期任务执行结果失败: %s, %v", schedule.ID, err)
+	}
+	if runErr != nil {
+		r.log.Warnf("周期任务创建任务失败: scheduleID=%s, userID=%s, %v", schedule.ID, schedule.UserID, runErr)
+		return nil
+	}
+
+	r.log.Infof("周期任务已创建任务: scheduleID=%s, taskID=%s", schedule.ID, taskID)
+	return nil
+}
+
+// createTask 按周期任务模板扣除积分、创建任务并入队，返回创建的任务ID
+func (r *ScheduleRunner) createTask(ctx context.Context, schedule *models.Schedule) (string, error) {
+	user, err := r.userService.GetUserByID(ctx, schedule.UserID)
+	if err != nil {
+		return "", fmt.Errorf("获取用户失败: %w", err)
+	}
+
+	input := &models.TaskInput{
+		Prompt:   schedule.Prompt,
+		UserID:   schedule.UserID,
+		Type:     schedule.Type,
+		Model:    schedule.Model,
+		Provider: schedule.Provider,
+
+		CallbackURL:    schedule.CallbackURL,
+		CallbackSecret: schedule.CallbackSecret,
+		AspectRatio:    schedule.AspectRatio,
+		N:              schedule.N,
+		ScheduleID:     schedule.ID,
+	}
+
+	input.Cost = service.CalculateTaskCost(input.Type, input.Model, input.N)
+	if err := r.creditService.Charge(ctx, input.UserID, input.Cost); err != nil {
+		return "", err
+	}
+
+	task, err := r.taskService.CreateTask(ctx, input)
+	if err != nil {
+		r.creditService.Refund(ctx, input.UserID, input.Cost)
+		return "", err
+	}
+
+	queueTaskType, payload := newGenerationPayload(task, user.Tier)
+	if err := r.queue.EnqueueTask(ctx, queueTaskType, payload); err != nil {
+		// 如果入队失败，删除已创建的任务记录
+		r.taskService.DeleteTask(ctx, task.ID)
+		return "", fmt.Errorf("任务入队失败: %w", err)
+	}
+
+	return task.ID, nil
+}
+
+// newGenerationPayload 根据任务记录构建生成任务的队列类型和载荷
+func newGenerationPayload(task *models.Task, userTier string) (string, *AITaskPayload) {
+	payload := &AITaskPayload{
+		TaskID:   task.ID,
+		UserID:   task.UserID,
+		UserTier: userTier,
+		Type:     task.Type + "_generation",
+		Provider: task.Provider,
+		Model:    task.Model,
+	}
+
+	switch task.Type {
+	case models.TaskTypeImage:
+		payload.Input = map[string]interface{}{
+			"prompt":       task.Prompt,
+			"aspect_ratio": task.AspectRatio,
+			"n":            task.N,
+		}
+		return TypeImageGeneration, payload
+	case models.TaskTypeVideo:
+		payload.Input = map[string]interface{}{
+			"prompt":       task.Prompt,
+			"seed":         task.Seed,
+			"aspect_ratio": task.AspectRatio,
+		}
+		return TypeVideoGeneration, payload
+	default:
+		payload.Input = map[string]interface{}{
+			"prompt":      task.Prompt,
+			"max_tokens":  task.MaxTokens,
+			"temperature": task.Temperature,
+		}
+		return TypeTextGeneration, payload
+	}
+}
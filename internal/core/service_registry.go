@@ -1,20 +1,53 @@
 package core
 
+import (
+	"sort"
+
+	"volcengine-go-server/internal/models"
+)
+
 // ServiceRegistry 服务注册器 - 管理AI任务分发器
+// 同时保存各服务商的能力描述，API服务器不注册分发器，只注册能力描述用于模型目录和请求校验
 type ServiceRegistry struct {
-	dispatchers map[string]AITaskDispatcher
+	dispatchers  map[string]AITaskDispatcher
+	capabilities map[string]models.ProviderCapability
 }
 
 // NewServiceRegistry 创建新的服务注册器
 func NewServiceRegistry() *ServiceRegistry {
 	return &ServiceRegistry{
-		dispatchers: make(map[string]AITaskDispatcher),
+		dispatchers:  make(map[string]AITaskDispatcher),
+		capabilities: make(map[string]models.ProviderCapability),
 	}
 }
 
-// RegisterDispatcher 注册AI任务分发器
+// RegisterDispatcher 注册AI任务分发器及其能力描述
 func (sr *ServiceRegistry) RegisterDispatcher(dispatcher AITaskDispatcher) {
 	sr.dispatchers[dispatcher.GetProviderName()] = dispatcher
+	sr.RegisterCapabilities(dispatcher.GetCapabilities())
+}
+
+// RegisterCapabilities 注册服务商能力描述，不注册分发器
+func (sr *ServiceRegistry) RegisterCapabilities(capability models.ProviderCapability) {
+	sr.capabilities[capability.Provider] = capability
+}
+
+// GetCapabilities 获取指定服务商的能力描述
+func (sr *ServiceRegistry) GetCapabilities(name string) (models.ProviderCapability, bool) {
+	capability, exists := sr.capabilities[name]
+	return capability, exists
+}
+
+// Catalog 获取全部服务商的能力描述，按服务商名称排序
+func (sr *ServiceRegistry) Catalog() []models.ProviderCapability {
+	catalog := make([]models.ProviderCapability, 0, len(sr.capabilities))
+	for _, capability := range sr.capabilities {
+		catalog = append(catalog, capability)
+	}
+	sort.Slice(catalog, func(i, j int) bool {
+		return catalog[i].Provider < catalog[j].Provider
+	})
+	return catalog
 }
 
 // GetDispatcher 获取指定名称的AI任务分发器
@@ -43,10 +76,11 @@ func (sr *ServiceRegistry) HasProvider(name string) bool {
 	return exists
 }
 
-// UnregisterDispatcher 注销AI任务分发器
+// UnregisterDispatcher 注销AI任务分发器及其能力描述
 func (sr *ServiceRegistry) UnregisterDispatcher(name string) bool {
 	if _, exists := sr.dispatchers[name]; exists {
 		delete(sr.dispatchers, name)
+		delete(sr.capabilities, name)
 		return true
 	}
 	return false
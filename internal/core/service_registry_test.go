@@ -0,0 +1,38 @@
+package core
+
+import (
+	"testing"
+
+	"volcengine-go-server/internal/models"
+)
+
+func TestServiceRegistryCatalog(t *testing.T) {
+	registry := NewServiceRegistry()
+	registry.RegisterCapabilities(models.ProviderCapability{
+		Provider: "volcengine",
+		Models:   []models.ModelCapability{{Model: "m-image", TaskType: models.TaskTypeImage}},
+	})
+	registry.RegisterCapabilities(models.ProviderCapability{
+		Provider: "openai",
+		Models:   []models.ModelCapability{{Model: "m-text", TaskType: models.TaskTypeText}},
+	})
+
+	catalog := registry.Catalog()
+	if len(catalog) != 2 || catalog[0].Provider != "openai" || catalog[1].Provider != "volcengine" {
+		t.Fatalf("Catalog() = %+v, 期望按服务商名称排序", catalog)
+	}
+	if registry.Count() != 0 {
+		t.Fatal("只注册能力描述时不应注册分发器")
+	}
+
+	capability, ok := registry.GetCapabilities("volcengine")
+	if !ok {
+		t.Fatal("未找到已注册的服务商")
+	}
+	if _, ok := capability.FindModel(models.TaskTypeImage, "m-image"); !ok {
+		t.Error("应找到已声明的模型")
+	}
+	if _, ok := capability.FindModel(models.TaskTypeVideo, "m-image"); ok {
+		t.Error("模型不支持的任务类型不应匹配")
+	}
+}
@@ -3,7 +3,10 @@ package core
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/hibiken/asynq"
@@ -11,6 +14,7 @@ import (
 
 	"volcengine-go-server/config"
 	"volcengine-go-server/internal/service"
+	"volcengine-go-server/internal/util"
 	"volcengine-go-server/pkg/logger"
 )
 
@@ -22,7 +26,12 @@ type TaskQueue struct {
 	// 使用服务注册器替代具体的服务依赖
 	serviceRegistry *ServiceRegistry
 	taskService     *service.TaskService
+	health          *ProviderHealth // 各服务商和模型的健康状态，用于故障转移
+	stream          *TaskStream     // 可选：文本任务将重试时通知流式订阅者
+	pipelines       *PipelineRunner // 可选：管理员移除流水线队列任务时结束流水线
 	log             *logrus.Logger
+	// 额外注册的任务处理器（如回调投递）
+	handlers map[string]asynq.HandlerFunc
 }
 
 // 任务类型常量
@@ -30,18 +39,44 @@ const (
 	TypeTextGeneration  = "ai:text_generation"
 	TypeImageGeneration = "ai:image_generation"
 	TypeVideoGeneration = "ai:video_generation"
+	TypeStatusCheck     = "ai:status_check"
+	TypeScheduleTrigger = "ai:schedule_trigger"
+	TypePipelineStep    = "ai:pipeline_step"
+	TypePipelineAdvance = "ai:pipeline_advance"
+	TypeWebhookDelivery = "webhook:delivery"
 )
 
+// 队列名称常量，路由配置见config.SelectQueue
+const (
+	QueueCritical = config.QueueCritical
+	QueueDefault  = config.QueueDefault
+	QueueLow      = config.QueueLow
+)
+
+// 所有队列名称，用于按任务ID查找队列中的任务
+var queueNames = []string{QueueCritical, QueueDefault, QueueLow}
+
 // 任务载荷结构
 type AITaskPayload struct {
 	TaskID   string                 `json:"task_id"`
 	UserID   string                 `json:"user_id"`
+	UserTier string                 `json:"user_tier,omitempty"`
+	Priority string                 `json:"priority,omitempty"`
 	Type     string                 `json:"type"`
 	Input    map[string]interface{} `json:"input"`
 	Model    string                 `json:"model"`
 	Provider string                 `json:"provider"`
 }
 
+// StatusCheckPayload 异步任务状态检查载荷
+type StatusCheckPayload struct {
+	TaskID         string `json:"task_id"`
+	Provider       string `json:"provider"`
+	Model          string `json:"model"`
+	ProviderTaskID string `json:"provider_task_id"`
+	Attempt        int    `json:"attempt"`
+}
+
 // NewTaskQueue 创建新的任务队列
 func NewTaskQueue(
 	redisURL string,
@@ -59,9 +94,23 @@ func NewTaskQueue(
 	server := asynq.NewServer(opt, asynq.Config{
 		Concurrency: config.QueueConcurrency,
 		Queues: map[string]int{
-			"critical": config.QueueCriticalWeight,
-			"default":  config.QueueDefaultWeight,
-			"low":      config.QueueLowWeight,
+			QueueCritical: config.QueueCriticalWeight,
+			QueueDefault:  config.QueueDefaultWeight,
+			QueueLow:      config.QueueLowWeight,
+		},
+		RetryDelayFunc: func(n int, err error, task *asynq.Task) time.Duration {
+			if delay, ok := backoffDelay(err); ok {
+				return delay
+			}
+			if task.Type() == TypeWebhookDelivery {
+				return webhookRetryDelay(n)
+			}
+			return asynq.DefaultRetryDelayFunc(n, err, task)
+		},
+		// 服务商熔断或限流导致的延迟执行不计入重试次数
+		IsFailure: func(err error) bool {
+			_, backoff := backoffDelay(err)
+			return !backoff
 		},
 		ErrorHandler: asynq.ErrorHandlerFunc(func(ctx context.Context, task *asynq.Task, err error) {
 			logger.GetLogger().Errorf("任务执行失败: %v, 错误: %v", task.Type(), err)
@@ -75,10 +124,27 @@ func NewTaskQueue(
 		opt:             opt,
 		serviceRegistry: serviceRegistry,
 		taskService:     taskService,
+		health:          NewProviderHealth(),
 		log:             logger.GetLogger(),
+		handlers:        make(map[string]asynq.HandlerFunc),
 	}
 }
 
+// RegisterHandler 注册额外的任务处理器，需在StartWorker之前调用
+func (r *TaskQueue) RegisterHandler(taskType string, handler asynq.HandlerFunc) {
+	r.handlers[taskType] = handler
+}
+
+// SetTaskStream 设置流式输出通道，文本任务将重试时发布retrying事件
+func (r *TaskQueue) SetTaskStream(stream *TaskStream) {
+	r.stream = stream
+}
+
+// SetPipelineRunner 设置流水线执行器，流水线步骤启动或推进任务被管理员删除或归档时结束流水线
+func (r *TaskQueue) SetPipelineRunner(runner *PipelineRunner) {
+	r.pipelines = runner
+}
+
 // 入队任务
 func (r *TaskQueue) EnqueueTask(ctx context.Context, taskType string, payload *AITaskPayload, opts ...asynq.Option) error {
 	data, err := json.Marshal(payload)
@@ -86,7 +152,7 @@ func (r *TaskQueue) EnqueueTask(ctx context.Context, taskType string, payload *A
 		return err
 	}
 
-	task := asynq.NewTask(taskType, data, opts...)
+	task := asynq.NewTask(taskType, data, withTaskOptions(payload, opts)...)
 	_, err = r.client.Enqueue(task)
 	return err
 }
@@ -98,7 +164,7 @@ func (r *TaskQueue) EnqueueDelayedTask(ctx context.Context, taskType string, pay
 		return err
 	}
 
-	task := asynq.NewTask(taskType, data, opts...)
+	task := asynq.NewTask(taskType, data, withTaskOptions(payload, opts)...)
 	_, err = r.client.Enqueue(task, asynq.ProcessIn(delay))
 	return err
 }
@@ -110,11 +176,162 @@ func (r *TaskQueue) EnqueueScheduledTask(ctx context.Context, taskType string, p
 		return err
 	}
 
-	task := asynq.NewTask(taskType, data, opts...)
+	task := asynq.NewTask(taskType, data, withTaskOptions(payload, opts)...)
 	_, err = r.client.Enqueue(task, asynq.ProcessAt(processAt))
 	return err
 }
 
+// QueueItem 批量入队的单个任务
+type QueueItem struct {
+	TaskType string
+	Payload  *AITaskPayload
+	Opts     []asynq.Option
+}
+
+// EnqueueTasks 批量入队任务，返回与items一一对应的错误
+// asynq客户端不支持管道写入，按config.BatchEnqueueConcurrency并发入队以减少总耗时
+func (r *TaskQueue) EnqueueTasks(ctx context.Context, items []*QueueItem) []error {
+	errs := make([]error, len(items))
+	sem := make(chan struct{}, config.BatchEnqueueConcurrency)
+
+	var wg sync.WaitGroup
+	for i, item := range items {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, item *QueueItem) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			errs[i] = r.EnqueueTask(ctx, item.TaskType, item.Payload, item.Opts...)
+		}(i, item)
+	}
+	wg.Wait()
+
+	return errs
+}
+
+// ScheduleStatusCheck 按自适应间隔安排一次状态检查
+// 状态检查任务持久化在Redis中，Worker重启后会继续执行
+func (r *TaskQueue) ScheduleStatusCheck(ctx context.Context, provider, model, taskID, providerTaskID string, attempt int) error {
+	data, err := json.Marshal(&StatusCheckPayload{
+		TaskID:         taskID,
+		Provider:       provider,
+		Model:          model,
+		ProviderTaskID: providerTaskID,
+		Attempt:        attempt,
+	})
+	if err != nil {
+		return err
+	}
+
+	// 同一任务的同一次检查只入队一次
+	task := asynq.NewTask(TypeStatusCheck, data,
+		asynq.TaskID(fmt.Sprintf("status:%s:%d", taskID, attempt)),
+		asynq.Queue(QueueDefault),
+	)
+	_, err = r.client.Enqueue(task, asynq.ProcessIn(util.NextPollInterval(attempt)))
+	if errors.Is(err, asynq.ErrTaskIDConflict) {
+		return nil
+	}
+	return err
+}
+
+// withTaskOptions 使用业务任务ID作为队列任务ID，便于之后按ID取消，并按路由配置选择队列
+// 调用方显式传入的asynq.TaskID和asynq.Queue会覆盖默认值
+func withTaskOptions(payload *AITaskPayload, opts []asynq.Option) []asynq.Option {
+	taskType := strings.TrimSuffix(payload.Type, "_generation")
+	queue := config.SelectQueue(taskType, payload.Model, payload.UserTier, payload.Priority)
+	return append([]asynq.Option{asynq.TaskID(payload.TaskID), asynq.Queue(queue), asynq.MaxRetry(config.GenerationMaxRetry)}, opts...)
+}
+
+// RescheduleTask 修改队列中定时任务的执行时间
+// asynq不支持直接修改执行时间，先删除原任务再以相同的ID、队列和载荷重新入队
+func (r *TaskQueue) RescheduleTask(ctx context.Context, taskID string, processAt time.Time) error {
+	inspector := asynq.NewInspector(r.opt)
+	defer inspector.Close()
+
+	for _, queue := range queueNames {
+		info, err := inspector.GetTaskInfo(queue, taskID)
+		if err != nil {
+			if errors.Is(err, asynq.ErrTaskNotFound) || errors.Is(err, asynq.ErrQueueNotFound) {
+				continue
+			}
+			return fmt.Errorf("查询队列任务失败: %w", err)
+		}
+
+		if info.State != asynq.TaskStateScheduled {
+			return fmt.Errorf("%w: %s", ErrQueueTaskState, info.State)
+		}
+		if err := inspector.DeleteTask(queue, taskID); err != nil {
+			return err
+		}
+
+		task := asynq.NewTask(info.Type, info.Payload,
+			asynq.TaskID(taskID),
+			asynq.Queue(queue),
+			asynq.MaxRetry(info.MaxRetry),
+		)
+		if _, err := r.client.Enqueue(task, asynq.ProcessAt(processAt)); err != nil {
+			return err
+		}
+
+		r.log.Infof("定时任务已重新安排: %s, 执行时间: %s", taskID, processAt.Format(time.RFC3339))
+		return nil
+	}
+
+	return asynq.ErrTaskNotFound
+}
+
+// CancelTask 取消队列中的任务
+// 等待中的任务直接从队列删除，执行中的任务向Worker发送取消信号
+func (r *TaskQueue) CancelTask(ctx context.Context, taskID string) error {
+	inspector := asynq.NewInspector(r.opt)
+	defer inspector.Close()
+
+	for _, queue := range queueNames {
+		info, err := inspector.GetTaskInfo(queue, taskID)
+		if err != nil {
+			if errors.Is(err, asynq.ErrTaskNotFound) || errors.Is(err, asynq.ErrQueueNotFound) {
+				continue
+			}
+			return fmt.Errorf("查询队列任务失败: %w", err)
+		}
+
+		switch info.State {
+		case asynq.TaskStateActive:
+			r.log.Infof("发送取消信号给执行中的任务: %s", taskID)
+			return inspector.CancelProcessing(taskID)
+		case asynq.TaskStateCompleted:
+			return nil
+		default:
+			r.log.Infof("从队列 %s 删除任务: %s (状态: %s)", queue, taskID, info.State)
+			return inspector.DeleteTask(queue, taskID)
+		}
+	}
+
+	// 队列中不存在该任务（已执行完毕或已被清理）
+	return nil
+}
+
+// activateScheduledTask 定时任务到达执行时间后转为等待中状态
+func (r *TaskQueue) activateScheduledTask(ctx context.Context, taskID string) {
+	if err := r.taskService.ActivateScheduledTask(ctx, taskID); err != nil {
+		r.log.Warnf("更新定时任务状态失败: %s, %v", taskID, err)
+	}
+}
+
+// shouldSkipTask 检查任务是否已被取消或删除，避免继续执行
+func (r *TaskQueue) shouldSkipTask(ctx context.Context, taskID string) bool {
+	cancelled, err := r.taskService.IsTaskCancelled(ctx, taskID)
+	if err != nil {
+		r.log.Warnf("查询任务取消状态失败: %s, %v", taskID, err)
+		return false
+	}
+	if cancelled {
+		r.log.Infof("任务已取消或删除，跳过执行: %s", taskID)
+	}
+	return cancelled
+}
+
 // 启动队列工作器
 func (r *TaskQueue) StartWorker(ctx context.Context) {
 	mux := asynq.NewServeMux()
@@ -123,6 +340,10 @@ func (r *TaskQueue) StartWorker(ctx context.Context) {
 	mux.HandleFunc(TypeTextGeneration, r.handleTextGeneration)
 	mux.HandleFunc(TypeImageGeneration, r.handleImageGeneration)
 	mux.HandleFunc(TypeVideoGeneration, r.handleVideoGeneration)
+	mux.HandleFunc(TypeStatusCheck, r.handleStatusCheck)
+	for taskType, handler := range r.handlers {
+		mux.HandleFunc(taskType, handler)
+	}
 
 	r.log.Info("队列工作器启动中...")
 	if err := r.server.Start(mux); err != nil {
@@ -142,121 +363,365 @@ func (r *TaskQueue) Close() error {
 
 // 文本生成任务处理器
 func (r *TaskQueue) handleTextGeneration(ctx context.Context, task *asynq.Task) error {
+	return r.handleGeneration(ctx, task, "文本生成", func(dispatcher AITaskDispatcher, payload *AITaskPayload, model string) error {
+		return dispatcher.DispatchTextTask(ctx, payload.TaskID, model, payload.Input)
+	})
+}
+
+// 图像生成任务处理器
+func (r *TaskQueue) handleImageGeneration(ctx context.Context, task *asynq.Task) error {
+	return r.handleGeneration(ctx, task, "图像生成", func(dispatcher AITaskDispatcher, payload *AITaskPayload, model string) error {
+		return dispatcher.DispatchImageTask(ctx, payload.TaskID, model, payload.Input)
+	})
+}
+
+// 视频生成任务处理器
+func (r *TaskQueue) handleVideoGeneration(ctx context.Context, task *asynq.Task) error {
+	return r.handleGeneration(ctx, task, "视频生成", func(dispatcher AITaskDispatcher, payload *AITaskPayload, model string) error {
+		return dispatcher.DispatchVideoTask(ctx, payload.TaskID, model, payload.Input)
+	})
+}
+
+// dispatchFunc 调用分发器执行任务
+type dispatchFunc func(dispatcher AITaskDispatcher, payload *AITaskPayload, model string) error
+
+// handleGeneration 生成任务的通用处理流程，按故障转移策略依次尝试候选服务商和模型
+func (r *TaskQueue) handleGeneration(ctx context.Context, task *asynq.Task, label string, dispatch dispatchFunc) error {
 	var payload AITaskPayload
 	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
 		return err
 	}
 
-	r.log.Infof("处理文本生成任务: %s, 用户: %s, 提供商: %s", payload.TaskID, payload.UserID, payload.Provider)
+	r.log.Infof("处理%s任务: %s, 用户: %s, 提供商: %s", label, payload.TaskID, payload.UserID, payload.Provider)
 
-	// 获取对应的AI任务分发器
-	dispatcher, exists := r.serviceRegistry.GetDispatcher(payload.Provider)
-	if !exists {
-		errorMsg := fmt.Sprintf("未找到AI任务分发器: %s", payload.Provider)
-		r.log.Error(errorMsg)
-		// 文本任务暂无数据库状态管理，返回SkipRetry错误让任务被正确归档
-		return fmt.Errorf("未找到AI任务分发器: %s: %w", payload.Provider, asynq.SkipRetry)
+	if r.shouldSkipTask(ctx, payload.TaskID) {
+		return fmt.Errorf("任务已取消或删除: %s: %w", payload.TaskID, asynq.SkipRetry)
+	}
+	r.activateScheduledTask(ctx, payload.TaskID)
+
+	retried, _ := asynq.GetRetryCount(ctx)
+	if err := r.taskService.StartTask(ctx, payload.TaskID, retried+1); err != nil {
+		// 任务已结束（如重复投递的队列任务）时不再执行
+		var transitionErr *service.TaskTransitionError
+		if errors.As(err, &transitionErr) {
+			return fmt.Errorf("%v: %w", err, asynq.SkipRetry)
+		}
+		return err
 	}
 
-	// 调用分发器的文本生成分发方法
-	if err := dispatcher.DispatchTextTask(ctx, payload.TaskID, payload.Model, payload.Input); err != nil {
-		r.log.Errorf("文本生成任务分发失败: %v", err)
-		// 文本任务暂无数据库状态管理，返回SkipRetry错误让任务被正确归档
-		return fmt.Errorf("文本生成任务分发失败: %v: %w", err, asynq.SkipRetry)
+	// 已提交到服务商的视频任务（如Worker重启后重新执行）不再重复提交，直接恢复状态检查
+	if task.Type() == TypeVideoGeneration {
+		if resumed, err := r.resumeStatusCheck(ctx, &payload); resumed || err != nil {
+			return err
+		}
 	}
 
-	r.log.Infof("文本生成任务完成: %s", payload.TaskID)
+	if err := r.dispatchWithFailover(ctx, &payload, dispatch); err != nil {
+		r.log.Errorf("%s任务分发失败: %v", label, err)
+		// 任务在执行期间被取消时不再重试
+		if r.shouldSkipTask(context.Background(), payload.TaskID) {
+			return fmt.Errorf("%s任务已取消: %v: %w", label, err, asynq.SkipRetry)
+		}
+		genErr := r.failGeneration(ctx, payload.TaskID, err)
+		// 任务最终失败时由TaskStream作为任务结束通知发布error事件，这里只通知将重试
+		if task.Type() == TypeTextGeneration && r.stream != nil && !errors.Is(genErr, asynq.SkipRetry) {
+			if pubErr := r.stream.PublishTextRetrying(ctx, payload.TaskID, err.Error()); pubErr != nil {
+				r.log.Warnf("发布流式重试事件失败: taskID=%s, %v", payload.TaskID, pubErr)
+			}
+		}
+		return genErr
+	}
+
+	r.log.Infof("%s任务完成: %s", label, payload.TaskID)
 	return nil
 }
 
-// 图像生成任务处理器
-func (r *TaskQueue) handleImageGeneration(ctx context.Context, task *asynq.Task) error {
-	var payload AITaskPayload
-	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+// failGeneration 按错误类型决定任务重试还是结束
+// 可重试的错误在最后一次重试前保持任务处理中状态，不可重试的错误或最后一次重试失败时才标记任务失败
+func (r *TaskQueue) failGeneration(ctx context.Context, taskID string, err error) error {
+	// 熔断或限流时由队列延迟后重新执行，不计入重试次数
+	if _, backoff := backoffDelay(err); backoff {
 		return err
 	}
 
-	r.log.Infof("处理图像生成任务: %s, 用户: %s, 提供商: %s", payload.TaskID, payload.UserID, payload.Provider)
+	if IsRetryableError(err) && !isLastAttempt(ctx) {
+		retried, _ := asynq.GetRetryCount(ctx)
+		r.log.Warnf("任务执行失败，将重试: taskID=%s, 已重试=%d, %v", taskID, retried, err)
+		return err
+	}
 
-	// 获取对应的AI任务分发器
-	dispatcher, exists := r.serviceRegistry.GetDispatcher(payload.Provider)
-	if !exists {
+	if updateErr := r.taskService.FailTask(ctx, taskID, err); updateErr != nil {
+		r.log.Errorf("更新任务状态失败: %s, %v", taskID, updateErr)
+	}
+	return fmt.Errorf("%v: %w", err, asynq.SkipRetry)
+}
+
+// isLastAttempt 当前是否为队列任务的最后一次执行
+func isLastAttempt(ctx context.Context) bool {
+	retried, ok := asynq.GetRetryCount(ctx)
+	maxRetry, hasMax := asynq.GetMaxRetry(ctx)
+	return !ok || !hasMax || retried >= maxRetry
+}
+
+// dispatchWithFailover 依次尝试候选服务商和模型，跳过未注册或熔断中的候选
+// 候选返回可重试的错误时切换到下一个候选，全部失败时返回最后一个错误
+func (r *TaskQueue) dispatchWithFailover(ctx context.Context, payload *AITaskPayload, dispatch dispatchFunc) error {
+	taskType := strings.TrimSuffix(payload.Type, "_generation")
+	candidates := config.FailoverCandidates(taskType, payload.Provider, payload.Model)
+
+	var lastErr error
+	registered := false
+	for i, candidate := range candidates {
+		dispatcher, exists := r.serviceRegistry.GetDispatcher(candidate.Provider)
+		if !exists {
+			r.log.Warnf("未找到AI任务分发器，跳过候选: %s", candidate.Provider)
+			continue
+		}
+		registered = true
+
+		// API服务器已按模型参数规则校验，这里防御性地再次校验，请求的模型参数无效时直接失败，备选模型不适用时跳过
+		if err := r.validateInput(candidate.Provider, taskType, candidate.Model, payload.Input); err != nil {
+			if i == 0 {
+				return err
+			}
+			r.log.Warnf("任务参数不适用于备选模型，跳过候选: taskID=%s, provider=%s, model=%s, %v", payload.TaskID, candidate.Provider, candidate.Model, err)
+			continue
+		}
+
+		if !r.health.Allow(candidate.Provider, candidate.Model) {
+			r.log.Warnf("服务商熔断中，跳过候选: taskID=%s, provider=%s, model=%s", payload.TaskID, candidate.Provider, candidate.Model)
+			lastErr = &ProviderBackoffError{
+				Provider: candidate.Provider,
+				Model:    candidate.Model,
+				Reason:   "熔断中",
+				Delay:    config.ProviderBreakerCooldown,
+			}
+			continue
+		}
+
+		if err := r.taskService.RecordServedBy(ctx, payload.TaskID, candidate.Provider, candidate.Model); err != nil {
+			r.log.Warnf("记录执行服务商失败: %s, %v", payload.TaskID, err)
+		}
+
+		err := dispatch(dispatcher, payload, candidate.Model)
+		if err == nil {
+			r.health.RecordSuccess(candidate.Provider, candidate.Model)
+			return nil
+		}
+		// 参数错误等不可重试的错误与服务商健康状态无关，换用其他候选也不会成功
+		if !IsRetryableError(err) {
+			return err
+		}
+		// 限流器拒绝调用时没有请求服务商，不计入健康统计
+		if _, backoff := backoffDelay(err); !backoff {
+			r.health.RecordFailure(candidate.Provider, candidate.Model, err)
+		}
+		lastErr = err
+
+		if i < len(candidates)-1 {
+			if r.shouldSkipTask(ctx, payload.TaskID) {
+				return err
+			}
+			r.log.Warnf("候选执行失败，切换到下一个候选: taskID=%s, provider=%s, model=%s, %v", payload.TaskID, candidate.Provider, candidate.Model, err)
+		}
+	}
+
+	if !registered {
 		errorMsg := fmt.Sprintf("未找到AI任务分发器: %s", payload.Provider)
 		r.log.Error(errorMsg)
-		r.taskService.UpdateTaskError(ctx, payload.TaskID, errorMsg)
-		return fmt.Errorf("未找到AI任务分发器: %s: %w", payload.Provider, asynq.SkipRetry)
+		r.taskService.FailTask(ctx, payload.TaskID, service.NewProviderError(config.TaskErrorInvalidInput, errorMsg, nil))
+		return fmt.Errorf("%s: %w", errorMsg, asynq.SkipRetry)
 	}
+	// 所有候选都被熔断或限流时任务保持当前状态，由队列按延迟时间重新执行
+	return lastErr
+}
 
-	// 调用分发器的图像生成分发方法
-	if err := dispatcher.DispatchImageTask(ctx, payload.TaskID, payload.Model, payload.Input); err != nil {
-		r.log.Errorf("图像生成任务分发失败: %v", err)
-		return err // 让任务重试
+// validateInput 按服务商声明的模型参数规则校验任务输入，参数无效时返回不可重试的错误
+func (r *TaskQueue) validateInput(provider, taskType, model string, input map[string]interface{}) error {
+	capability, exists := r.serviceRegistry.GetCapabilities(provider)
+	if !exists {
+		return nil
+	}
+	modelCapability, found := capability.FindModel(taskType, model)
+	if !found {
+		return service.NewProviderError(config.TaskErrorInvalidInput, fmt.Sprintf("服务提供商%s不支持%s模型: %s", provider, taskType, model), nil)
+	}
+	if errs := service.ValidateModelInput(modelCapability, input); len(errs) > 0 {
+		return service.NewProviderError(config.TaskErrorInvalidInput, "任务参数无效: "+util.JoinValidationErrors(errs), nil)
 	}
-
-	r.log.Infof("图像生成任务完成: %s", payload.TaskID)
 	return nil
 }
 
-// 视频生成任务处理器
-func (r *TaskQueue) handleVideoGeneration(ctx context.Context, task *asynq.Task) error {
-	var payload AITaskPayload
+// resumeStatusCheck 任务已记录服务商任务ID时重新安排状态检查，返回是否已恢复
+// 按实际提交的服务商和模型查询，故障转移后可能与请求的不同
+func (r *TaskQueue) resumeStatusCheck(ctx context.Context, payload *AITaskPayload) (bool, error) {
+	current, err := r.taskService.GetTask(ctx, payload.TaskID)
+	if err != nil || current.ProviderTaskID == "" {
+		return false, nil
+	}
+
+	provider, model := payload.Provider, payload.Model
+	if current.ServedProvider != "" {
+		provider, model = current.ServedProvider, current.ServedModel
+	}
+	dispatcher, exists := r.serviceRegistry.GetDispatcher(provider)
+	if !exists {
+		return false, nil
+	}
+	if _, ok := dispatcher.(VideoStatusChecker); !ok {
+		return false, nil
+	}
+
+	r.log.Infof("任务已提交到服务商，恢复状态检查: %s, 外部任务ID: %s", payload.TaskID, current.ProviderTaskID)
+	return true, r.ScheduleStatusCheck(ctx, provider, model, payload.TaskID, current.ProviderTaskID, 0)
+}
+
+// 异步任务状态检查处理器
+// 每次只查询一次结果，未完成时重新入队，避免长时间占用Worker
+func (r *TaskQueue) handleStatusCheck(ctx context.Context, task *asynq.Task) error {
+	var payload StatusCheckPayload
 	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
-		return err
+		return fmt.Errorf("解析状态检查载荷失败: %v: %w", err, asynq.SkipRetry)
+	}
+
+	if r.shouldSkipTask(ctx, payload.TaskID) {
+		return fmt.Errorf("任务已取消或删除: %s: %w", payload.TaskID, asynq.SkipRetry)
 	}
 
-	r.log.Infof("处理视频生成任务: %s, 用户: %s, 提供商: %s", payload.TaskID, payload.UserID, payload.Provider)
+	// Worker在确认前重启会重复执行同一次检查，任务已结束时直接返回
+	if current, err := r.taskService.GetTask(ctx, payload.TaskID); err == nil &&
+		(current.Status == config.TaskStatusCompleted || current.Status == config.TaskStatusFailed) {
+		return nil
+	}
 
-	// 获取对应的AI任务分发器
 	dispatcher, exists := r.serviceRegistry.GetDispatcher(payload.Provider)
-	if !exists {
-		errorMsg := fmt.Sprintf("未找到AI任务分发器: %s", payload.Provider)
+	checker, ok := dispatcher.(VideoStatusChecker)
+	if !exists || !ok {
+		errorMsg := fmt.Sprintf("AI任务分发器不支持状态检查: %s", payload.Provider)
 		r.log.Error(errorMsg)
-		r.taskService.UpdateTaskError(ctx, payload.TaskID, errorMsg)
-		return fmt.Errorf("未找到AI任务分发器: %s: %w", payload.Provider, asynq.SkipRetry)
+		r.taskService.FailTask(ctx, payload.TaskID, service.NewProviderError(config.TaskErrorInvalidInput, errorMsg, nil))
+		return fmt.Errorf("%s: %w", errorMsg, asynq.SkipRetry)
 	}
 
-	// 调用分发器的视频生成分发方法
-	if err := dispatcher.DispatchVideoTask(ctx, payload.TaskID, payload.Model, payload.Input); err != nil {
-		r.log.Errorf("视频生成任务分发失败: %v", err)
-		return err // 让任务重试
+	done, err := checker.CheckVideoTask(ctx, payload.TaskID, payload.Model, payload.ProviderTaskID)
+	if done {
+		if err != nil {
+			// 结果已产出但转存或保存失败（如存储暂时不可用），由队列重新执行本次检查，再次查询会重新保存结果
+			if IsRetryableError(err) && !isLastAttempt(ctx) {
+				r.log.Warnf("保存任务结果失败，将重试: taskID=%s, %v", payload.TaskID, err)
+				return err
+			}
+			r.taskService.FailTask(ctx, payload.TaskID, err)
+			return fmt.Errorf("保存任务结果失败: %v: %w", err, asynq.SkipRetry)
+		}
+		r.log.Infof("状态检查完成，任务已结束: %s", payload.TaskID)
+		return nil
+	}
+	if err != nil {
+		// 熔断或限流时不计入检查次数，由队列延迟后重新执行本次检查
+		if _, backoff := backoffDelay(err); backoff {
+			return err
+		}
+		// 内容审核未通过等错误再次查询也不会改变结果
+		if !IsRetryableError(err) {
+			r.taskService.FailTask(ctx, payload.TaskID, err)
+			return fmt.Errorf("%v: %w", err, asynq.SkipRetry)
+		}
+		r.log.Warnf("查询任务状态失败，将重试: taskID=%s, attempt=%d, %v", payload.TaskID, payload.Attempt+1, err)
 	}
 
-	r.log.Infof("视频生成任务完成: %s", payload.TaskID)
-	return nil
+	next := payload.Attempt + 1
+	if next >= config.StatusCheckMaxAttempts {
+		errorMsg := fmt.Sprintf("任务状态检查超时: taskID=%s, 总检查次数=%d", payload.TaskID, next)
+		if err != nil {
+			errorMsg += fmt.Sprintf(", 最后错误: %v", err)
+		}
+		r.log.Error(errorMsg)
+		r.taskService.FailTask(ctx, payload.TaskID, service.NewProviderError(config.TaskErrorTimeout, errorMsg, nil))
+		return fmt.Errorf("%s: %w", errorMsg, asynq.SkipRetry)
+	}
+
+	if err := r.taskService.RecordPollAttempt(ctx, payload.TaskID, next); err != nil {
+		r.log.Warnf("更新任务进度失败: %s, %v", payload.TaskID, err)
+	}
+	return r.ScheduleStatusCheck(ctx, payload.Provider, payload.Model, payload.TaskID, payload.ProviderTaskID, next)
 }
 
-// 获取队列统计信息
+// 获取队列统计信息，汇总所有队列并附带各队列明细
 func (r *TaskQueue) GetQueueStats(ctx context.Context) (*QueueStats, error) {
 	inspector := asynq.NewInspector(r.opt)
 	defer inspector.Close()
 
-	stats, err := inspector.GetQueueInfo("default")
+	result := &QueueStats{
+		Queues:    make(map[string]QueueCounts, len(queueNames)),
+		Timestamp: time.Now(),
+	}
+
+	// 还没有任务进入过的队列在Redis中不存在，查询会报错，只统计已存在的队列
+	existing, err := inspector.Queues()
 	if err != nil {
 		return nil, err
 	}
+	exists := make(map[string]bool, len(existing))
+	for _, queue := range existing {
+		exists[queue] = true
+	}
 
-	return &QueueStats{
-		Pending:   stats.Pending,
-		Active:    stats.Active,
-		Scheduled: stats.Scheduled,
-		Retry:     stats.Retry,
-		Archived:  stats.Archived,
-		Completed: stats.Completed,
-		Processed: stats.Processed,
-		Failed:    stats.Failed,
-		Timestamp: time.Now(),
-	}, nil
+	for _, queue := range queueNames {
+		if !exists[queue] {
+			result.Queues[queue] = QueueCounts{}
+			continue
+		}
+
+		info, err := inspector.GetQueueInfo(queue)
+		if err != nil {
+			return nil, err
+		}
+
+		counts := QueueCounts{
+			Pending:   info.Pending,
+			Active:    info.Active,
+			Scheduled: info.Scheduled,
+			Retry:     info.Retry,
+			Archived:  info.Archived,
+			Completed: info.Completed,
+			Processed: info.Processed,
+			Failed:    info.Failed,
+		}
+		result.Queues[queue] = counts
+		result.add(counts)
+	}
+
+	return result, nil
 }
 
-// 队列统计信息结构
+// QueueCounts 单个队列的任务数量
+type QueueCounts struct {
+	Pending   int `json:"pending"`
+	Active    int `json:"active"`
+	Scheduled int `json:"scheduled"`
+	Retry     int `json:"retry"`
+	Archived  int `json:"archived"`
+	Completed int `json:"completed"`
+	Processed int `json:"processed"`
+	Failed    int `json:"failed"`
+}
+
+// 队列统计信息结构，顶层字段为所有队列的合计
 type QueueStats struct {
-	Pending   int       `json:"pending"`
-	Active    int       `json:"active"`
-	Scheduled int       `json:"scheduled"`
-	Retry     int       `json:"retry"`
-	Archived  int       `json:"archived"`
-	Completed int       `json:"completed"`
-	Processed int       `json:"processed"`
-	Failed    int       `json:"failed"`
-	Timestamp time.Time `json:"timestamp"`
+	QueueCounts
+	Queues    map[string]QueueCounts `json:"queues"`
+	Timestamp time.Time              `json:"timestamp"`
+}
+
+// add 累加单个队列的任务数量
+func (s *QueueStats) add(c QueueCounts) {
+	s.Pending += c.Pending
+	s.Active += c.Active
+	s.Scheduled += c.Scheduled
+	s.Retry += c.Retry
+	s.Archived += c.Archived
+	s.Completed += c.Completed
+	s.Processed += c.Processed
+	s.Failed += c.Failed
 }
@@ -0,0 +1,211 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+
+	"volcengine-go-server/config"
+	"volcengine-go-server/internal/models"
+	"volcengine-go-server/pkg/logger"
+)
+
+// 流式事件类型常量
+const (
+	StreamEventDelta    = "delta"    // 增量文本
+	StreamEventDone     = "done"     // 生成完成
+	StreamEventError    = "error"    // 任务最终失败或被取消
+	StreamEventRetrying = "retrying" // 本次执行失败，任务将重试，不是最终事件
+	StreamEventReset    = "reset"    // 重新开始生成，之前收到的文本作废
+)
+
+// TaskStreamEvent 流式任务事件
+type TaskStreamEvent struct {
+	Type      string `json:"type"`
+	Content   string `json:"content,omitempty"` // delta为增量文本，done为完整文本
+	Offset    int    `json:"offset"`            // delta在累积文本中的起始字节偏移
+	Error     string `json:"error,omitempty"`
+	ErrorCode string `json:"error_code,omitempty"`
+}
+
+// TaskStream 基于Redis发布订阅的任务流式输出通道
+// Worker进程发布增量结果，API进程订阅并转发给客户端
+type TaskStream struct {
+	client redis.UniversalClient
+	log    *logrus.Logger
+}
+
+// NewTaskStream 创建任务流式输出通道（与TaskQueue共用同一个Redis）
+func NewTaskStream(redisURL string) *TaskStream {
+	opt, err := asynq.ParseRedisURI(redisURL)
+	if err != nil {
+		logger.GetLogger().Fatal("解析Redis URL失败: ", err)
+	}
+
+	client, ok := opt.MakeRedisClient().(redis.UniversalClient)
+	if !ok {
+		logger.GetLogger().Fatal("创建Redis客户端失败: 不支持的连接类型")
+	}
+
+	return &TaskStream{
+		client: client,
+		log:    logger.GetLogger(),
+	}
+}
+
+// PublishTextDelta 发布增量文本，同时追加到缓冲区供中途加入的订阅者补齐
+func (s *TaskStream) PublishTextDelta(ctx context.Context, taskID, delta string) error {
+	bufferKey := streamBufferKey(taskID)
+
+	length, err := s.client.Append(ctx, bufferKey, delta).Result()
+	if err != nil {
+		return fmt.Errorf("追加流式缓冲区失败: %w", err)
+	}
+	s.client.Expire(ctx, bufferKey, config.TaskStreamBufferTTL)
+
+	return s.publish(ctx, taskID, &TaskStreamEvent{
+		Type:    StreamEventDelta,
+		Content: delta,
+		Offset:  int(length) - len(delta),
+	})
+}
+
+// PublishTextDone 发布生成完成事件
+func (s *TaskStream) PublishTextDone(ctx context.Context, taskID, text string) error {
+	return s.publish(ctx, taskID, &TaskStreamEvent{
+		Type:    StreamEventDone,
+		Content: text,
+	})
+}
+
+// PublishTextError 发布任务最终失败事件，只在任务标记为失败或取消后调用，同时清空缓冲区
+func (s *TaskStream) PublishTextError(ctx context.Context, taskID, errorMsg, errorCode string) error {
+	s.client.Del(ctx, streamBufferKey(taskID))
+	return s.publish(ctx, taskID, &TaskStreamEvent{
+		Type:      StreamEventError,
+		Error:     errorMsg,
+		ErrorCode: errorCode,
+	})
+}
+
+// PublishTextRetrying 发布本次执行失败、任务将重试的事件
+// 保留缓冲区，重试开始生成时由ResetText清空
+func (s *TaskStream) PublishTextRetrying(ctx context.Context, taskID, errorMsg string) error {
+	return s.publish(ctx, taskID, &TaskStreamEvent{
+		Type:  StreamEventRetrying,
+		Error: errorMsg,
+	})
+}
+
+// ResetText 开始生成前清空之前执行（重试或故障转移前的候选）留下的缓冲区
+// 缓冲区非空时发布reset事件，通知订阅者丢弃已收到的文本，避免新旧输出拼接在一起
+func (s *TaskStream) ResetText(ctx context.Context, taskID string) error {
+	deleted, err := s.client.Del(ctx, streamBufferKey(taskID)).Result()
+	if err != nil {
+		return fmt.Errorf("清空流式缓冲区失败: %w", err)
+	}
+	if deleted == 0 {
+		return nil
+	}
+	return s.publish(ctx, taskID, &TaskStreamEvent{Type: StreamEventReset})
+}
+
+// NotifyTaskFinished 实现service.TaskNotifier接口，文本任务最终失败或被取消时通知流式订阅者
+// 完成事件由文本生成服务在保存结果后发布
+func (s *TaskStream) NotifyTaskFinished(ctx context.Context, task *models.Task) error {
+	if task.Type != models.TaskTypeText {
+		return nil
+	}
+	switch task.Status {
+	case config.TaskStatusFailed:
+		return s.PublishTextError(ctx, task.ID, task.Error, task.ErrorCode)
+	case config.TaskStatusCancelled:
+		return s.PublishTextError(ctx, task.ID, "任务已取消", "")
+	}
+	return nil
+}
+
+// GetBuffer 获取已累积的文本
+func (s *TaskStream) GetBuffer(ctx context.Context, taskID string) (string, error) {
+	text, err := s.client.Get(ctx, streamBufferKey(taskID)).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return text, err
+}
+
+// Subscribe 订阅指定任务的流式事件
+// 返回时订阅已经生效，调用方随后读取缓冲区不会遗漏事件
+func (s *TaskStream) Subscribe(ctx context.Context, taskID string) (*TaskStreamSubscription, error) {
+	pubsub := s.client.Subscribe(ctx, streamChannel(taskID))
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("订阅任务流失败: %w", err)
+	}
+
+	events := make(chan TaskStreamEvent)
+	go func() {
+		defer close(events)
+		for msg := range pubsub.Channel() {
+			var event TaskStreamEvent
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				s.log.Warnf("解析流式事件失败: %v", err)
+				continue
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return &TaskStreamSubscription{
+		pubsub: pubsub,
+		events: events,
+	}, nil
+}
+
+// Close 关闭Redis连接
+func (s *TaskStream) Close() error {
+	return s.client.Close()
+}
+
+// publish 发布事件到任务频道
+func (s *TaskStream) publish(ctx context.Context, taskID string, event *TaskStreamEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return s.client.Publish(ctx, streamChannel(taskID), data).Err()
+}
+
+// TaskStreamSubscription 任务流订阅
+type TaskStreamSubscription struct {
+	pubsub *redis.PubSub
+	events chan TaskStreamEvent
+}
+
+// Events 返回事件通道
+func (sub *TaskStreamSubscription) Events() <-chan TaskStreamEvent {
+	return sub.events
+}
+
+// Close 取消订阅
+func (sub *TaskStreamSubscription) Close() error {
+	return sub.pubsub.Close()
+}
+
+// streamChannel 任务流发布订阅频道名
+func streamChannel(taskID string) string {
+	return "ai:task_stream:" + taskID
+}
+
+// streamBufferKey 任务流累积文本缓冲区键名
+func streamBufferKey(taskID string) string {
+	return "ai:task_stream:buffer:" + taskID
+}
@@ -0,0 +1,240 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"volcengine-go-server/config"
+	"volcengine-go-server/internal/models"
+	"volcengine-go-server/internal/service"
+	"volcengine-go-server/pkg/logger"
+)
+
+// 回调请求头常量
+const (
+	WebhookHeaderEvent     = "X-Webhook-Event"
+	WebhookHeaderDelivery  = "X-Webhook-Delivery"
+	WebhookHeaderTimestamp = "X-Webhook-Timestamp"
+	WebhookHeaderSignature = "X-Webhook-Signature"
+)
+
+// WebhookPayload 回调投递队列载荷
+type WebhookPayload struct {
+	TaskID string `json:"task_id"`
+	Event  string `json:"event"`
+}
+
+// WebhookBody 回调请求体，data与任务结果查询接口返回的数据一致
+type WebhookBody struct {
+	Event      string                 `json:"event"`
+	DeliveryID string                 `json:"delivery_id"`
+	Data       map[string]interface{} `json:"data"`
+}
+
+// WebhookDispatcher 任务回调投递器
+// 任务结束时入队投递任务，由Worker执行HTTP回调并按退避策略重试
+type WebhookDispatcher struct {
+	queue          *TaskQueue
+	taskService    *service.TaskService
+	webhookService *service.WebhookService
+	httpClient     *http.Client
+	log            *logrus.Logger
+}
+
+// NewWebhookDispatcher 创建任务回调投递器
+func NewWebhookDispatcher(queue *TaskQueue, taskService *service.TaskService, webhookService *service.WebhookService) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		queue:          queue,
+		taskService:    taskService,
+		webhookService: webhookService,
+		httpClient:     service.NewCallbackHTTPClient(config.WebhookTimeout),
+		log:            logger.GetLogger(),
+	}
+}
+
+// NotifyTaskFinished 实现service.TaskNotifier接口，将回调投递任务放入队列
+// 只投递设置了回调地址的已完成或失败任务，取消的任务不回调
+func (d *WebhookDispatcher) NotifyTaskFinished(ctx context.Context, task *models.Task) error {
+	if task.CallbackURL == "" || (task.Status != config.TaskStatusCompleted && task.Status != config.TaskStatusFailed) {
+		return nil
+	}
+
+	event := models.WebhookEventTaskCompleted
+	if task.Status == config.TaskStatusFailed {
+		event = models.WebhookEventTaskFailed
+	}
+
+	data, err := json.Marshal(&WebhookPayload{
+		TaskID: task.ID,
+		Event:  event,
+	})
+	if err != nil {
+		return err
+	}
+
+	// 同一任务的同一事件只投递一次
+	queueTask := asynq.NewTask(TypeWebhookDelivery, data,
+		asynq.TaskID(fmt.Sprintf("webhook:%s:%s", task.ID, event)),
+		asynq.MaxRetry(config.WebhookMaxRetry),
+		asynq.Queue(QueueDefault),
+	)
+	if _, err := d.queue.client.Enqueue(queueTask); err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+		return err
+	}
+
+	d.log.Infof("任务回调已入队: taskID=%s, event=%s", task.ID, event)
+	return nil
+}
+
+// HandleDelivery 回调投递任务处理器
+func (d *WebhookDispatcher) HandleDelivery(ctx context.Context, t *asynq.Task) error {
+	var payload WebhookPayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		return fmt.Errorf("解析回调载荷失败: %v: %w", err, asynq.SkipRetry)
+	}
+
+	task, err := d.taskService.GetTask(ctx, payload.TaskID)
+	if err != nil {
+		return fmt.Errorf("回调任务不存在: %s: %w", payload.TaskID, asynq.SkipRetry)
+	}
+	if task.CallbackURL == "" {
+		return nil
+	}
+
+	retryCount, _ := asynq.GetRetryCount(ctx)
+	attempt := retryCount + 1
+	deliveryID := primitive.NewObjectID().Hex()
+
+	body, err := json.Marshal(&WebhookBody{
+		Event:      payload.Event,
+		DeliveryID: deliveryID,
+		Data:       task.ResultData(),
+	})
+	if err != nil {
+		return fmt.Errorf("构建回调请求体失败: %v: %w", err, asynq.SkipRetry)
+	}
+
+	startTime := time.Now()
+	statusCode, sendErr := d.send(ctx, task, payload.Event, deliveryID, body)
+
+	delivery := &models.WebhookDelivery{
+		ID:         deliveryID,
+		TaskID:     task.ID,
+		Event:      payload.Event,
+		URL:        task.CallbackURL,
+		Attempt:    attempt,
+		Success:    sendErr == nil,
+		StatusCode: statusCode,
+		DurationMs: time.Since(startTime).Milliseconds(),
+		Created:    startTime,
+	}
+	if sendErr != nil {
+		delivery.Error = sendErr.Error()
+	}
+	if err := d.webhookService.RecordDelivery(ctx, delivery); err != nil {
+		d.log.Warnf("记录回调投递失败: %s, %v", task.ID, err)
+	}
+
+	fields := logrus.Fields{
+		"task_id":     task.ID,
+		"event":       payload.Event,
+		"attempt":     attempt,
+		"status_code": statusCode,
+		"duration_ms": delivery.DurationMs,
+	}
+	if sendErr != nil {
+		fields["error"] = sendErr.Error()
+		if !isRetryableDelivery(statusCode, sendErr) {
+			d.log.WithFields(fields).Warn("任务回调投递失败，不再重试")
+			return fmt.Errorf("%v: %w", sendErr, asynq.SkipRetry)
+		}
+		d.log.WithFields(fields).Warn("任务回调投递失败，将重试")
+		return sendErr
+	}
+
+	d.log.WithFields(fields).Info("任务回调投递成功")
+	return nil
+}
+
+// send 发送回调请求，返回HTTP状态码
+// 发送前重新校验回调地址，兼容校验规则上线前创建的任务和周期任务
+func (d *WebhookDispatcher) send(ctx context.Context, task *models.Task, event, deliveryID string, body []byte) (int, error) {
+	if err := service.ValidateCallbackURL(ctx, task.CallbackURL); err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, task.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("创建回调请求失败: %v", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "VolcengineAI-Webhook/1.0")
+	req.Header.Set(WebhookHeaderEvent, event)
+	req.Header.Set(WebhookHeaderDelivery, deliveryID)
+	req.Header.Set(WebhookHeaderTimestamp, timestamp)
+	if task.CallbackSecret != "" {
+		req.Header.Set(WebhookHeaderSignature, "sha256="+SignWebhookPayload(task.CallbackSecret, timestamp, body))
+	}
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("回调请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("回调接收方返回非2xx状态码: %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// isRetryableDelivery 判断投递失败是否需要重试
+// 回调地址指向内网时不重试；接收方返回4xx说明请求本身被拒绝，除408和429外重试也不会成功
+func isRetryableDelivery(statusCode int, err error) bool {
+	if errors.Is(err, service.ErrInvalidCallbackURL) {
+		return false
+	}
+	if statusCode >= 400 && statusCode < 500 {
+		return statusCode == http.StatusRequestTimeout || statusCode == http.StatusTooManyRequests
+	}
+	return true
+}
+
+// SignWebhookPayload 计算回调签名：HMAC-SHA256(secret, timestamp + "." + body)
+// 接收方使用相同方式计算并与X-Webhook-Signature比对
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookRetryDelay 回调重试的指数退避间隔
+func webhookRetryDelay(retried int) time.Duration {
+	delay := config.WebhookRetryBaseDelay
+	for i := 0; i < retried && delay < config.WebhookMaxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > config.WebhookMaxRetryDelay {
+		delay = config.WebhookMaxRetryDelay
+	}
+	return delay
+}
@@ -0,0 +1,77 @@
+package core
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"volcengine-go-server/config"
+	"volcengine-go-server/internal/service"
+)
+
+func TestSignWebhookPayload(t *testing.T) {
+	body := []byte(`{"event":"task.completed","data":{"task_id":"abc"}}`)
+	timestamp := "1700000000"
+
+	// 按文档约定独立计算期望签名
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(timestamp + "." + string(body)))
+	expected := hex.EncodeToString(mac.Sum(nil))
+
+	if got := SignWebhookPayload("secret", timestamp, body); got != expected {
+		t.Errorf("SignWebhookPayload() = %s, 期望 %s", got, expected)
+	}
+
+	if SignWebhookPayload("other", timestamp, body) == expected {
+		t.Error("不同密钥不应产生相同签名")
+	}
+	if SignWebhookPayload("secret", "1700000001", body) == expected {
+		t.Error("不同时间戳不应产生相同签名")
+	}
+}
+
+func TestWebhookRetryDelay(t *testing.T) {
+	tests := []struct {
+		retried  int
+		expected time.Duration
+	}{
+		{retried: 0, expected: config.WebhookRetryBaseDelay},
+		{retried: 1, expected: 2 * config.WebhookRetryBaseDelay},
+		{retried: 3, expected: 8 * config.WebhookRetryBaseDelay},
+		{retried: 50, expected: config.WebhookMaxRetryDelay},
+	}
+
+	for _, tt := range tests {
+		if got := webhookRetryDelay(tt.retried); got != tt.expected {
+			t.Errorf("webhookRetryDelay(%d) = %v, 期望 %v", tt.retried, got, tt.expected)
+		}
+	}
+}
+
+func TestIsRetryableDelivery(t *testing.T) {
+	statusErr := errors.New("回调接收方返回非2xx状态码")
+	tests := []struct {
+		name       string
+		statusCode int
+		err        error
+		expected   bool
+	}{
+		{"服务端错误", 502, statusErr, true},
+		{"网络错误", 0, errors.New("connection reset"), true},
+		{"请求超时", 408, statusErr, true},
+		{"限流", 429, statusErr, true},
+		{"地址不存在", 404, statusErr, false},
+		{"签名被拒绝", 401, statusErr, false},
+		{"内网地址", 0, fmt.Errorf("回调请求失败: %w", service.ErrInvalidCallbackURL), false},
+	}
+
+	for _, tt := range tests {
+		if got := isRetryableDelivery(tt.statusCode, tt.err); got != tt.expected {
+			t.Errorf("%s: isRetryableDelivery() = %v, 期望 %v", tt.name, got, tt.expected)
+		}
+	}
+}
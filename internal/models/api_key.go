@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// APIKey API密钥数据模型，只保存密钥的哈希值
+type APIKey struct {
+	ID         string     `json:"id" bson:"_id,omitempty"`
+	UserID     string     `json:"user_id" bson:"user_id"`
+	Name       string     `json:"name" bson:"name"`
+	Prefix     string     `json:"prefix" bson:"prefix"` // 密钥前几位，便于用户识别
+	KeyHash    string     `json:"-" bson:"key_hash"`
+	Revoked    bool       `json:"revoked" bson:"revoked"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" bson:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at" bson:"created_at"`
+}
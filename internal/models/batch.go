@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"volcengine-go-server/config"
+)
+
+// Batch 批量任务，子任务通过batch_id关联
+type Batch struct {
+	ID        string    `json:"id" bson:"_id,omitempty"`
+	UserID    string    `json:"user_id" bson:"user_id"`
+	TaskIDs   []string  `json:"task_ids" bson:"task_ids"` // 子任务ID，与提交顺序一致
+	Cost      int64     `json:"cost" bson:"cost"`         // 创建时扣除的总积分
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+// BatchProgress 批量任务进度，按子任务状态汇总
+type BatchProgress struct {
+	Total      int  `json:"total"`
+	Scheduled  int  `json:"scheduled"`
+	Pending    int  `json:"pending"`
+	Processing int  `json:"processing"`
+	Completed  int  `json:"completed"`
+	Failed     int  `json:"failed"`
+	Cancelled  int  `json:"cancelled"`
+	Done       bool `json:"done"` // 所有子任务都已结束
+}
+
+// NewBatchProgress 汇总子任务状态
+func NewBatchProgress(tasks []*Task) *BatchProgress {
+	progress := &BatchProgress{Total: len(tasks)}
+	for _, task := range tasks {
+		switch task.Status {
+		case config.TaskStatusScheduled:
+			progress.Scheduled++
+		case config.TaskStatusPending:
+			progress.Pending++
+		case config.TaskStatusProcessing:
+			progress.Processing++
+		case config.TaskStatusCompleted:
+			progress.Completed++
+		case config.TaskStatusFailed:
+			progress.Failed++
+		case config.TaskStatusCancelled:
+			progress.Cancelled++
+		}
+	}
+	progress.Done = progress.Completed+progress.Failed+progress.Cancelled == progress.Total
+	return progress
+}
@@ -0,0 +1,64 @@
+package models
+
+// 参数类型常量
+const (
+	ParamTypeString  = "string"   // 字符串
+	ParamTypeInteger = "integer"  // 整数
+	ParamTypeNumber  = "number"   // 数值
+	ParamTypeURLList = "url_list" // http(s)链接数组
+	ParamTypeImage   = "image"    // 图片：http(s)链接，或base64编码的图片数据（可带data URI前缀）
+)
+
+// ModelCapability 模型能力描述，供客户端选择模型，也用于在创建任务前和Worker执行前校验请求参数
+type ModelCapability struct {
+	Model    string      `json:"model"`
+	TaskType string      `json:"task_type"` // 任务类型: image, text, video
+	Name     string      `json:"name"`      // 展示名称
+	Params   []ParamRule `json:"params"`    // 参数校验规则，未声明的参数不做校验
+}
+
+// ParamRule 单个请求参数的校验规则
+type ParamRule struct {
+	Field     string      `json:"field"`
+	Type      string      `json:"type"`
+	Required  bool        `json:"required,omitempty"`
+	Default   interface{} `json:"default,omitempty"`    // 未填写时使用的默认值，仅用于展示
+	MaxLength int         `json:"max_length,omitempty"` // 字符串最大字符数，按Unicode字符计算，0表示不限制
+	Enum      []string    `json:"enum,omitempty"`       // 字符串可选值
+	Range     *ParamRange `json:"range,omitempty"`      // 数值取值范围
+	MinItems  int         `json:"min_items,omitempty"`  // 数组最少元素数
+	MaxItems  int         `json:"max_items,omitempty"`  // 数组最多元素数，0表示不限制
+}
+
+// ParamRange 数值取值范围，包含边界
+type ParamRange struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+// ProviderCapability 服务商能力描述
+type ProviderCapability struct {
+	Provider string            `json:"provider"`
+	Models   []ModelCapability `json:"models"`
+}
+
+// FindModel 查找指定任务类型的模型
+func (p ProviderCapability) FindModel(taskType, model string) (ModelCapability, bool) {
+	for _, m := range p.Models {
+		if m.Model == model && m.TaskType == taskType {
+			return m, true
+		}
+	}
+	return ModelCapability{}, false
+}
+
+// ModelNames 获取指定任务类型的全部模型名称
+func (p ProviderCapability) ModelNames(taskType string) []string {
+	var names []string
+	for _, m := range p.Models {
+		if m.TaskType == taskType {
+			names = append(names, m.Model)
+		}
+	}
+	return names
+}
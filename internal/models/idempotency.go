@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// 幂等记录状态
+const (
+	IdempotencyStatusProcessing = "processing"
+	IdempotencyStatusCompleted  = "completed"
+)
+
+// IdempotencyRecord 任务创建请求的幂等记录
+// 同一用户的同一Idempotency-Key只会创建一次任务，重放请求直接返回首次响应
+type IdempotencyRecord struct {
+	ID          string    `json:"id" bson:"_id,omitempty"`
+	UserID      string    `json:"user_id" bson:"user_id"`
+	Key         string    `json:"key" bson:"key"`
+	RequestHash string    `json:"request_hash" bson:"request_hash"` // 请求体摘要，用于识别同一key下的不同请求
+	Status      string    `json:"status" bson:"status"`
+	TaskID      string    `json:"task_id,omitempty" bson:"task_id,omitempty"`
+	StatusCode  int       `json:"status_code,omitempty" bson:"status_code,omitempty"`
+	Response    []byte    `json:"-" bson:"response,omitempty"`
+	Created     time.Time `json:"created" bson:"created"`
+	Updated     time.Time `json:"updated" bson:"updated"`
+}
@@ -0,0 +1,10 @@
+package models
+
+// MediaAsset 转存到自有存储的生成结果
+type MediaAsset struct {
+	URL      string `json:"url" bson:"url"`             // 稳定访问地址
+	Key      string `json:"key" bson:"key"`             // 存储中的对象key
+	SHA256   string `json:"sha256" bson:"sha256"`       // 内容哈希
+	Size     int64  `json:"size" bson:"size"`           // 字节数
+	MimeType string `json:"mime_type" bson:"mime_type"` // 内容类型
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"volcengine-go-server/config"
+)
+
+// Pipeline 多步骤生成流水线，各步骤依次执行，后续步骤可引用之前步骤的输出
+// 流水线和步骤的状态复用任务状态常量
+type Pipeline struct {
+	ID          string         `json:"id" bson:"_id,omitempty"`
+	UserID      string         `json:"user_id" bson:"user_id"`
+	Status      string         `json:"status" bson:"status"`             // pending, processing, completed, failed, cancelled
+	CurrentStep int            `json:"current_step" bson:"current_step"` // 当前执行的步骤序号，从0开始
+	Steps       []PipelineStep `json:"steps" bson:"steps"`
+	Cost        int64          `json:"cost" bson:"cost"` // 创建时扣除的全部步骤积分
+	Error       string         `json:"error,omitempty" bson:"error,omitempty"`
+	Created     time.Time      `json:"created" bson:"created"`
+	Updated     time.Time      `json:"updated" bson:"updated"`
+}
+
+// PipelineStep 流水线步骤，prompt和image_urls中的 {{prev.output}}、{{steps.N.output}} 在执行时替换为对应步骤的输出
+type PipelineStep struct {
+	Name     string `json:"name,omitempty" bson:"name,omitempty"`
+	Type     string `json:"type" bson:"type"` // image, video, text
+	Provider string `json:"provider" bson:"provider"`
+	Model    string `json:"model" bson:"model"`
+
+	// 输入模板
+	Prompt      string   `json:"prompt,omitempty" bson:"prompt,omitempty"`
+	ImageURLs   []string `json:"image_urls,omitempty" bson:"image_urls,omitempty"`
+	AspectRatio string   `json:"aspect_ratio,omitempty" bson:"aspect_ratio,omitempty"`
+	N           int      `json:"n,omitempty" bson:"n,omitempty"`
+	Seed        int64    `json:"seed,omitempty" bson:"seed,omitempty"`
+	MaxTokens   int      `json:"max_tokens,omitempty" bson:"max_tokens,omitempty"`
+	Temperature float64  `json:"temperature,omitempty" bson:"temperature,omitempty"`
+
+	// 执行状态
+	Cost       int64      `json:"cost" bson:"cost"`
+	Status     string     `json:"status" bson:"status"`
+	TaskID     string     `json:"task_id,omitempty" bson:"task_id,omitempty"` // 步骤开始时分配的任务ID
+	Output     string     `json:"output,omitempty" bson:"output,omitempty"`   // 文本结果，或图像、视频地址
+	Error      string     `json:"error,omitempty" bson:"error,omitempty"`
+	StartedAt  *time.Time `json:"started_at,omitempty" bson:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty" bson:"finished_at,omitempty"`
+}
+
+// IsFinished 流水线是否已结束
+func (p *Pipeline) IsFinished() bool {
+	switch p.Status {
+	case config.TaskStatusCompleted, config.TaskStatusFailed, config.TaskStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// PendingStepsCost 尚未开始的步骤的积分合计，流水线提前结束时退还
+func (p *Pipeline) PendingStepsCost() int64 {
+	var cost int64
+	for _, step := range p.Steps {
+		if step.Status == config.TaskStatusPending {
+			cost += step.Cost
+		}
+	}
+	return cost
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+)
+
+// Schedule 周期任务模板，Worker按cron表达式定期用模板创建生成任务
+type Schedule struct {
+	ID       string `json:"id" bson:"_id,omitempty"`
+	UserID   string `json:"user_id" bson:"user_id"`
+	Name     string `json:"name" bson:"name"`
+	Cron     string `json:"cron" bson:"cron"`                             // 标准5段cron表达式
+	Timezone string `json:"timezone,omitempty" bson:"timezone,omitempty"` // IANA时区，为空时使用UTC
+	Enabled  bool   `json:"enabled" bson:"enabled"`
+
+	// 任务模板
+	Type        string `json:"type" bson:"type"` // image, video, text
+	Provider    string `json:"provider" bson:"provider"`
+	Model       string `json:"model" bson:"model"`
+	Prompt      string `json:"prompt" bson:"prompt"`
+	AspectRatio string `json:"aspect_ratio,omitempty" bson:"aspect_ratio,omitempty"`
+	N           int    `json:"n,omitempty" bson:"n,omitempty"`
+
+	// 回调通知，每次创建的任务都使用该配置
+	CallbackURL    string `json:"callback_url,omitempty" bson:"callback_url,omitempty"`
+	CallbackSecret string `json:"-" bson:"callback_secret,omitempty"`
+
+	// 最近一次执行情况
+	LastRunAt  *time.Time `json:"last_run_at,omitempty" bson:"last_run_at,omitempty"`
+	LastTaskID string     `json:"last_task_id,omitempty" bson:"last_task_id,omitempty"`
+	LastError  string     `json:"last_error,omitempty" bson:"last_error,omitempty"`
+
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// CronSpec 返回带时区前缀的cron表达式
+func (s *Schedule) CronSpec() string {
+	if s.Timezone == "" {
+		return s.Cron
+	}
+	return "CRON_TZ=" + s.Timezone + " " + s.Cron
+}
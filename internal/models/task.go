@@ -2,10 +2,13 @@ package models
 
 import (
 	"time"
+
+	"volcengine-go-server/config"
 )
 
 // TaskInput 统一任务输入
 type TaskInput struct {
+	ID       string `json:"-"` // 预先分配的任务ID，为空时自动生成
 	Prompt   string `json:"prompt" binding:"required"`
 	UserID   string `json:"user_id" binding:"required"`
 	Type     string `json:"type" binding:"required"` // image, video, text
@@ -16,42 +19,116 @@ type TaskInput struct {
 	AspectRatio string `json:"aspect_ratio,omitempty"` // 宽高比例
 	N           int    `json:"n,omitempty"`            // 生成数量
 
-	// 视频生成特有字段
+	// 视频生成和图像编辑共用字段
 	Seed int64 `json:"seed,omitempty"` // 随机种子
 
+	// 图像编辑特有字段
+	SourceImageURL string  `json:"source_image_url,omitempty"` // 待编辑的原图地址
+	Strength       float64 `json:"strength,omitempty"`         // 编辑强度
+
 	// 文本生成特有字段
 	MaxTokens   int     `json:"max_tokens,omitempty"`
 	Temperature float64 `json:"temperature,omitempty"`
+
+	// 回调通知
+	CallbackURL    string `json:"callback_url,omitempty"`
+	CallbackSecret string `json:"callback_secret,omitempty"`
+
+	// 创建时已扣除的积分
+	Cost int64 `json:"cost,omitempty"`
+
+	// 计划执行时间，为空表示立即执行
+	RunAt *time.Time `json:"run_at,omitempty"`
+
+	// 创建该任务的周期任务ID
+	ScheduleID string `json:"schedule_id,omitempty"`
+
+	// 所属批量任务ID
+	BatchID string `json:"batch_id,omitempty"`
+
+	// 所属流水线ID和步骤序号
+	PipelineID   string `json:"pipeline_id,omitempty"`
+	PipelineStep int    `json:"pipeline_step,omitempty"`
 }
 
 // Task 统一任务数据模型
 type Task struct {
-	ID       string    `json:"id" bson:"_id,omitempty"`
-	UserID   string    `json:"user_id" bson:"user_id"`
-	Type     string    `json:"type" bson:"type"` // image, video, text
-	Prompt   string    `json:"prompt" bson:"prompt"`
-	Model    string    `json:"model" bson:"model"`
-	Provider string    `json:"provider" bson:"provider"`
-	Status   string    `json:"status" bson:"status"` // pending, processing, completed, failed
-	Error    string    `json:"error" bson:"error"`   // 错误信息
-	Created  time.Time `json:"created" bson:"created"`
-	Updated  time.Time `json:"updated" bson:"updated"`
+	ID        string     `json:"id" bson:"_id,omitempty"`
+	UserID    string     `json:"user_id" bson:"user_id"`
+	Type      string     `json:"type" bson:"type"` // image, video, text
+	Prompt    string     `json:"prompt" bson:"prompt"`
+	Model     string     `json:"model" bson:"model"`
+	Provider  string     `json:"provider" bson:"provider"`
+	Status    string     `json:"status" bson:"status"`                             // scheduled, pending, processing, completed, failed
+	Error     string     `json:"error" bson:"error"`                               // 错误信息
+	ErrorCode string     `json:"error_code,omitempty" bson:"error_code,omitempty"` // 错误码，见config.TaskError*
+	Created   time.Time  `json:"created" bson:"created"`
+	Updated   time.Time  `json:"updated" bson:"updated"`
+	RunAt     *time.Time `json:"run_at,omitempty" bson:"run_at,omitempty"` // 定时任务的计划执行时间
+
+	Progress int                `json:"progress" bson:"progress"`                   // 进度估算，0-100
+	History  []TaskStatusChange `json:"history,omitempty" bson:"history,omitempty"` // 状态变更历史，按时间顺序
+
+	ScheduleID string `json:"schedule_id,omitempty" bson:"schedule_id,omitempty"` // 由周期任务创建时记录周期任务ID
+	BatchID    string `json:"batch_id,omitempty" bson:"batch_id,omitempty"`       // 批量提交时记录所属批量任务ID
+
+	PipelineID   string `json:"pipeline_id,omitempty" bson:"pipeline_id,omitempty"`     // 流水线步骤创建的任务记录所属流水线ID
+	PipelineStep int    `json:"pipeline_step,omitempty" bson:"pipeline_step,omitempty"` // 流水线步骤序号，从0开始
+
+	// 实际执行任务的服务商和模型，故障转移后可能与请求的不同
+	ServedProvider string `json:"served_provider,omitempty" bson:"served_provider,omitempty"`
+	ServedModel    string `json:"served_model,omitempty" bson:"served_model,omitempty"`
+
+	// 服务商侧任务信息，只对管理员展示，便于与服务商核对问题
+	ProviderTaskID      string     `json:"-" bson:"provider_task_id,omitempty"`      // 服务商异步任务ID，状态检查任务据此查询结果
+	ReqKey              string     `json:"-" bson:"req_key,omitempty"`               // 服务商接口标识
+	ProviderSubmittedAt *time.Time `json:"-" bson:"provider_submitted_at,omitempty"` // 提交到服务商的时间
+	ProviderCompletedAt *time.Time `json:"-" bson:"provider_completed_at,omitempty"` // 服务商返回最终结果的时间
+	ProviderResponse    string     `json:"-" bson:"provider_response,omitempty"`     // 服务商最近一次原始响应，超长时截断
+
+	// 积分计费字段
+	Cost           int64 `json:"cost" bson:"cost"`                                           // 创建时扣除的积分
+	CreditRefunded bool  `json:"credit_refunded,omitempty" bson:"credit_refunded,omitempty"` // 任务失败或取消后积分已退还
+	CreditUnpaid   bool  `json:"credit_unpaid,omitempty" bson:"credit_unpaid,omitempty"`     // 重试成功后余额不足，退还的积分未能重新扣除
 
 	// 图像和视频生成共用字段
 	AspectRatio string `json:"aspect_ratio,omitempty" bson:"aspect_ratio,omitempty"` // 宽高比例
 
 	// 图像生成特有字段
-	N        int    `json:"n,omitempty" bson:"n,omitempty"`
-	ImageURL string `json:"image_url,omitempty" bson:"image_url,omitempty"`
+	N         int      `json:"n,omitempty" bson:"n,omitempty"`
+	ImageURL  string   `json:"image_url,omitempty" bson:"image_url,omitempty"`   // 第一张图像URL，兼容旧客户端
+	ImageURLs []string `json:"image_urls,omitempty" bson:"image_urls,omitempty"` // 全部N张图像URL
+
+	// 图像编辑特有字段，编辑结果与其他图像任务一样保存在image_url
+	SourceImageURL string  `json:"source_image_url,omitempty" bson:"source_image_url,omitempty"` // 待编辑的原图地址
+	Strength       float64 `json:"strength,omitempty" bson:"strength,omitempty"`                 // 编辑强度
+
+	// 视频生成和图像编辑共用字段
+	Seed int64 `json:"seed,omitempty" bson:"seed,omitempty"` // 随机种子
 
 	// 视频生成特有字段
-	Seed     int64  `json:"seed,omitempty" bson:"seed,omitempty"`           // 随机种子
 	VideoURL string `json:"video_url,omitempty" bson:"video_url,omitempty"` // 生成的视频URL
 
 	// 文本生成特有字段
 	MaxTokens   int     `json:"max_tokens,omitempty" bson:"max_tokens,omitempty"`
 	Temperature float64 `json:"temperature,omitempty" bson:"temperature,omitempty"`
 	TextResult  string  `json:"text_result,omitempty" bson:"text_result,omitempty"` // 生成的文本结果
+
+	// 转存后的媒体文件信息，与image_urls或video_url一一对应
+	Media []MediaAsset `json:"media,omitempty" bson:"media,omitempty"`
+
+	// 回调通知字段
+	CallbackURL    string `json:"callback_url,omitempty" bson:"callback_url,omitempty"`
+	CallbackSecret string `json:"-" bson:"callback_secret,omitempty"` // 签名密钥，不对外返回
+}
+
+// TaskStatusChange 任务状态变更记录
+type TaskStatusChange struct {
+	From    string    `json:"from,omitempty" bson:"from,omitempty"`
+	Status  string    `json:"status" bson:"status"`
+	Attempt int       `json:"attempt,omitempty" bson:"attempt,omitempty"` // 队列第几次执行任务，从1开始
+	Message string    `json:"message,omitempty" bson:"message,omitempty"`
+	At      time.Time `json:"at" bson:"at"`
 }
 
 // TaskType 任务类型常量
@@ -61,6 +138,16 @@ const (
 	TaskTypeText  = "text"
 )
 
+// LastAttempt 最近一次执行的序号，任务尚未执行时返回0
+func (t *Task) LastAttempt() int {
+	for i := len(t.History) - 1; i >= 0; i-- {
+		if t.History[i].Attempt > 0 {
+			return t.History[i].Attempt
+		}
+	}
+	return 0
+}
+
 // GetResultURL 根据任务类型获取结果URL
 func (t *Task) GetResultURL() string {
 	switch t.Type {
@@ -73,6 +160,14 @@ func (t *Task) GetResultURL() string {
 	}
 }
 
+// PrimaryOutput 获取任务的主要输出：文本结果，或第一张图像、视频的地址
+func (t *Task) PrimaryOutput() string {
+	if t.Type == TaskTypeText {
+		return t.TextResult
+	}
+	return t.GetResultURL()
+}
+
 // SetResultURL 根据任务类型设置结果URL
 func (t *Task) SetResultURL(url string) {
 	switch t.Type {
@@ -82,3 +177,85 @@ func (t *Task) SetResultURL(url string) {
 		t.VideoURL = url
 	}
 }
+
+// ResultData 构建任务结果数据，查询接口与回调通知共用
+func (t *Task) ResultData() map[string]interface{} {
+	data := map[string]interface{}{
+		"task_id":  t.ID,
+		"type":     t.Type,
+		"status":   t.Status,
+		"progress": t.Progress,
+		"history":  t.History,
+		"created":  t.Created,
+		"updated":  t.Updated,
+	}
+	if t.RunAt != nil {
+		data["run_at"] = t.RunAt
+	}
+	if t.ScheduleID != "" {
+		data["schedule_id"] = t.ScheduleID
+	}
+	if t.BatchID != "" {
+		data["batch_id"] = t.BatchID
+	}
+	if t.PipelineID != "" {
+		data["pipeline_id"] = t.PipelineID
+		data["pipeline_step"] = t.PipelineStep
+	}
+	if t.ServedProvider != "" {
+		data["served_provider"] = t.ServedProvider
+		data["served_model"] = t.ServedModel
+	}
+
+	// 根据任务类型添加特定字段
+	switch t.Type {
+	case TaskTypeImage:
+		if t.ImageURL != "" {
+			data["image_url"] = t.ImageURL
+		}
+		if len(t.ImageURLs) > 0 {
+			data["image_urls"] = t.ImageURLs
+		}
+		data["n"] = t.N
+		data["aspect_ratio"] = t.AspectRatio
+		if t.SourceImageURL != "" {
+			data["source_image_url"] = t.SourceImageURL
+			data["strength"] = t.Strength
+			data["seed"] = t.Seed
+		}
+	case TaskTypeVideo:
+		if t.VideoURL != "" {
+			data["video_url"] = t.VideoURL
+		}
+		data["seed"] = t.Seed
+		data["aspect_ratio"] = t.AspectRatio
+	case TaskTypeText:
+		if t.TextResult != "" {
+			data["text_result"] = t.TextResult
+		}
+		data["max_tokens"] = t.MaxTokens
+		data["temperature"] = t.Temperature
+	}
+
+	if len(t.Media) > 0 {
+		data["media"] = t.Media
+	}
+
+	if t.Status == config.TaskStatusFailed {
+		data["error"] = t.Error
+		data["error_code"] = t.ErrorCode
+	}
+
+	return data
+}
+
+// ProviderData 构建服务商侧任务信息，仅供管理员查询
+func (t *Task) ProviderData() map[string]interface{} {
+	return map[string]interface{}{
+		"provider_task_id": t.ProviderTaskID,
+		"req_key":          t.ReqKey,
+		"submitted_at":     t.ProviderSubmittedAt,
+		"completed_at":     t.ProviderCompletedAt,
+		"raw_response":     t.ProviderResponse,
+	}
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// UsageItem 按任务类型和模型汇总的积分消耗
+type UsageItem struct {
+	Type    string `json:"type" bson:"type"`
+	Model   string `json:"model" bson:"model"`
+	Tasks   int64  `json:"tasks" bson:"tasks"`     // 计费任务数（不含已退还的任务）
+	Credits int64  `json:"credits" bson:"credits"` // 消耗积分
+}
+
+// UsageSummary 用户用量汇总
+type UsageSummary struct {
+	UserID    string       `json:"user_id"`
+	Period    string       `json:"period"`          // day, month, all
+	Since     *time.Time   `json:"since,omitempty"` // 统计起始时间，all时为空
+	Consumed  int64        `json:"consumed"`        // 统计周期内消耗积分
+	Remaining int64        `json:"remaining"`       // 当前剩余积分
+	Items     []*UsageItem `json:"items"`
+}
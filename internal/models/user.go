@@ -9,6 +9,8 @@ type User struct {
 	ID        string    `json:"id" bson:"_id,omitempty"`
 	Email     string    `json:"email" bson:"email"`
 	Name      string    `json:"name" bson:"name"`
+	Credits   int64     `json:"credits" bson:"credits"`     // 剩余积分
+	Tier      string    `json:"tier" bson:"tier,omitempty"` // 用户等级，决定任务进入的队列，为空时按免费用户处理
 	CreatedAt time.Time `json:"created_at" bson:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
 }
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// WebhookDelivery 任务回调投递记录，每次投递尝试一条
+type WebhookDelivery struct {
+	ID         string    `json:"id" bson:"_id,omitempty"`
+	TaskID     string    `json:"task_id" bson:"task_id"`
+	Event      string    `json:"event" bson:"event"` // task.completed, task.failed
+	URL        string    `json:"url" bson:"url"`
+	Attempt    int       `json:"attempt" bson:"attempt"` // 第几次尝试，从1开始
+	Success    bool      `json:"success" bson:"success"`
+	StatusCode int       `json:"status_code,omitempty" bson:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty" bson:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms" bson:"duration_ms"`
+	Created    time.Time `json:"created" bson:"created"`
+}
+
+// 回调事件常量
+const (
+	WebhookEventTaskCompleted = "task.completed"
+	WebhookEventTaskFailed    = "task.failed"
+)
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"volcengine-go-server/config"
+	"volcengine-go-server/internal/models"
 	"volcengine-go-server/internal/service"
 	"volcengine-go-server/pkg/logger"
 )
@@ -26,7 +27,47 @@ func NewOpenAIProvider(openaiService *service.OpenAIService, taskService *servic
 
 // GetProviderName 获取分发器名称
 func (p *OpenAIProvider) GetProviderName() string {
-	return "openai"
+	return service.OpenAIProviderName
+}
+
+// 请求参数限制
+const (
+	openAIImageMaxPromptLength = 4000  // DALL-E 3 prompt最大字符数
+	openAITextMaxTokens        = 16384 // GPT-4o mini单次最大输出token数
+)
+
+// OpenAICapabilities OpenAI能力描述，API服务器不创建分发器，直接使用该描述构建模型目录
+func OpenAICapabilities() models.ProviderCapability {
+	return models.ProviderCapability{
+		Provider: service.OpenAIProviderName,
+		Models: []models.ModelCapability{
+			{
+				Model:    config.OpenAIImageModel,
+				TaskType: models.TaskTypeImage,
+				Name:     "DALL-E 3",
+				Params: []models.ParamRule{
+					{Field: "prompt", Type: models.ParamTypeString, Required: true, MaxLength: openAIImageMaxPromptLength},
+					{Field: "aspect_ratio", Type: models.ParamTypeString, Default: "1:1", Enum: []string{"1:1", "16:9", "9:16", "4:3", "3:4", "3:2", "2:3", "21:9"}},
+					{Field: "n", Type: models.ParamTypeInteger, Default: config.DefaultImageN, Range: &models.ParamRange{Min: 1, Max: config.MaxImageN}},
+				},
+			},
+			{
+				Model:    config.OpenAITextModel,
+				TaskType: models.TaskTypeText,
+				Name:     "GPT-4o mini",
+				Params: []models.ParamRule{
+					{Field: "prompt", Type: models.ParamTypeString, Required: true},
+					{Field: "max_tokens", Type: models.ParamTypeInteger, Default: config.DefaultTextMaxTokens, Range: &models.ParamRange{Min: 1, Max: openAITextMaxTokens}},
+					{Field: "temperature", Type: models.ParamTypeNumber, Default: config.DefaultTextTemperature, Range: &models.ParamRange{Min: 0, Max: 2}},
+				},
+			},
+		},
+	}
+}
+
+// GetCapabilities 获取OpenAI能力描述
+func (p *OpenAIProvider) GetCapabilities() models.ProviderCapability {
+	return OpenAICapabilities()
 }
 
 // DispatchImageTask 分发图像生成任务
@@ -38,10 +79,10 @@ func (p *OpenAIProvider) DispatchImageTask(ctx context.Context, taskID string, m
 	switch model {
 	case config.OpenAIImageModel: // dall-e-3
 		log.Infof("分发到DALL-E图像生成服务: %s", taskID)
-		return p.openaiService.GenerateImageByDALLE(ctx, taskID, input)
+		return p.openaiService.GenerateImageByDALLE(ctx, taskID, model, input)
 
 	default:
-		return fmt.Errorf("不支持的OpenAI图像生成模型: %s", model)
+		return unsupportedModelError("不支持的OpenAI图像生成模型: %s", model)
 	}
 }
 
@@ -52,23 +93,20 @@ func (p *OpenAIProvider) DispatchTextTask(ctx context.Context, taskID string, mo
 
 	// 根据模型选择不同的处理方法
 	switch model {
-	case config.OpenAITextModel: // gpt-4
-		return p.openaiService.GenerateTextByGPT(ctx, taskID, input)
+	case config.OpenAITextModel:
+		return p.openaiService.GenerateTextByGPT(ctx, taskID, model, input)
 	default:
-		return fmt.Errorf("不支持的OpenAI文本生成模型: %s", model)
+		return unsupportedModelError("不支持的OpenAI文本生成模型: %s", model)
 	}
 }
 
-// DispatchVideoTask 分发视频生成任务
+// DispatchVideoTask 分发视频生成任务，OpenAI兼容接口没有视频生成能力
 func (p *OpenAIProvider) DispatchVideoTask(ctx context.Context, taskID string, model string, input map[string]interface{}) error {
-	log := logger.GetLogger()
-	log.Infof("OpenAI视频任务分发: taskID=%s, model=%s", taskID, model)
+	logger.GetLogger().Infof("OpenAI视频任务分发: taskID=%s, model=%s", taskID, model)
+	return unsupportedModelError("不支持的OpenAI视频生成模型: %s", model)
+}
 
-	// 根据模型选择不同的处理方法
-	switch model {
-	case config.OpenAIVideoModel: // sora
-		return p.openaiService.GenerateVideoBySora(ctx, taskID, input)
-	default:
-		return fmt.Errorf("不支持的OpenAI视频生成模型: %s", model)
-	}
+// unsupportedModelError 不支持的模型属于参数错误，重试不会成功
+func unsupportedModelError(format string, model string) error {
+	return service.NewProviderError(config.TaskErrorInvalidInput, fmt.Sprintf(format, model), nil)
 }
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"volcengine-go-server/internal/models"
+)
+
+// APIKeyRepositoryImpl API密钥仓储实现
+type APIKeyRepositoryImpl struct {
+	database   *mongo.Database
+	collection *mongo.Collection
+}
+
+// NewAPIKeyRepository 创建API密钥仓储
+func NewAPIKeyRepository(database *mongo.Database) APIKeyRepository {
+	return &APIKeyRepositoryImpl{
+		database:   database,
+		collection: database.Collection("api_keys"),
+	}
+}
+
+// CreateAPIKey 创建API密钥
+func (r *APIKeyRepositoryImpl) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	_, err := r.collection.InsertOne(ctx, key)
+	return err
+}
+
+// GetAPIKeyByHash 根据密钥哈希获取未吊销的API密钥
+func (r *APIKeyRepositoryImpl) GetAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.collection.FindOne(ctx, bson.M{"key_hash": keyHash, "revoked": false}).Decode(&key)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// GetAPIKeysByUserID 获取用户的全部API密钥，按创建时间倒序
+func (r *APIKeyRepositoryImpl) GetAPIKeysByUserID(ctx context.Context, userID string) ([]*models.APIKey, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	keys := make([]*models.APIKey, 0)
+	if err = cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey 吊销用户的API密钥，返回是否有密钥被吊销
+func (r *APIKeyRepositoryImpl) RevokeAPIKey(ctx context.Context, id, userID string) (bool, error) {
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"revoked":    true,
+			"revoked_at": now,
+		},
+	}
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "user_id": userID, "revoked": false}, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// UpdateLastUsed 更新API密钥最近使用时间
+func (r *APIKeyRepositoryImpl) UpdateLastUsed(ctx context.Context, id string, usedAt time.Time) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"last_used_at": usedAt}})
+	return err
+}
+
+// CreateAPIKeyIndexes 创建API密钥索引
+func (r *APIKeyRepositoryImpl) CreateAPIKeyIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "key_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "created_at", Value: -1},
+			},
+		},
+	}
+
+	_, err := r.collection.Indexes().CreateMany(ctx, indexes)
+	return err
+}
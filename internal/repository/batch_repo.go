@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"volcengine-go-server/internal/models"
+)
+
+// BatchRepositoryImpl 批量任务仓储实现
+type BatchRepositoryImpl struct {
+	database   *mongo.Database
+	collection *mongo.Collection
+}
+
+// NewBatchRepository 创建批量任务仓储
+func NewBatchRepository(database *mongo.Database) BatchRepository {
+	return &BatchRepositoryImpl{
+		database:   database,
+		collection: database.Collection("batches"),
+	}
+}
+
+// CreateBatch 创建批量任务
+func (r *BatchRepositoryImpl) CreateBatch(ctx context.Context, batch *models.Batch) error {
+	_, err := r.collection.InsertOne(ctx, batch)
+	return err
+}
+
+// GetBatchByID 根据ID获取批量任务
+func (r *BatchRepositoryImpl) GetBatchByID(ctx context.Context, id string) (*models.Batch, error) {
+	var batch models.Batch
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&batch)
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// DeleteBatch 删除批量任务
+func (r *BatchRepositoryImpl) DeleteBatch(ctx context.Context, id string) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+// CreateBatchIndexes 创建批量任务索引
+func (r *BatchRepositoryImpl) CreateBatchIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "created_at", Value: -1},
+			},
+		},
+	}
+
+	_, err := r.collection.Indexes().CreateMany(ctx, indexes)
+	return err
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"volcengine-go-server/config"
+	"volcengine-go-server/internal/models"
+)
+
+// IdempotencyRepositoryImpl 幂等记录仓储实现
+type IdempotencyRepositoryImpl struct {
+	database   *mongo.Database
+	collection *mongo.Collection
+}
+
+// NewIdempotencyRepository 创建幂等记录仓储
+func NewIdempotencyRepository(database *mongo.Database) IdempotencyRepository {
+	return &IdempotencyRepositoryImpl{
+		database:   database,
+		collection: database.Collection("idempotency_keys"),
+	}
+}
+
+// CreateRecord 插入幂等记录，同一用户的key已存在时返回false
+func (r *IdempotencyRepositoryImpl) CreateRecord(ctx context.Context, record *models.IdempotencyRecord) (bool, error) {
+	if _, err := r.collection.InsertOne(ctx, record); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// GetRecord 获取幂等记录
+func (r *IdempotencyRepositoryImpl) GetRecord(ctx context.Context, userID, key string) (*models.IdempotencyRecord, error) {
+	var record models.IdempotencyRecord
+	err := r.collection.FindOne(ctx, bson.M{"user_id": userID, "key": key}).Decode(&record)
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// CompleteRecord 保存首次请求的响应
+func (r *IdempotencyRepositoryImpl) CompleteRecord(ctx context.Context, id, taskID string, statusCode int, response []byte) error {
+	update := bson.M{
+		"$set": bson.M{
+			"status":      models.IdempotencyStatusCompleted,
+			"task_id":     taskID,
+			"status_code": statusCode,
+			"response":    response,
+			"updated":     time.Now(),
+		},
+	}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+// DeleteRecord 删除幂等记录
+func (r *IdempotencyRepositoryImpl) DeleteRecord(ctx context.Context, id string) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+// DeleteStaleRecord 删除超时未完成的记录，用于接管异常中断的请求
+func (r *IdempotencyRepositoryImpl) DeleteStaleRecord(ctx context.Context, id string, before time.Time) (bool, error) {
+	filter := bson.M{
+		"_id":     id,
+		"status":  models.IdempotencyStatusProcessing,
+		"created": bson.M{"$lt": before},
+	}
+	result, err := r.collection.DeleteOne(ctx, filter)
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
+
+// CreateIdempotencyIndexes 创建幂等记录索引
+func (r *IdempotencyRepositoryImpl) CreateIdempotencyIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "key", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			// 过期记录由MongoDB自动清理
+			Keys:    bson.D{{Key: "created", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(config.IdempotencyKeyTTL.Seconds())),
+		},
+	}
+
+	_, err := r.collection.Indexes().CreateMany(ctx, indexes)
+	return err
+}
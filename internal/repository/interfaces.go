@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
 
@@ -15,26 +16,107 @@ type UserRepository interface {
 	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
 	UpdateUser(ctx context.Context, user *models.User) error
 	DeleteUser(ctx context.Context, id string) error
+	DeductCredits(ctx context.Context, id string, amount int64) (bool, error)
+	AddCredits(ctx context.Context, id string, amount int64) error
 	CreateUserIndexes(ctx context.Context) error
 }
 
 // TaskRepository 任务数据访问接口
 type TaskRepository interface {
 	CreateTask(ctx context.Context, task *models.Task) error
+	CreateTasks(ctx context.Context, tasks []*models.Task) error
 	GetTaskByID(ctx context.Context, id string) (*models.Task, error)
 	GetTasksByUserID(ctx context.Context, userID string, taskType string, limit, offset int) ([]*models.Task, error)
-	UpdateTaskStatus(ctx context.Context, id, status string) error
-	UpdateTaskResult(ctx context.Context, taskID string, task *models.Task, resultURL string) error
-	UpdateTaskError(ctx context.Context, id, errorMsg string) error
+	GetTasksByBatchID(ctx context.Context, batchID string) ([]*models.Task, error)
+	DeleteTasksByBatchID(ctx context.Context, batchID string) error
+	TransitionTaskStatus(ctx context.Context, id, from string, change *models.TaskStatusChange) (bool, error)
+	UpdateTaskProgress(ctx context.Context, id string, progress int) error
+	RescheduleTask(ctx context.Context, id string, runAt time.Time) (bool, error)
+	UpdateTaskResult(ctx context.Context, task *models.Task, resultURL string, media []models.MediaAsset, change *models.TaskStatusChange) (bool, error)
+	UpdateTaskImageResults(ctx context.Context, taskID, from string, imageURLs []string, media []models.MediaAsset, change *models.TaskStatusChange) (bool, error)
+	UpdateTaskError(ctx context.Context, id, from, errorCode, errorMsg string, change *models.TaskStatusChange) (bool, error)
+	UpdateProviderSubmission(ctx context.Context, id, providerTaskID, reqKey, rawResponse string, submittedAt time.Time) error
+	UpdateProviderResponse(ctx context.Context, id, rawResponse string, completedAt *time.Time) error
+	UpdateServedBy(ctx context.Context, id, provider, model string) error
+	CancelTask(ctx context.Context, id, from string, change *models.TaskStatusChange) (bool, error)
+	SetCreditRefunded(ctx context.Context, id string, refunded bool) (bool, error)
+	SetCreditUnpaid(ctx context.Context, id string) error
+	GetUserUsage(ctx context.Context, userID string, since time.Time) ([]*models.UsageItem, error)
 	DeleteTask(ctx context.Context, id string) error
 	CreateTaskIndexes(ctx context.Context) error
 }
 
+// WebhookDeliveryRepository 回调投递记录数据访问接口
+type WebhookDeliveryRepository interface {
+	CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	GetDeliveriesByTaskID(ctx context.Context, taskID string) ([]*models.WebhookDelivery, error)
+	CreateWebhookDeliveryIndexes(ctx context.Context) error
+}
+
+// APIKeyRepository API密钥数据访问接口
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key *models.APIKey) error
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error)
+	GetAPIKeysByUserID(ctx context.Context, userID string) ([]*models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id, userID string) (bool, error)
+	UpdateLastUsed(ctx context.Context, id string, usedAt time.Time) error
+	CreateAPIKeyIndexes(ctx context.Context) error
+}
+
+// IdempotencyRepository 幂等记录数据访问接口
+type IdempotencyRepository interface {
+	CreateRecord(ctx context.Context, record *models.IdempotencyRecord) (bool, error)
+	GetRecord(ctx context.Context, userID, key string) (*models.IdempotencyRecord, error)
+	CompleteRecord(ctx context.Context, id, taskID string, statusCode int, response []byte) error
+	DeleteRecord(ctx context.Context, id string) error
+	DeleteStaleRecord(ctx context.Context, id string, before time.Time) (bool, error)
+	CreateIdempotencyIndexes(ctx context.Context) error
+}
+
+// ScheduleRepository 周期任务模板数据访问接口
+type ScheduleRepository interface {
+	CreateSchedule(ctx context.Context, schedule *models.Schedule) error
+	GetScheduleByID(ctx context.Context, id string) (*models.Schedule, error)
+	GetSchedulesByUserID(ctx context.Context, userID string) ([]*models.Schedule, error)
+	GetEnabledSchedules(ctx context.Context) ([]*models.Schedule, error)
+	CountSchedulesByUserID(ctx context.Context, userID string) (int64, error)
+	UpdateSchedule(ctx context.Context, schedule *models.Schedule) error
+	ClaimRun(ctx context.Context, id string, runAt, before time.Time) (bool, error)
+	UpdateLastResult(ctx context.Context, id, taskID, errorMsg string) error
+	DeleteSchedule(ctx context.Context, id, userID string) (bool, error)
+	CreateScheduleIndexes(ctx context.Context) error
+}
+
+// BatchRepository 批量任务数据访问接口
+type BatchRepository interface {
+	CreateBatch(ctx context.Context, batch *models.Batch) error
+	GetBatchByID(ctx context.Context, id string) (*models.Batch, error)
+	DeleteBatch(ctx context.Context, id string) error
+	CreateBatchIndexes(ctx context.Context) error
+}
+
+// PipelineRepository 流水线数据访问接口
+type PipelineRepository interface {
+	CreatePipeline(ctx context.Context, pipeline *models.Pipeline) error
+	GetPipelineByID(ctx context.Context, id string) (*models.Pipeline, error)
+	GetPipelinesByUserID(ctx context.Context, userID string, limit, offset int) ([]*models.Pipeline, error)
+	StartStep(ctx context.Context, id string, step int, taskID string) (bool, error)
+	CompleteStep(ctx context.Context, id string, step int, output string) (bool, error)
+	FinishPipeline(ctx context.Context, id, status, errorMsg string, step int, stepStatus string) (bool, error)
+	CreatePipelineIndexes(ctx context.Context) error
+}
+
 // Database 数据库接口 - 提供Repository实例的工厂
 type Database interface {
 	// 获取Repository实例
 	UserRepository() UserRepository
 	TaskRepository() TaskRepository
+	WebhookDeliveryRepository() WebhookDeliveryRepository
+	APIKeyRepository() APIKeyRepository
+	IdempotencyRepository() IdempotencyRepository
+	ScheduleRepository() ScheduleRepository
+	BatchRepository() BatchRepository
+	PipelineRepository() PipelineRepository
 
 	// 获取底层的mongo.Database实例
 	GetDatabase() *mongo.Database
@@ -13,8 +13,14 @@ type MongoDB struct {
 	database *mongo.Database
 
 	// Repository实例
-	userRepo UserRepository
-	taskRepo TaskRepository
+	userRepo     UserRepository
+	taskRepo     TaskRepository
+	webhookRepo  WebhookDeliveryRepository
+	apiKeyRepo   APIKeyRepository
+	idemRepo     IdempotencyRepository
+	scheduleRepo ScheduleRepository
+	batchRepo    BatchRepository
+	pipelineRepo PipelineRepository
 }
 
 func NewMongoDB(uri string) (Database, error) {
@@ -33,6 +39,12 @@ func NewMongoDB(uri string) (Database, error) {
 	// 创建各个repository实例
 	userRepo := NewUserRepository(database)
 	taskRepo := NewTaskRepository(database)
+	webhookRepo := NewWebhookDeliveryRepository(database)
+	apiKeyRepo := NewAPIKeyRepository(database)
+	idemRepo := NewIdempotencyRepository(database)
+	scheduleRepo := NewScheduleRepository(database)
+	batchRepo := NewBatchRepository(database)
+	pipelineRepo := NewPipelineRepository(database)
 
 	// 创建索引
 	if err := userRepo.CreateUserIndexes(context.Background()); err != nil {
@@ -41,12 +53,36 @@ func NewMongoDB(uri string) (Database, error) {
 	if err := taskRepo.CreateTaskIndexes(context.Background()); err != nil {
 		return nil, err
 	}
+	if err := webhookRepo.CreateWebhookDeliveryIndexes(context.Background()); err != nil {
+		return nil, err
+	}
+	if err := apiKeyRepo.CreateAPIKeyIndexes(context.Background()); err != nil {
+		return nil, err
+	}
+	if err := idemRepo.CreateIdempotencyIndexes(context.Background()); err != nil {
+		return nil, err
+	}
+	if err := scheduleRepo.CreateScheduleIndexes(context.Background()); err != nil {
+		return nil, err
+	}
+	if err := batchRepo.CreateBatchIndexes(context.Background()); err != nil {
+		return nil, err
+	}
+	if err := pipelineRepo.CreatePipelineIndexes(context.Background()); err != nil {
+		return nil, err
+	}
 
 	return &MongoDB{
-		client:   client,
-		database: database,
-		userRepo: userRepo,
-		taskRepo: taskRepo,
+		client:       client,
+		database:     database,
+		userRepo:     userRepo,
+		taskRepo:     taskRepo,
+		webhookRepo:  webhookRepo,
+		apiKeyRepo:   apiKeyRepo,
+		idemRepo:     idemRepo,
+		scheduleRepo: scheduleRepo,
+		batchRepo:    batchRepo,
+		pipelineRepo: pipelineRepo,
 	}, nil
 }
 
@@ -60,6 +96,36 @@ func (m *MongoDB) TaskRepository() TaskRepository {
 	return m.taskRepo
 }
 
+// WebhookDeliveryRepository 返回回调投递记录Repository实例
+func (m *MongoDB) WebhookDeliveryRepository() WebhookDeliveryRepository {
+	return m.webhookRepo
+}
+
+// APIKeyRepository 返回API密钥Repository实例
+func (m *MongoDB) APIKeyRepository() APIKeyRepository {
+	return m.apiKeyRepo
+}
+
+// IdempotencyRepository 返回幂等记录Repository实例
+func (m *MongoDB) IdempotencyRepository() IdempotencyRepository {
+	return m.idemRepo
+}
+
+// ScheduleRepository 返回周期任务模板Repository实例
+func (m *MongoDB) ScheduleRepository() ScheduleRepository {
+	return m.scheduleRepo
+}
+
+// BatchRepository 返回批量任务Repository实例
+func (m *MongoDB) BatchRepository() BatchRepository {
+	return m.batchRepo
+}
+
+// PipelineRepository 返回流水线Repository实例
+func (m *MongoDB) PipelineRepository() PipelineRepository {
+	return m.pipelineRepo
+}
+
 // Close 关闭数据库连接
 func (m *MongoDB) Close() error {
 	return m.client.Disconnect(context.Background())
@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"volcengine-go-server/config"
+	"volcengine-go-server/internal/models"
+)
+
+// PipelineRepositoryImpl 流水线仓储实现
+type PipelineRepositoryImpl struct {
+	database   *mongo.Database
+	collection *mongo.Collection
+}
+
+// NewPipelineRepository 创建流水线仓储
+func NewPipelineRepository(database *mongo.Database) PipelineRepository {
+	return &PipelineRepositoryImpl{
+		database:   database,
+		collection: database.Collection("pipelines"),
+	}
+}
+
+// CreatePipeline 创建流水线
+func (r *PipelineRepositoryImpl) CreatePipeline(ctx context.Context, pipeline *models.Pipeline) error {
+	_, err := r.collection.InsertOne(ctx, pipeline)
+	return err
+}
+
+// GetPipelineByID 根据ID获取流水线
+func (r *PipelineRepositoryImpl) GetPipelineByID(ctx context.Context, id string) (*models.Pipeline, error) {
+	var pipeline models.Pipeline
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&pipeline)
+	if err != nil {
+		return nil, err
+	}
+	return &pipeline, nil
+}
+
+// GetPipelinesByUserID 分页获取用户的流水线，按创建时间倒序
+func (r *PipelineRepositoryImpl) GetPipelinesByUserID(ctx context.Context, userID string, limit, offset int) ([]*models.Pipeline, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created", Value: -1}}).
+		SetLimit(int64(limit)).
+		SetSkip(int64(offset))
+
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	pipelines := make([]*models.Pipeline, 0)
+	if err = cursor.All(ctx, &pipelines); err != nil {
+		return nil, err
+	}
+
+	return pipelines, nil
+}
+
+// StartStep 开始执行步骤，仅当流水线未结束且该步骤尚未开始时成功，返回是否更新成功
+func (r *PipelineRepositoryImpl) StartStep(ctx context.Context, id string, step int, taskID string) (bool, error) {
+	prefix := fmt.Sprintf("steps.%d.", step)
+	now := time.Now()
+
+	filter := bson.M{
+		"_id":             id,
+		"status":          bson.M{"$in": []string{config.TaskStatusPending, config.TaskStatusProcessing}},
+		prefix + "status": config.TaskStatusPending,
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":              config.TaskStatusProcessing,
+			"current_step":        step,
+			prefix + "status":     config.TaskStatusProcessing,
+			prefix + "task_id":    taskID,
+			prefix + "started_at": now,
+			"updated":             now,
+		},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// CompleteStep 记录步骤输出并标记完成，仅当流水线执行中且该步骤执行中时成功
+func (r *PipelineRepositoryImpl) CompleteStep(ctx context.Context, id string, step int, output string) (bool, error) {
+	prefix := fmt.Sprintf("steps.%d.", step)
+	now := time.Now()
+
+	filter := bson.M{
+		"_id":             id,
+		"status":          config.TaskStatusProcessing,
+		prefix + "status": config.TaskStatusProcessing,
+	}
+	update := bson.M{
+		"$set": bson.M{
+			prefix + "status":      config.TaskStatusCompleted,
+			prefix + "output":      output,
+			prefix + "finished_at": now,
+			"updated":              now,
+		},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// FinishPipeline 结束流水线，仅当流水线未结束时成功，返回是否更新成功
+// step不小于0时同时将该步骤标记为stepStatus并记录错误信息
+func (r *PipelineRepositoryImpl) FinishPipeline(ctx context.Context, id, status, errorMsg string, step int, stepStatus string) (bool, error) {
+	now := time.Now()
+
+	filter := bson.M{
+		"_id":    id,
+		"status": bson.M{"$in": []string{config.TaskStatusPending, config.TaskStatusProcessing}},
+	}
+	set := bson.M{
+		"status":  status,
+		"error":   errorMsg,
+		"updated": now,
+	}
+	if step >= 0 {
+		prefix := fmt.Sprintf("steps.%d.", step)
+		set[prefix+"status"] = stepStatus
+		set[prefix+"error"] = errorMsg
+		set[prefix+"finished_at"] = now
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": set})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// CreatePipelineIndexes 创建流水线索引
+func (r *PipelineRepositoryImpl) CreatePipelineIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "created", Value: -1},
+			},
+		},
+	}
+
+	_, err := r.collection.Indexes().CreateMany(ctx, indexes)
+	return err
+}
@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"volcengine-go-server/internal/models"
+)
+
+// ScheduleRepositoryImpl 周期任务模板仓储实现
+type ScheduleRepositoryImpl struct {
+	database   *mongo.Database
+	collection *mongo.Collection
+}
+
+// NewScheduleRepository 创建周期任务模板仓储
+func NewScheduleRepository(database *mongo.Database) ScheduleRepository {
+	return &ScheduleRepositoryImpl{
+		database:   database,
+		collection: database.Collection("schedules"),
+	}
+}
+
+// CreateSchedule 创建周期任务模板
+func (r *ScheduleRepositoryImpl) CreateSchedule(ctx context.Context, schedule *models.Schedule) error {
+	_, err := r.collection.InsertOne(ctx, schedule)
+	return err
+}
+
+// GetScheduleByID 根据ID获取周期任务模板
+func (r *ScheduleRepositoryImpl) GetScheduleByID(ctx context.Context, id string) (*models.Schedule, error) {
+	var schedule models.Schedule
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&schedule)
+	if err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// GetSchedulesByUserID 获取用户的全部周期任务模板，按创建时间倒序
+func (r *ScheduleRepositoryImpl) GetSchedulesByUserID(ctx context.Context, userID string) ([]*models.Schedule, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	return r.find(ctx, bson.M{"user_id": userID}, opts)
+}
+
+// GetEnabledSchedules 获取所有启用的周期任务模板
+func (r *ScheduleRepositoryImpl) GetEnabledSchedules(ctx context.Context) ([]*models.Schedule, error) {
+	return r.find(ctx, bson.M{"enabled": true})
+}
+
+// CountSchedulesByUserID 统计用户的周期任务模板数量
+func (r *ScheduleRepositoryImpl) CountSchedulesByUserID(ctx context.Context, userID string) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{"user_id": userID})
+}
+
+// UpdateSchedule 更新周期任务模板的配置，不修改执行记录
+func (r *ScheduleRepositoryImpl) UpdateSchedule(ctx context.Context, schedule *models.Schedule) error {
+	update := bson.M{
+		"$set": bson.M{
+			"name":            schedule.Name,
+			"cron":            schedule.Cron,
+			"timezone":        schedule.Timezone,
+			"enabled":         schedule.Enabled,
+			"type":            schedule.Type,
+			"provider":        schedule.Provider,
+			"model":           schedule.Model,
+			"prompt":          schedule.Prompt,
+			"aspect_ratio":    schedule.AspectRatio,
+			"n":               schedule.N,
+			"callback_url":    schedule.CallbackURL,
+			"callback_secret": schedule.CallbackSecret,
+			"updated_at":      time.Now(),
+		},
+	}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": schedule.ID}, update)
+	return err
+}
+
+// ClaimRun 占用一次执行，仅当模板启用且上次执行早于before时成功，返回是否占用成功
+func (r *ScheduleRepositoryImpl) ClaimRun(ctx context.Context, id string, runAt, before time.Time) (bool, error) {
+	filter := bson.M{
+		"_id":     id,
+		"enabled": true,
+		"$or": []bson.M{
+			{"last_run_at": bson.M{"$exists": false}},
+			{"last_run_at": bson.M{"$lt": before}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"last_run_at": runAt}}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// UpdateLastResult 记录最近一次执行创建的任务或失败原因
+func (r *ScheduleRepositoryImpl) UpdateLastResult(ctx context.Context, id, taskID, errorMsg string) error {
+	update := bson.M{
+		"$set": bson.M{
+			"last_task_id": taskID,
+			"last_error":   errorMsg,
+		},
+	}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+// DeleteSchedule 删除用户的周期任务模板，返回是否有模板被删除
+func (r *ScheduleRepositoryImpl) DeleteSchedule(ctx context.Context, id, userID string) (bool, error) {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id, "user_id": userID})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
+
+// CreateScheduleIndexes 创建周期任务模板索引
+func (r *ScheduleRepositoryImpl) CreateScheduleIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "created_at", Value: -1},
+			},
+		},
+		{
+			Keys: bson.D{{Key: "enabled", Value: 1}},
+		},
+	}
+
+	_, err := r.collection.Indexes().CreateMany(ctx, indexes)
+	return err
+}
+
+// find 按条件查询周期任务模板
+func (r *ScheduleRepositoryImpl) find(ctx context.Context, filter bson.M, opts ...*options.FindOptions) ([]*models.Schedule, error) {
+	cursor, err := r.collection.Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	schedules := make([]*models.Schedule, 0)
+	if err = cursor.All(ctx, &schedules); err != nil {
+		return nil, err
+	}
+
+	return schedules, nil
+}
@@ -32,6 +32,16 @@ func (r *TaskRepositoryImpl) CreateTask(ctx context.Context, task *models.Task)
 	return err
 }
 
+// CreateTasks 批量创建任务，使用一次InsertMany写入
+func (r *TaskRepositoryImpl) CreateTasks(ctx context.Context, tasks []*models.Task) error {
+	docs := make([]interface{}, len(tasks))
+	for i, task := range tasks {
+		docs[i] = task
+	}
+	_, err := r.collection.InsertMany(ctx, docs)
+	return err
+}
+
 // GetTaskByID 根据ID获取任务
 func (r *TaskRepositoryImpl) GetTaskByID(ctx context.Context, id string) (*models.Task, error) {
 	var task models.Task
@@ -68,54 +78,234 @@ func (r *TaskRepositoryImpl) GetTasksByUserID(ctx context.Context, userID string
 	return tasks, nil
 }
 
-// UpdateTaskStatus 更新任务状态
-func (r *TaskRepositoryImpl) UpdateTaskStatus(ctx context.Context, id, status string) error {
-	update := bson.M{
-		"$set": bson.M{
-			"status":  status,
-			"updated": time.Now(),
-		},
+// GetTasksByBatchID 获取批量任务的全部子任务
+func (r *TaskRepositoryImpl) GetTasksByBatchID(ctx context.Context, batchID string) ([]*models.Task, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"batch_id": batchID})
+	if err != nil {
+		return nil, err
 	}
-	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	defer cursor.Close(ctx)
+
+	tasks := make([]*models.Task, 0)
+	if err = cursor.All(ctx, &tasks); err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+// DeleteTasksByBatchID 删除批量任务的全部子任务
+func (r *TaskRepositoryImpl) DeleteTasksByBatchID(ctx context.Context, batchID string) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"batch_id": batchID})
 	return err
 }
 
-// UpdateTaskResult 更新任务结果
-func (r *TaskRepositoryImpl) UpdateTaskResult(ctx context.Context, taskID string, task *models.Task, resultURL string) error {
+// TransitionTaskStatus 仅当任务处于from状态时更新为change中的状态并追加状态历史，返回是否发生了更新
+func (r *TaskRepositoryImpl) TransitionTaskStatus(ctx context.Context, id, from string, change *models.TaskStatusChange) (bool, error) {
+	return r.updateIfStatus(ctx, id, from, transitionUpdate(change, bson.M{}))
+}
+
+// UpdateTaskProgress 更新处理中任务的进度估算，进度只增不减
+func (r *TaskRepositoryImpl) UpdateTaskProgress(ctx context.Context, id string, progress int) error {
+	filter := bson.M{"_id": id, "status": config.TaskStatusProcessing}
+	update := bson.M{"$max": bson.M{"progress": progress}}
+	_, err := r.collection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// RescheduleTask 修改定时任务的计划执行时间，只有尚未执行的定时任务会被更新
+func (r *TaskRepositoryImpl) RescheduleTask(ctx context.Context, id string, runAt time.Time) (bool, error) {
+	filter := bson.M{"_id": id, "status": config.TaskStatusScheduled}
 	update := bson.M{
 		"$set": bson.M{
-			"status":  config.TaskStatusCompleted,
+			"run_at":  runAt,
 			"updated": time.Now(),
 		},
 	}
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// UpdateTaskResult 更新任务结果，仅当任务仍处于task.Status状态时更新
+func (r *TaskRepositoryImpl) UpdateTaskResult(ctx context.Context, task *models.Task, resultURL string, media []models.MediaAsset, change *models.TaskStatusChange) (bool, error) {
+	set := bson.M{"progress": 100}
 
 	// 根据任务类型设置相应的结果字段
 	switch task.Type {
 	case models.TaskTypeImage:
-		update["$set"].(bson.M)["image_url"] = resultURL
+		set["image_url"] = resultURL
 	case models.TaskTypeVideo:
-		update["$set"].(bson.M)["video_url"] = resultURL
+		set["video_url"] = resultURL
 	case models.TaskTypeText:
-		update["$set"].(bson.M)["text_result"] = resultURL
+		set["text_result"] = resultURL
+	}
+	if len(media) > 0 {
+		set["media"] = media
+	}
+
+	return r.updateIfStatus(ctx, task.ID, task.Status, transitionUpdate(change, set))
+}
+
+// UpdateTaskImageResults 更新图像任务的全部结果，仅当任务仍处于from状态时更新
+func (r *TaskRepositoryImpl) UpdateTaskImageResults(ctx context.Context, taskID, from string, imageURLs []string, media []models.MediaAsset, change *models.TaskStatusChange) (bool, error) {
+	set := bson.M{
+		"image_urls": imageURLs,
+		"progress":   100,
+	}
+	// image_url保留第一张图片，兼容只读取单张结果的客户端
+	if len(imageURLs) > 0 {
+		set["image_url"] = imageURLs[0]
+	}
+	if len(media) > 0 {
+		set["media"] = media
+	}
+
+	return r.updateIfStatus(ctx, taskID, from, transitionUpdate(change, set))
+}
+
+// UpdateTaskError 更新任务错误码和错误信息，仅当任务仍处于from状态时更新
+func (r *TaskRepositoryImpl) UpdateTaskError(ctx context.Context, id, from, errorCode, errorMsg string, change *models.TaskStatusChange) (bool, error) {
+	set := bson.M{
+		"error":      errorMsg,
+		"error_code": errorCode,
+	}
+	return r.updateIfStatus(ctx, id, from, transitionUpdate(change, set))
+}
+
+// UpdateProviderSubmission 记录任务提交到服务商后的任务ID、接口标识和原始响应
+// 已取消的任务同样记录，便于与服务商核对
+func (r *TaskRepositoryImpl) UpdateProviderSubmission(ctx context.Context, id, providerTaskID, reqKey, rawResponse string, submittedAt time.Time) error {
+	update := bson.M{
+		"$set": bson.M{
+			"provider_task_id":      providerTaskID,
+			"req_key":               reqKey,
+			"provider_submitted_at": submittedAt,
+			"provider_response":     rawResponse,
+			"updated":               time.Now(),
+		},
 	}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
 
-	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": taskID}, update)
+// UpdateProviderResponse 记录服务商最近一次原始响应，completedAt不为空时同时记录完成时间
+func (r *TaskRepositoryImpl) UpdateProviderResponse(ctx context.Context, id, rawResponse string, completedAt *time.Time) error {
+	set := bson.M{"provider_response": rawResponse}
+	if completedAt != nil {
+		set["provider_completed_at"] = *completedAt
+	}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set})
 	return err
 }
 
-// UpdateTaskError 更新任务错误
-func (r *TaskRepositoryImpl) UpdateTaskError(ctx context.Context, id, errorMsg string) error {
+// UpdateServedBy 记录执行任务的服务商和模型
+func (r *TaskRepositoryImpl) UpdateServedBy(ctx context.Context, id, provider, model string) error {
 	update := bson.M{
 		"$set": bson.M{
-			"status":  config.TaskStatusFailed,
-			"error":   errorMsg,
-			"updated": time.Now(),
+			"served_provider": provider,
+			"served_model":    model,
+			"updated":         time.Now(),
 		},
 	}
 	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
 	return err
 }
 
+// CancelTask 取消任务，仅当任务仍处于from状态时更新
+func (r *TaskRepositoryImpl) CancelTask(ctx context.Context, id, from string, change *models.TaskStatusChange) (bool, error) {
+	return r.updateIfStatus(ctx, id, from, transitionUpdate(change, bson.M{}))
+}
+
+// SetCreditRefunded 切换任务的积分退还标记，返回是否发生了切换
+// 只有状态确实变化时调用方才调整用户积分，保证重复调用不会重复退还
+func (r *TaskRepositoryImpl) SetCreditRefunded(ctx context.Context, id string, refunded bool) (bool, error) {
+	filter := bson.M{
+		"_id":             id,
+		"cost":            bson.M{"$gt": 0},
+		"credit_refunded": bson.M{"$ne": refunded},
+	}
+	update := bson.M{"$set": bson.M{"credit_refunded": refunded}}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// SetCreditUnpaid 标记任务积分未能重新扣除
+func (r *TaskRepositoryImpl) SetCreditUnpaid(ctx context.Context, id string) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"credit_unpaid": true}})
+	return err
+}
+
+// GetUserUsage 按任务类型和模型汇总用户的积分消耗，since为零值时统计全部
+func (r *TaskRepositoryImpl) GetUserUsage(ctx context.Context, userID string, since time.Time) ([]*models.UsageItem, error) {
+	match := bson.M{
+		"user_id":         userID,
+		"cost":            bson.M{"$gt": 0},
+		"credit_refunded": bson.M{"$ne": true},
+		"credit_unpaid":   bson.M{"$ne": true},
+	}
+	if !since.IsZero() {
+		match["created"] = bson.M{"$gte": since}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id":     bson.M{"type": "$type", "model": "$model"},
+			"tasks":   bson.M{"$sum": 1},
+			"credits": bson.M{"$sum": "$cost"},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":     0,
+			"type":    "$_id.type",
+			"model":   "$_id.model",
+			"tasks":   1,
+			"credits": 1,
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "type", Value: 1}, {Key: "model", Value: 1}}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	items := make([]*models.UsageItem, 0)
+	if err = cursor.All(ctx, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// updateIfStatus 仅当任务处于from状态时执行更新，以比较交换的方式防止并发回写覆盖其他状态
+func (r *TaskRepositoryImpl) updateIfStatus(ctx context.Context, id, from string, update bson.M) (bool, error) {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "status": from}, update)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// transitionUpdate 构建状态转换的更新语句，追加状态历史并只保留最近的记录
+func transitionUpdate(change *models.TaskStatusChange, set bson.M) bson.M {
+	set["status"] = change.Status
+	set["updated"] = change.At
+	return bson.M{
+		"$set": set,
+		"$push": bson.M{"history": bson.M{
+			"$each":  []*models.TaskStatusChange{change},
+			"$slice": -config.TaskHistoryMaxEntries,
+		}},
+	}
+}
+
 // DeleteTask 删除任务
 func (r *TaskRepositoryImpl) DeleteTask(ctx context.Context, id string) error {
 	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
@@ -140,6 +330,16 @@ func (r *TaskRepositoryImpl) CreateTaskIndexes(ctx context.Context) error {
 				{Key: "type", Value: 1},
 			},
 		},
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "created", Value: -1},
+			},
+		},
+		{
+			Keys:    bson.D{{Key: "batch_id", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
 	}
 
 	_, err := r.collection.Indexes().CreateMany(ctx, indexes)
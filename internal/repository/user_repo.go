@@ -79,6 +79,7 @@ func (r *UserRepositoryImpl) UpdateUser(ctx context.Context, user *models.User)
 		"$set": bson.M{
 			"email":      user.Email,
 			"name":       user.Name,
+			"tier":       user.Tier,
 			"updated_at": user.UpdatedAt,
 		},
 	}
@@ -100,6 +101,51 @@ func (r *UserRepositoryImpl) DeleteUser(ctx context.Context, id string) error {
 	return err
 }
 
+// DeductCredits 扣除积分，余额不足时不扣除并返回false
+func (r *UserRepositoryImpl) DeductCredits(ctx context.Context, id string, amount int64) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, err
+	}
+
+	// 余额判断与扣除在同一次更新中完成，并发请求不会超扣
+	filter := bson.M{"_id": objectID, "credits": bson.M{"$gte": amount}}
+	update := bson.M{
+		"$inc": bson.M{"credits": -amount},
+		"$set": bson.M{"updated_at": time.Now()},
+	}
+
+	collection := r.database.Collection("users")
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// AddCredits 增加积分（充值或退还），amount为负数时直接扣减
+func (r *UserRepositoryImpl) AddCredits(ctx context.Context, id string, amount int64) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{
+		"$inc": bson.M{"credits": amount},
+		"$set": bson.M{"updated_at": time.Now()},
+	}
+
+	collection := r.database.Collection("users")
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
 // CreateUserIndexes 创建用户相关的索引
 func (r *UserRepositoryImpl) CreateUserIndexes(ctx context.Context) error {
 	userCollection := r.database.Collection("users")
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"volcengine-go-server/internal/models"
+)
+
+// WebhookDeliveryRepositoryImpl 回调投递记录仓储实现
+type WebhookDeliveryRepositoryImpl struct {
+	database   *mongo.Database
+	collection *mongo.Collection
+}
+
+// NewWebhookDeliveryRepository 创建回调投递记录仓储
+func NewWebhookDeliveryRepository(database *mongo.Database) WebhookDeliveryRepository {
+	return &WebhookDeliveryRepositoryImpl{
+		database:   database,
+		collection: database.Collection("webhook_deliveries"),
+	}
+}
+
+// CreateDelivery 记录一次投递尝试
+func (r *WebhookDeliveryRepositoryImpl) CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	_, err := r.collection.InsertOne(ctx, delivery)
+	return err
+}
+
+// GetDeliveriesByTaskID 获取任务的全部投递记录，按时间正序
+func (r *WebhookDeliveryRepositoryImpl) GetDeliveriesByTaskID(ctx context.Context, taskID string) ([]*models.WebhookDelivery, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, bson.M{"task_id": taskID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	deliveries := make([]*models.WebhookDelivery, 0)
+	if err = cursor.All(ctx, &deliveries); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// CreateWebhookDeliveryIndexes 创建回调投递记录索引
+func (r *WebhookDeliveryRepositoryImpl) CreateWebhookDeliveryIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "task_id", Value: 1},
+				{Key: "created", Value: 1},
+			},
+		},
+	}
+
+	_, err := r.collection.Indexes().CreateMany(ctx, indexes)
+	return err
+}
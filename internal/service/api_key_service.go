@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"volcengine-go-server/config"
+	"volcengine-go-server/internal/models"
+	"volcengine-go-server/internal/repository"
+	"volcengine-go-server/pkg/logger"
+)
+
+// ErrInvalidAPIKey API密钥不存在、已吊销或所属用户已删除
+var ErrInvalidAPIKey = errors.New("无效的API密钥")
+
+// APIKeyService API密钥服务
+type APIKeyService struct {
+	apiKeyRepo repository.APIKeyRepository
+	userRepo   repository.UserRepository
+}
+
+// NewAPIKeyService 创建API密钥服务
+func NewAPIKeyService(db repository.Database) *APIKeyService {
+	return &APIKeyService{
+		apiKeyRepo: db.APIKeyRepository(),
+		userRepo:   db.UserRepository(),
+	}
+}
+
+// CreateAPIKey 为用户创建API密钥，明文密钥只在创建时返回一次
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, userID, name string) (*models.APIKey, string, error) {
+	if _, err := s.userRepo.GetUserByID(ctx, userID); err != nil {
+		return nil, "", err
+	}
+
+	random := make([]byte, config.APIKeyRandomBytes)
+	if _, err := rand.Read(random); err != nil {
+		return nil, "", err
+	}
+	rawKey := config.APIKeyPrefix + hex.EncodeToString(random)
+
+	key := &models.APIKey{
+		ID:        primitive.NewObjectID().Hex(),
+		UserID:    userID,
+		Name:      name,
+		Prefix:    rawKey[:config.APIKeyDisplayPrefixLen],
+		KeyHash:   HashAPIKey(rawKey),
+		CreatedAt: time.Now(),
+	}
+	if err := s.apiKeyRepo.CreateAPIKey(ctx, key); err != nil {
+		return nil, "", err
+	}
+
+	return key, rawKey, nil
+}
+
+// Authenticate 校验API密钥并返回所属用户
+func (s *APIKeyService) Authenticate(ctx context.Context, rawKey string) (*models.User, *models.APIKey, error) {
+	key, err := s.apiKeyRepo.GetAPIKeyByHash(ctx, HashAPIKey(rawKey))
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil, ErrInvalidAPIKey
+		}
+		return nil, nil, err
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, key.UserID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil, ErrInvalidAPIKey
+		}
+		return nil, nil, err
+	}
+
+	// 最近使用时间只做粗粒度记录，失败不影响认证
+	now := time.Now()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > config.APIKeyLastUsedInterval {
+		if err := s.apiKeyRepo.UpdateLastUsed(ctx, key.ID, now); err != nil {
+			logger.GetLogger().Warnf("更新API密钥使用时间失败: %s, %v", key.ID, err)
+		}
+	}
+
+	return user, key, nil
+}
+
+// ListAPIKeys 获取用户的API密钥列表
+func (s *APIKeyService) ListAPIKeys(ctx context.Context, userID string) ([]*models.APIKey, error) {
+	return s.apiKeyRepo.GetAPIKeysByUserID(ctx, userID)
+}
+
+// RevokeAPIKey 吊销用户的API密钥，返回false表示密钥不存在或已吊销
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, userID, keyID string) (bool, error) {
+	return s.apiKeyRepo.RevokeAPIKey(ctx, keyID, userID)
+}
+
+// HashAPIKey 计算API密钥的存储哈希
+// 密钥本身是高熵随机串，使用SHA-256即可，无需慢哈希
+func HashAPIKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"volcengine-go-server/internal/models"
+	"volcengine-go-server/internal/repository"
+	"volcengine-go-server/pkg/logger"
+)
+
+// ErrBatchNotFound 批量任务不存在
+var ErrBatchNotFound = errors.New("批量任务不存在")
+
+// BatchService 批量任务服务
+type BatchService struct {
+	batchRepo repository.BatchRepository
+	taskRepo  repository.TaskRepository
+}
+
+// NewBatchService 创建批量任务服务
+func NewBatchService(db repository.Database) *BatchService {
+	return &BatchService{
+		batchRepo: db.BatchRepository(),
+		taskRepo:  db.TaskRepository(),
+	}
+}
+
+// CreateBatch 创建批量任务和全部子任务，子任务通过一次InsertMany写入
+// 返回的子任务与inputs顺序一致，调用方需先扣除积分并在失败时退还
+func (s *BatchService) CreateBatch(ctx context.Context, userID string, inputs []*models.TaskInput) (*models.Batch, []*models.Task, error) {
+	batch := &models.Batch{
+		ID:        primitive.NewObjectID().Hex(),
+		UserID:    userID,
+		TaskIDs:   make([]string, 0, len(inputs)),
+		CreatedAt: time.Now(),
+	}
+
+	tasks := make([]*models.Task, 0, len(inputs))
+	for _, input := range inputs {
+		input.BatchID = batch.ID
+		task := newTask(input)
+		tasks = append(tasks, task)
+		batch.TaskIDs = append(batch.TaskIDs, task.ID)
+		batch.Cost += task.Cost
+	}
+
+	if err := s.batchRepo.CreateBatch(ctx, batch); err != nil {
+		return nil, nil, err
+	}
+	if err := s.taskRepo.CreateTasks(ctx, tasks); err != nil {
+		// InsertMany中途失败时可能已写入部分子任务，一并清理
+		if delErr := s.taskRepo.DeleteTasksByBatchID(ctx, batch.ID); delErr != nil {
+			logger.GetLogger().Errorf("清理批量子任务失败: %s, %v", batch.ID, delErr)
+		}
+		if delErr := s.batchRepo.DeleteBatch(ctx, batch.ID); delErr != nil {
+			logger.GetLogger().Errorf("清理批量任务失败: %s, %v", batch.ID, delErr)
+		}
+		return nil, nil, err
+	}
+
+	return batch, tasks, nil
+}
+
+// GetBatch 获取批量任务
+func (s *BatchService) GetBatch(ctx context.Context, id string) (*models.Batch, error) {
+	batch, err := s.batchRepo.GetBatchByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrBatchNotFound
+		}
+		return nil, err
+	}
+	return batch, nil
+}
+
+// GetBatchTasks 获取批量任务的子任务，按提交顺序排列，已被删除的子任务不返回
+func (s *BatchService) GetBatchTasks(ctx context.Context, batch *models.Batch) ([]*models.Task, error) {
+	tasks, err := s.taskRepo.GetTasksByBatchID(ctx, batch.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]*models.Task, len(tasks))
+	for _, task := range tasks {
+		byID[task.ID] = task
+	}
+
+	ordered := make([]*models.Task, 0, len(tasks))
+	for _, id := range batch.TaskIDs {
+		if task, ok := byID[id]; ok {
+			ordered = append(ordered, task)
+		}
+	}
+	return ordered, nil
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"volcengine-go-server/config"
+	"volcengine-go-server/internal/models"
+	"volcengine-go-server/internal/repository"
+	"volcengine-go-server/pkg/logger"
+)
+
+// ErrInsufficientCredits 用户积分不足
+var ErrInsufficientCredits = errors.New("积分不足")
+
+// CreditService 积分服务，负责任务扣费、退还和用量统计
+type CreditService struct {
+	userRepo repository.UserRepository
+	taskRepo repository.TaskRepository
+}
+
+// NewCreditService 创建积分服务
+func NewCreditService(db repository.Database) *CreditService {
+	return &CreditService{
+		userRepo: db.UserRepository(),
+		taskRepo: db.TaskRepository(),
+	}
+}
+
+// CalculateTaskCost 计算任务费用，图像任务按生成张数计费
+func CalculateTaskCost(taskType, model string, n int) int64 {
+	price := config.GetCreditPrice(taskType, model)
+	if taskType == models.TaskTypeImage && n > 1 {
+		return price * int64(n)
+	}
+	return price
+}
+
+// Charge 扣除积分，余额不足时返回ErrInsufficientCredits
+func (s *CreditService) Charge(ctx context.Context, userID string, amount int64) error {
+	if amount <= 0 {
+		return nil
+	}
+
+	ok, err := s.userRepo.DeductCredits(ctx, userID, amount)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInsufficientCredits
+	}
+	return nil
+}
+
+// Refund 退还积分
+func (s *CreditService) Refund(ctx context.Context, userID string, amount int64) error {
+	if amount <= 0 {
+		return nil
+	}
+	return s.userRepo.AddCredits(ctx, userID, amount)
+}
+
+// AddCredits 为用户充值积分，返回充值后的用户信息
+func (s *CreditService) AddCredits(ctx context.Context, userID string, amount int64) (*models.User, error) {
+	if err := s.userRepo.AddCredits(ctx, userID, amount); err != nil {
+		return nil, err
+	}
+	return s.userRepo.GetUserByID(ctx, userID)
+}
+
+// RefundTask 退还任务扣除的积分，同一任务重复调用只退还一次
+func (s *CreditService) RefundTask(ctx context.Context, taskID string) {
+	changed, err := s.taskRepo.SetCreditRefunded(ctx, taskID, true)
+	if err != nil || !changed {
+		if err != nil {
+			logger.GetLogger().Errorf("标记任务积分退还失败: %s, %v", taskID, err)
+		}
+		return
+	}
+
+	task, err := s.taskRepo.GetTaskByID(ctx, taskID)
+	if err == nil {
+		err = s.Refund(ctx, task.UserID, task.Cost)
+	}
+	if err != nil {
+		// 退还失败时恢复标记，下次失败回写时会再次尝试
+		logger.GetLogger().Errorf("退还任务积分失败: %s, %v", taskID, err)
+		s.taskRepo.SetCreditRefunded(ctx, taskID, false)
+		return
+	}
+
+	logger.GetLogger().Infof("任务积分已退还: taskID=%s, userID=%s, credits=%d", taskID, task.UserID, task.Cost)
+}
+
+// ReclaimTaskRefund 任务重试后成功完成时，重新扣除之前失败时退还的积分
+// 按余额条件扣除，余额不足时不扣成负数，而是标记任务积分未付，由管理员处理
+func (s *CreditService) ReclaimTaskRefund(ctx context.Context, taskID string) {
+	changed, err := s.taskRepo.SetCreditRefunded(ctx, taskID, false)
+	if err != nil || !changed {
+		if err != nil {
+			logger.GetLogger().Errorf("清除任务积分退还标记失败: %s, %v", taskID, err)
+		}
+		return
+	}
+
+	task, err := s.taskRepo.GetTaskByID(ctx, taskID)
+	if err == nil {
+		err = s.Charge(ctx, task.UserID, task.Cost)
+	}
+	if errors.Is(err, ErrInsufficientCredits) {
+		logger.GetLogger().Warnf("任务重试成功但余额不足，积分未重新扣除: taskID=%s, userID=%s, credits=%d", taskID, task.UserID, task.Cost)
+		if err := s.taskRepo.SetCreditUnpaid(ctx, taskID); err != nil {
+			logger.GetLogger().Errorf("标记任务积分未付失败: %s, %v", taskID, err)
+		}
+		return
+	}
+	if err != nil {
+		logger.GetLogger().Errorf("重新扣除任务积分失败: %s, %v", taskID, err)
+		return
+	}
+
+	logger.GetLogger().Infof("任务重试成功，重新扣除积分: taskID=%s, userID=%s, credits=%d", taskID, task.UserID, task.Cost)
+}
+
+// GetUsage 获取用户在统计周期内的积分消耗和剩余积分
+func (s *CreditService) GetUsage(ctx context.Context, userID, period string) (*models.UsageSummary, error) {
+	since, err := usagePeriodStart(period, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := s.taskRepo.GetUserUsage(ctx, userID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &models.UsageSummary{
+		UserID:    userID,
+		Period:    period,
+		Remaining: user.Credits,
+		Items:     items,
+	}
+	if !since.IsZero() {
+		summary.Since = &since
+	}
+	for _, item := range items {
+		summary.Consumed += item.Credits
+	}
+
+	return summary, nil
+}
+
+// usagePeriodStart 计算统计周期的起始时间，all返回零值
+func usagePeriodStart(period string, now time.Time) (time.Time, error) {
+	switch period {
+	case config.UsagePeriodDay:
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()), nil
+	case config.UsagePeriodMonth:
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()), nil
+	case config.UsagePeriodAll:
+		return time.Time{}, nil
+	default:
+		return time.Time{}, fmt.Errorf("不支持的统计周期: %s", period)
+	}
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"volcengine-go-server/config"
+	"volcengine-go-server/internal/models"
+	"volcengine-go-server/internal/repository"
+)
+
+var (
+	// ErrIdempotencyKeyConflict 同一key对应了不同的请求内容
+	ErrIdempotencyKeyConflict = errors.New("幂等键已被不同的请求使用")
+	// ErrIdempotencyKeyInProgress 同一key的首次请求仍在处理中
+	ErrIdempotencyKeyInProgress = errors.New("幂等键对应的请求正在处理中")
+)
+
+// IdempotencyService 幂等服务
+type IdempotencyService struct {
+	idemRepo repository.IdempotencyRepository
+}
+
+// NewIdempotencyService 创建幂等服务
+func NewIdempotencyService(db repository.Database) *IdempotencyService {
+	return &IdempotencyService{
+		idemRepo: db.IdempotencyRepository(),
+	}
+}
+
+// Reserve 占用幂等键
+// 首次请求返回新记录和false；key已完成时返回已有记录和true，调用方直接重放响应
+func (s *IdempotencyService) Reserve(ctx context.Context, userID, key, requestHash string) (*models.IdempotencyRecord, bool, error) {
+	now := time.Now()
+	record := &models.IdempotencyRecord{
+		ID:          primitive.NewObjectID().Hex(),
+		UserID:      userID,
+		Key:         key,
+		RequestHash: requestHash,
+		Status:      models.IdempotencyStatusProcessing,
+		Created:     now,
+		Updated:     now,
+	}
+
+	// 最多尝试两次：第二次用于接管超时未完成的记录
+	for attempt := 0; attempt < 2; attempt++ {
+		created, err := s.idemRepo.CreateRecord(ctx, record)
+		if err != nil {
+			return nil, false, err
+		}
+		if created {
+			return record, false, nil
+		}
+
+		existing, err := s.idemRepo.GetRecord(ctx, userID, key)
+		if err != nil {
+			// 记录恰好过期被清理，重新插入
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				continue
+			}
+			return nil, false, err
+		}
+
+		if existing.RequestHash != requestHash {
+			return nil, false, ErrIdempotencyKeyConflict
+		}
+		if existing.Status == models.IdempotencyStatusCompleted {
+			return existing, true, nil
+		}
+
+		// 首次请求异常中断后记录会一直处于处理中，超时后允许接管
+		deleted, err := s.idemRepo.DeleteStaleRecord(ctx, existing.ID, now.Add(-config.IdempotencyLockTimeout))
+		if err != nil {
+			return nil, false, err
+		}
+		if !deleted {
+			return nil, false, ErrIdempotencyKeyInProgress
+		}
+	}
+
+	return nil, false, ErrIdempotencyKeyInProgress
+}
+
+// Complete 保存首次请求的响应
+func (s *IdempotencyService) Complete(ctx context.Context, record *models.IdempotencyRecord, taskID string, statusCode int, response []byte) error {
+	return s.idemRepo.CompleteRecord(ctx, record.ID, taskID, statusCode, response)
+}
+
+// Release 释放幂等键，首次请求失败时调用，允许客户端使用同一key重试
+func (s *IdempotencyService) Release(ctx context.Context, record *models.IdempotencyRecord) error {
+	return s.idemRepo.DeleteRecord(ctx, record.ID)
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"volcengine-go-server/config"
+	"volcengine-go-server/internal/models"
+	"volcengine-go-server/internal/storage"
+	"volcengine-go-server/pkg/logger"
+)
+
+// ErrInvalidSourceImage 上传的原图无效
+var ErrInvalidSourceImage = errors.New("无效的图片数据")
+
+// MediaService 媒体转存服务
+// 服务商返回的签名URL很快过期，即梦还可能直接返回base64数据，
+// 任务完成前将结果下载并保存到自有存储，对外只暴露稳定地址
+type MediaService struct {
+	storage    storage.Storage
+	httpClient *http.Client
+	log        *logrus.Logger
+}
+
+// NewMediaService 创建媒体转存服务
+func NewMediaService(store storage.Storage) *MediaService {
+	return &MediaService{
+		storage:    store,
+		httpClient: &http.Client{Timeout: config.MediaDownloadTimeout},
+		log:        logger.GetLogger(),
+	}
+}
+
+// Rehost 下载生成结果并转存，source可以是http(s)地址或data URI
+// 对象key由任务ID和序号决定，任务重试时覆盖而不是产生新文件
+func (s *MediaService) Rehost(ctx context.Context, taskID string, index int, source string) (*models.MediaAsset, error) {
+	// 下载到临时文件，同时计算哈希和大小，避免大视频占用内存
+	tmp, err := os.CreateTemp("", "media-*")
+	if err != nil {
+		return nil, fmt.Errorf("创建临时文件失败: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	body, headerType, err := s.open(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(body, config.MediaMaxDownloadBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("下载生成结果失败: %v", err)
+	}
+	if size == 0 {
+		return nil, fmt.Errorf("生成结果为空")
+	}
+	if size > config.MediaMaxDownloadBytes {
+		return nil, fmt.Errorf("生成结果超过大小限制: %d字节", config.MediaMaxDownloadBytes)
+	}
+
+	// 优先根据内容识别类型，无法识别时使用服务商返回的类型
+	sniff := make([]byte, 512)
+	n, _ := tmp.ReadAt(sniff, 0)
+	mimeType := detectMimeType(sniff[:n], headerType)
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("读取临时文件失败: %v", err)
+	}
+
+	key := fmt.Sprintf("tasks/%s/%d%s", taskID, index, extensionForMimeType(mimeType))
+	url, err := s.storage.Put(ctx, key, tmp, size, mimeType)
+	if err != nil {
+		return nil, fmt.Errorf("保存生成结果失败: %v", err)
+	}
+
+	asset := &models.MediaAsset{
+		URL:      url,
+		Key:      key,
+		SHA256:   hex.EncodeToString(hash.Sum(nil)),
+		Size:     size,
+		MimeType: mimeType,
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"task_id":   taskID,
+		"index":     index,
+		"key":       key,
+		"size":      size,
+		"mime_type": mimeType,
+	}).Info("生成结果转存成功")
+
+	return asset, nil
+}
+
+// StoreSourceImage 保存用户上传的原图并返回可访问的地址，data为base64编码或data URI
+// 对象key由内容哈希决定，重复上传同一张图片时复用同一对象
+func (s *MediaService) StoreSourceImage(ctx context.Context, data string) (*models.MediaAsset, error) {
+	content, err := DecodeImageData(data)
+	if err != nil {
+		return nil, err
+	}
+
+	mimeType := detectMimeType(content, "")
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+	key := fmt.Sprintf("uploads/%s%s", hash, extensionForMimeType(mimeType))
+
+	url, err := s.storage.Put(ctx, key, bytes.NewReader(content), int64(len(content)), mimeType)
+	if err != nil {
+		return nil, fmt.Errorf("保存上传图片失败: %v", err)
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"key":       key,
+		"size":      len(content),
+		"mime_type": mimeType,
+	}).Info("上传图片保存成功")
+
+	return &models.MediaAsset{
+		URL:      url,
+		Key:      key,
+		SHA256:   hash,
+		Size:     int64(len(content)),
+		MimeType: mimeType,
+	}, nil
+}
+
+// DecodeImageData 解码上传的图片数据，支持纯base64和 data:<mime>;base64,<data> 格式
+// 数据无法解码、超过大小限制或不是图片时返回ErrInvalidSourceImage
+func DecodeImageData(data string) ([]byte, error) {
+	if strings.HasPrefix(data, "data:") {
+		header, encoded, ok := strings.Cut(strings.TrimPrefix(data, "data:"), ",")
+		if !ok || !strings.HasSuffix(header, ";base64") {
+			return nil, fmt.Errorf("%w: 不支持的data URI格式", ErrInvalidSourceImage)
+		}
+		data = encoded
+	}
+
+	if base64.StdEncoding.DecodedLen(len(data)) > config.SourceImageMaxBytes+2 {
+		return nil, fmt.Errorf("%w: 超过%dMB大小限制", ErrInvalidSourceImage, config.SourceImageMaxBytes>>20)
+	}
+	content, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: base64解码失败", ErrInvalidSourceImage)
+	}
+	if len(content) > config.SourceImageMaxBytes {
+		return nil, fmt.Errorf("%w: 超过%dMB大小限制", ErrInvalidSourceImage, config.SourceImageMaxBytes>>20)
+	}
+	if !strings.HasPrefix(http.DetectContentType(content), "image/") {
+		return nil, fmt.Errorf("%w: 内容不是图片", ErrInvalidSourceImage)
+	}
+	return content, nil
+}
+
+// RehostAll 依次转存多个生成结果，任意一个失败即返回错误
+func (s *MediaService) RehostAll(ctx context.Context, taskID string, sources []string) ([]models.MediaAsset, error) {
+	assets := make([]models.MediaAsset, 0, len(sources))
+	for i, source := range sources {
+		asset, err := s.Rehost(ctx, taskID, i, source)
+		if err != nil {
+			return nil, fmt.Errorf("转存第%d个结果失败: %w", i+1, err)
+		}
+		assets = append(assets, *asset)
+	}
+	return assets, nil
+}
+
+// open 打开生成结果数据源，返回内容和服务商声明的类型
+func (s *MediaService) open(ctx context.Context, source string) (io.ReadCloser, string, error) {
+	if strings.HasPrefix(source, "data:") {
+		return decodeDataURI(source)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("无效的结果地址: %v", err)
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("下载生成结果失败: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, "", fmt.Errorf("下载生成结果失败: 状态码 %d", resp.StatusCode)
+	}
+	return resp.Body, resp.Header.Get("Content-Type"), nil
+}
+
+// decodeDataURI 解析 data:<mime>;base64,<data> 格式的内容
+func decodeDataURI(source string) (io.ReadCloser, string, error) {
+	header, data, ok := strings.Cut(strings.TrimPrefix(source, "data:"), ",")
+	if !ok || !strings.HasSuffix(header, ";base64") {
+		return nil, "", fmt.Errorf("不支持的data URI格式")
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, "", fmt.Errorf("解码base64数据失败: %v", err)
+	}
+	return io.NopCloser(bytes.NewReader(decoded)), strings.TrimSuffix(header, ";base64"), nil
+}
+
+// detectMimeType 识别内容类型
+func detectMimeType(head []byte, declared string) string {
+	detected := http.DetectContentType(head)
+	if detected != "application/octet-stream" {
+		return strings.SplitN(detected, ";", 2)[0]
+	}
+	if mediaType, _, err := mime.ParseMediaType(declared); err == nil && mediaType != "" {
+		return mediaType
+	}
+	return detected
+}
+
+// extensionForMimeType 根据内容类型确定文件扩展名
+func extensionForMimeType(mimeType string) string {
+	switch mimeType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "image/webp":
+		return ".webp"
+	case "image/gif":
+		return ".gif"
+	case "video/mp4":
+		return ".mp4"
+	case "video/webm":
+		return ".webm"
+	}
+	if exts, err := mime.ExtensionsByType(mimeType); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+	return ""
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"volcengine-go-server/internal/models"
+	"volcengine-go-server/internal/util"
+)
+
+// ValidateModelInput 按模型声明的参数规则校验任务输入
+// 缺失、空字符串和空数组视为未填写，只有必填参数会报错；未声明的参数不做校验
+func ValidateModelInput(capability models.ModelCapability, input map[string]interface{}) []util.ValidationError {
+	var errs []util.ValidationError
+	for _, rule := range capability.Params {
+		value, exists := input[rule.Field]
+		if !exists || isEmptyParam(value) {
+			if rule.Required {
+				errs = append(errs, util.ValidationError{
+					Field:   rule.Field,
+					Message: fmt.Sprintf("%s 是必填字段", rule.Field),
+				})
+			}
+			continue
+		}
+		errs = append(errs, checkParam(rule, value)...)
+	}
+	return errs
+}
+
+// checkParam 校验单个已填写的参数
+func checkParam(rule models.ParamRule, value interface{}) []util.ValidationError {
+	invalid := func(format string, args ...interface{}) []util.ValidationError {
+		return []util.ValidationError{{Field: rule.Field, Message: fmt.Sprintf(format, args...), Value: value}}
+	}
+
+	switch rule.Type {
+	case models.ParamTypeString:
+		s, ok := value.(string)
+		if !ok {
+			return invalid("%s 必须是字符串", rule.Field)
+		}
+		if rule.MaxLength > 0 && utf8.RuneCountInString(s) > rule.MaxLength {
+			return invalid("%s 长度不能超过 %d 个字符，当前 %d 个字符", rule.Field, rule.MaxLength, utf8.RuneCountInString(s))
+		}
+		if len(rule.Enum) > 0 && !containsString(rule.Enum, s) {
+			return invalid("%s 不支持 %s，可选值: %s", rule.Field, s, strings.Join(rule.Enum, ", "))
+		}
+
+	case models.ParamTypeInteger, models.ParamTypeNumber:
+		n, ok := paramNumber(value)
+		if !ok {
+			return invalid("%s 必须是数字", rule.Field)
+		}
+		if rule.Type == models.ParamTypeInteger && n != math.Trunc(n) {
+			return invalid("%s 必须是整数", rule.Field)
+		}
+		if rule.Range != nil && (n < rule.Range.Min || n > rule.Range.Max) {
+			return invalid("%s 必须在 %v 到 %v 之间", rule.Field, rule.Range.Min, rule.Range.Max)
+		}
+
+	case models.ParamTypeURLList:
+		urls, ok := paramStrings(value)
+		if !ok {
+			return invalid("%s 必须是链接数组", rule.Field)
+		}
+		if len(urls) < rule.MinItems {
+			return invalid("%s 至少需要 %d 个链接", rule.Field, rule.MinItems)
+		}
+		if rule.MaxItems > 0 && len(urls) > rule.MaxItems {
+			return invalid("%s 最多支持 %d 个链接", rule.Field, rule.MaxItems)
+		}
+		var errs []util.ValidationError
+		for i, raw := range urls {
+			if !isHTTPURL(raw) {
+				errs = append(errs, util.ValidationError{
+					Field:   fmt.Sprintf("%s[%d]", rule.Field, i),
+					Message: fmt.Sprintf("%s[%d] 必须是有效的http或https链接", rule.Field, i),
+					Value:   raw,
+				})
+			}
+		}
+		return errs
+
+	case models.ParamTypeImage:
+		s, ok := value.(string)
+		if !ok {
+			return invalid("%s 必须是图片链接或base64编码的图片", rule.Field)
+		}
+		if isHTTPURL(s) {
+			return nil
+		}
+		if _, err := DecodeImageData(s); err != nil {
+			// 图片数据可能很大，错误详情中不返回原值
+			return []util.ValidationError{{Field: rule.Field, Message: fmt.Sprintf("%s 必须是http或https链接或base64编码的图片: %v", rule.Field, err)}}
+		}
+	}
+	return nil
+}
+
+// isEmptyParam 参数是否视为未填写
+func isEmptyParam(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []string:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	default:
+		return false
+	}
+}
+
+// paramNumber 读取数值参数，载荷经过JSON序列化后数字会变为float64
+func paramNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+// paramStrings 读取字符串数组参数
+func paramStrings(value interface{}) ([]string, bool) {
+	switch v := value.(type) {
+	case []string:
+		return v, true
+	case []interface{}:
+		strs := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			strs = append(strs, s)
+		}
+		return strs, true
+	default:
+		return nil, false
+	}
+}
+
+// isHTTPURL 是否为带主机名的http或https链接
+func isHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
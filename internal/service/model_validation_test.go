@@ -0,0 +1,78 @@
+package service
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"volcengine-go-server/internal/models"
+)
+
+func TestValidateModelInput(t *testing.T) {
+	capability := models.ModelCapability{
+		Model:    "video-model",
+		TaskType: models.TaskTypeVideo,
+		Params: []models.ParamRule{
+			{Field: "prompt", Type: models.ParamTypeString, Required: true, MaxLength: 150},
+			{Field: "aspect_ratio", Type: models.ParamTypeString, Enum: []string{"16:9", "9:16"}},
+			{Field: "n", Type: models.ParamTypeInteger, Range: &models.ParamRange{Min: 1, Max: 4}},
+			{Field: "image_urls", Type: models.ParamTypeURLList, MinItems: 1, MaxItems: 2},
+			{Field: "source_image_url", Type: models.ParamTypeImage},
+		},
+	}
+	png := base64.StdEncoding.EncodeToString([]byte("\x89PNG\r\n\x1a\n0000"))
+
+	tests := []struct {
+		name   string
+		input  map[string]interface{}
+		fields []string
+	}{
+		{"中文prompt按字符计算", map[string]interface{}{"prompt": strings.Repeat("猫", 150)}, nil},
+		{"prompt超长", map[string]interface{}{"prompt": strings.Repeat("猫", 151)}, []string{"prompt"}},
+		{"缺少必填参数", map[string]interface{}{"prompt": "", "aspect_ratio": "16:9"}, []string{"prompt"}},
+		{"枚举值无效", map[string]interface{}{"prompt": "猫", "aspect_ratio": "1:1"}, []string{"aspect_ratio"}},
+		{"JSON数字", map[string]interface{}{"prompt": "猫", "n": float64(4)}, nil},
+		{"数值超出范围", map[string]interface{}{"prompt": "猫", "n": 5}, []string{"n"}},
+		{"非整数", map[string]interface{}{"prompt": "猫", "n": 1.5}, []string{"n"}},
+		{"链接无效", map[string]interface{}{"prompt": "猫", "image_urls": []interface{}{"https://a.com/1.png", "ftp://a.com/2.png"}}, []string{"image_urls[1]"}},
+		{"链接过多", map[string]interface{}{"prompt": "猫", "image_urls": []string{"https://a.com/1", "https://a.com/2", "https://a.com/3"}}, []string{"image_urls"}},
+		{"原图链接", map[string]interface{}{"prompt": "猫", "source_image_url": "https://a.com/1.png"}, nil},
+		{"原图data URI", map[string]interface{}{"prompt": "猫", "source_image_url": "data:image/png;base64," + png}, nil},
+		{"原图不是图片", map[string]interface{}{"prompt": "猫", "source_image_url": base64.StdEncoding.EncodeToString([]byte("hello"))}, []string{"source_image_url"}},
+		{"原图无法解码", map[string]interface{}{"prompt": "猫", "source_image_url": "not-base64!"}, []string{"source_image_url"}},
+	}
+
+	for _, tt := range tests {
+		errs := ValidateModelInput(capability, tt.input)
+		if len(errs) != len(tt.fields) {
+			t.Errorf("%s: 错误 = %+v, 期望字段 %v", tt.name, errs, tt.fields)
+			continue
+		}
+		for i, field := range tt.fields {
+			if errs[i].Field != field {
+				t.Errorf("%s: 错误字段 = %s, 期望 %s", tt.name, errs[i].Field, field)
+			}
+		}
+	}
+}
+
+func TestStepValidationInput(t *testing.T) {
+	capability := models.ModelCapability{
+		Model:    "i2v-model",
+		TaskType: models.TaskTypeVideo,
+		Params: []models.ParamRule{
+			{Field: "image_urls", Type: models.ParamTypeURLList, Required: true, MinItems: 1},
+			{Field: "prompt", Type: models.ParamTypeString, Required: true, MaxLength: 10},
+		},
+	}
+
+	step := models.PipelineStep{Prompt: "{{steps.0.output}}", ImageURLs: []string{"{{prev.output}}"}}
+	if errs := ValidateModelInput(capability, StepValidationInput(step)); len(errs) > 0 {
+		t.Errorf("引用之前步骤的输出不应校验失败: %+v", errs)
+	}
+
+	step.Prompt = "{{prev.output}}" + strings.Repeat("猫", 11)
+	if errs := ValidateModelInput(capability, StepValidationInput(step)); len(errs) != 1 || errs[0].Field != "prompt" {
+		t.Errorf("引用之外的文本超长应校验失败: %+v", errs)
+	}
+}
@@ -1,83 +1,397 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
 	"time"
 
+	"github.com/sirupsen/logrus"
+
+	"volcengine-go-server/config"
 	"volcengine-go-server/pkg/logger"
 )
 
+// OpenAIProviderName OpenAI分发器名称
+const OpenAIProviderName = "openai"
+
+// CallLimiter 服务商接口调用限流和熔断接口，避免依赖core包
+type CallLimiter interface {
+	// 调用前获取令牌，熔断中或限流时返回需要延迟重试的错误
+	Acquire(ctx context.Context, provider, model string) error
+	// 记录调用结果，overloaded表示服务商限流、服务端错误或网络错误
+	RecordResult(ctx context.Context, provider, model string, overloaded bool)
+}
+
 // OpenAIService OpenAI服务 - Service层，负责具体的API调用实现
-// 注意：这是一个示例实现，实际使用时需要集成真实的OpenAI API
+// 通过配置的BaseURL可以接入OpenAI官方接口，也可以接入本地部署的OpenAI兼容服务
 type OpenAIService struct {
 	apiKey      string
+	baseURL     string
+	httpClient  *http.Client
+	logger      *logrus.Logger
 	taskService *TaskService
+	// 可选：设置后调用服务商接口前进行限流和熔断检查
+	limiter CallLimiter
 }
 
 // NewOpenAIService 创建OpenAI服务实例
-func NewOpenAIService(apiKey string, taskService *TaskService) *OpenAIService {
+func NewOpenAIService(cfg config.AIConfig, taskService *TaskService) *OpenAIService {
+	timeout, err := time.ParseDuration(cfg.Timeout)
+	if err != nil || timeout <= 0 {
+		timeout = config.DefaultOpenAITimeout
+	}
+
 	return &OpenAIService{
-		apiKey:      apiKey,
+		apiKey:      cfg.OpenAIAPIKey,
+		baseURL:     strings.TrimRight(cfg.OpenAIBaseURL, "/"),
+		httpClient:  &http.Client{Timeout: timeout},
+		logger:      logger.GetLogger(),
 		taskService: taskService,
 	}
 }
 
+// SetCallLimiter 设置服务商接口调用限流器
+func (s *OpenAIService) SetCallLimiter(limiter CallLimiter) {
+	s.limiter = limiter
+}
+
+// openAIImageRequest 图像生成接口请求
+type openAIImageRequest struct {
+	Model          string `json:"model"`
+	Prompt         string `json:"prompt"`
+	N              int    `json:"n"`
+	Size           string `json:"size"`
+	ResponseFormat string `json:"response_format"`
+}
+
+// openAIImageResponse 图像生成接口响应
+type openAIImageResponse struct {
+	Data []struct {
+		URL string `json:"url"`
+	} `json:"data"`
+}
+
+// openAIChatMessage 对话消息
+type openAIChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// openAIChatRequest 对话接口请求
+type openAIChatRequest struct {
+	Model       string              `json:"model"`
+	Messages    []openAIChatMessage `json:"messages"`
+	MaxTokens   int                 `json:"max_tokens,omitempty"`
+	Temperature float64             `json:"temperature"`
+}
+
+// openAIChatResponse 对话接口响应
+type openAIChatResponse struct {
+	Choices []struct {
+		Message      openAIChatMessage `json:"message"`
+		FinishReason string            `json:"finish_reason"`
+	} `json:"choices"`
+	Usage struct {
+		TotalTokens int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+// openAIErrorResponse 接口错误响应
+type openAIErrorResponse struct {
+	Error struct {
+		Message string      `json:"message"`
+		Type    string      `json:"type"`
+		Code    interface{} `json:"code"` // 官方接口为字符串，部分兼容服务返回数字
+	} `json:"error"`
+}
+
 // GenerateImageByDALLE DALL-E图像生成具体实现
-func (s *OpenAIService) GenerateImageByDALLE(ctx context.Context, taskID string, input map[string]interface{}) error {
-	// TODO: 实现OpenAI DALL-E图像生成逻辑
-	log := logger.GetLogger()
-	log.Infof("DALL-E图像生成任务处理中: %s", taskID)
-
-	// 模拟处理时间
-	time.Sleep(3 * time.Second)
-
-	// 模拟成功结果
-	imageURL := "https://example.com/generated-image.jpg"
-	if err := s.taskService.UpdateTaskResult(ctx, taskID, imageURL); err != nil {
-		log.Errorf("更新任务状态失败: %v", err)
+func (s *OpenAIService) GenerateImageByDALLE(ctx context.Context, taskID, model string, input map[string]interface{}) error {
+	s.logger.Infof("DALL-E图像生成开始: taskID=%s, model=%s", taskID, model)
+
+	prompt, ok := input["prompt"].(string)
+	if !ok || prompt == "" {
+		err := NewProviderError(config.TaskErrorInvalidInput, "无效的prompt参数", nil)
+		s.logger.Errorf("获取任务输入失败: %v", err)
 		return err
 	}
 
-	log.Infof("DALL-E图像生成任务完成: %s", taskID)
+	aspectRatio, _ := input["aspect_ratio"].(string)
+	request := &openAIImageRequest{
+		Model:          model,
+		Prompt:         prompt,
+		N:              1, // dall-e-3 单次只能生成1张图片，按数量并发调用
+		Size:           openAIImageSize(aspectRatio),
+		ResponseFormat: "url",
+	}
+
+	n := openAIImageCount(input)
+	results := make(chan []string, n)
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			urls, err := s.generateImage(ctx, request)
+			if err != nil {
+				errs <- err
+				return
+			}
+			results <- urls
+		}()
+	}
+
+	var imageURLs []string
+	var lastErr error
+	for i := 0; i < n; i++ {
+		select {
+		case urls := <-results:
+			imageURLs = append(imageURLs, urls...)
+		case err := <-errs:
+			lastErr = err
+			s.logger.Warnf("第%d次图像生成调用失败: %v", i+1, err)
+		}
+	}
+	if len(imageURLs) == 0 {
+		if lastErr == nil {
+			lastErr = errors.New("未生成任何图像")
+		}
+		s.logger.Errorf("DALL-E图像生成失败: %v", lastErr)
+		return lastErr
+	}
+	// 部分调用失败时不保存部分结果，由队列重新生成全部图片
+	if len(imageURLs) < n {
+		err := NewIncompleteImagesError(n, len(imageURLs), lastErr)
+		s.logger.Errorf("DALL-E图像生成失败: %v", err)
+		return err
+	}
+	imageURLs = imageURLs[:n]
+
+	s.logger.Infof("DALL-E图像生成任务完成: %s (尺寸: %s), 图像数量: %d/%d", taskID, request.Size, len(imageURLs), n)
+
+	if err := s.taskService.UpdateTaskImageResults(ctx, taskID, imageURLs); err != nil {
+		s.logger.Errorf("更新任务状态失败: %v", err)
+		return err
+	}
 	return nil
 }
 
 // GenerateTextByGPT GPT文本生成具体实现
-func (s *OpenAIService) GenerateTextByGPT(ctx context.Context, taskID string, input map[string]interface{}) error {
-	// TODO: 实现OpenAI GPT文本生成逻辑
-	log := logger.GetLogger()
-	log.Infof("GPT文本生成任务处理中: %s", taskID)
-
-	// 模拟处理时间
-	time.Sleep(1 * time.Second)
-
-	// 模拟成功结果
-	textResult := "这是GPT生成的文本内容"
-	if err := s.taskService.UpdateTaskResult(ctx, taskID, textResult); err != nil {
-		log.Errorf("更新任务状态失败: %v", err)
+func (s *OpenAIService) GenerateTextByGPT(ctx context.Context, taskID, model string, input map[string]interface{}) error {
+	s.logger.Infof("GPT文本生成开始: taskID=%s, model=%s", taskID, model)
+
+	prompt, ok := input["prompt"].(string)
+	if !ok || prompt == "" {
+		err := NewProviderError(config.TaskErrorInvalidInput, "无效的prompt参数", nil)
+		s.logger.Errorf("获取任务输入失败: %v", err)
 		return err
 	}
 
-	log.Infof("GPT文本生成任务完成: %s", taskID)
+	request := &openAIChatRequest{
+		Model:       model,
+		Messages:    []openAIChatMessage{{Role: "user", Content: prompt}},
+		MaxTokens:   getInputInt(input, "max_tokens", config.DefaultTextMaxTokens),
+		Temperature: getInputFloat(input, "temperature", config.DefaultTextTemperature),
+	}
+
+	text, err := s.generateText(ctx, request)
+	if err != nil {
+		s.logger.Errorf("GPT文本生成失败: %v", err)
+		return err
+	}
+
+	s.logger.Infof("GPT文本生成任务完成: %s, 文本长度: %d", taskID, len([]rune(text)))
+
+	if err := s.taskService.UpdateTaskResult(ctx, taskID, text); err != nil {
+		s.logger.Errorf("更新任务状态失败: %v", err)
+		return err
+	}
 	return nil
 }
 
-// GenerateVideoBySora Sora视频生成具体实现
-func (s *OpenAIService) GenerateVideoBySora(ctx context.Context, taskID string, input map[string]interface{}) error {
-	// TODO: 实现OpenAI Sora视频生成逻辑
-	log := logger.GetLogger()
-	log.Infof("Sora视频生成任务处理中: %s", taskID)
+// generateImage 调用图像生成接口 - 内部方法
+func (s *OpenAIService) generateImage(ctx context.Context, request *openAIImageRequest) ([]string, error) {
+	var resp openAIImageResponse
+	if err := s.post(ctx, "/images/generations", request.Model, request, &resp); err != nil {
+		return nil, err
+	}
 
-	// 模拟处理时间（视频生成通常需要更长时间）
-	time.Sleep(15 * time.Second)
+	urls := make([]string, 0, len(resp.Data))
+	for _, data := range resp.Data {
+		if data.URL != "" {
+			urls = append(urls, data.URL)
+		}
+	}
+	if len(urls) == 0 {
+		return nil, errors.New("响应中未找到有效的图片地址")
+	}
+	return urls, nil
+}
 
-	// 模拟成功结果
-	videoURL := "https://example.com/generated-video.mp4"
-	if err := s.taskService.UpdateTaskResult(ctx, taskID, videoURL); err != nil {
-		log.Errorf("更新任务状态失败: %v", err)
-		return err
+// generateText 调用对话接口 - 内部方法
+func (s *OpenAIService) generateText(ctx context.Context, request *openAIChatRequest) (string, error) {
+	var resp openAIChatResponse
+	if err := s.post(ctx, "/chat/completions", request.Model, request, &resp); err != nil {
+		return "", err
+	}
+
+	if len(resp.Choices) == 0 {
+		return "", errors.New("响应中未包含任何候选结果")
+	}
+	if resp.Choices[0].Message.Content == "" {
+		return "", errors.New("未生成任何文本")
+	}
+	return resp.Choices[0].Message.Content, nil
+}
+
+// post 发送JSON请求并解析响应，失败时返回分类后的ProviderError
+func (s *OpenAIService) post(ctx context.Context, path, model string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("序列化请求失败: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
 	}
 
-	log.Infof("Sora视频生成任务完成: %s", taskID)
+	if s.limiter != nil {
+		if err := s.limiter.Acquire(ctx, OpenAIProviderName, model); err != nil {
+			return err
+		}
+	}
+
+	startTime := time.Now()
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		s.recordResult(ctx, model, !errors.Is(err, context.Canceled))
+		s.logger.WithFields(logrus.Fields{
+			"api_endpoint": path,
+			"model":        model,
+			"duration_ms":  time.Since(startTime).Milliseconds(),
+			"error":        err.Error(),
+		}).Error("OpenAI API调用失败")
+		return NewProviderError(config.TaskErrorTransient, "OpenAI接口请求失败", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	s.recordResult(ctx, model, err != nil || isOverloadedStatus(resp.StatusCode))
+	if err != nil {
+		return NewProviderError(config.TaskErrorTransient, "读取OpenAI接口响应失败", err)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"api_endpoint": path,
+		"model":        model,
+		"status":       resp.StatusCode,
+		"duration_ms":  time.Since(startTime).Milliseconds(),
+	}).Info("OpenAI API调用完成")
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return classifyOpenAIError(resp.StatusCode, data)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("解析OpenAI接口响应失败: %w", err)
+	}
 	return nil
 }
+
+// recordResult 记录接口调用结果，未设置限流器时忽略
+func (s *OpenAIService) recordResult(ctx context.Context, model string, overloaded bool) {
+	if s.limiter != nil {
+		s.limiter.RecordResult(ctx, OpenAIProviderName, model, overloaded)
+	}
+}
+
+// classifyOpenAIError 按HTTP状态码和错误码将接口错误映射为任务错误码
+func classifyOpenAIError(status int, body []byte) error {
+	var errResp openAIErrorResponse
+	_ = json.Unmarshal(body, &errResp)
+	code, _ := errResp.Error.Code.(string)
+
+	message := errResp.Error.Message
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	err := fmt.Errorf("status=%d, type=%s, code=%v: %s", status, errResp.Error.Type, errResp.Error.Code, message)
+
+	switch {
+	case code == "content_policy_violation":
+		return NewProviderError(config.TaskErrorContentPolicy, "生成内容未通过安全审核", err)
+	case code == "insufficient_quota" || errResp.Error.Type == "insufficient_quota":
+		return NewProviderError(config.TaskErrorQuotaExceeded, "OpenAI账户额度不足", err)
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return NewProviderError(config.TaskErrorAuthFailed, "OpenAI鉴权失败", err)
+	case isOverloadedStatus(status) || status == http.StatusRequestTimeout:
+		return NewProviderError(config.TaskErrorTransient, "OpenAI服务暂时不可用", err)
+	default:
+		return NewProviderError(config.TaskErrorInvalidInput, "OpenAI请求参数无效", err)
+	}
+}
+
+// isOverloadedStatus 限流或服务端错误的HTTP状态码
+func isOverloadedStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+// openAIImageSize 将宽高比映射为DALL-E 3支持的尺寸
+func openAIImageSize(aspectRatio string) string {
+	switch aspectRatio {
+	case "16:9", "4:3", "3:2", "21:9":
+		return config.OpenAIImageSizeLandscape
+	case "9:16", "3:4", "2:3":
+		return config.OpenAIImageSizePortrait
+	default:
+		return config.OpenAIImageSizeSquare
+	}
+}
+
+// openAIImageCount 获取图像生成数量，限制在[1, MaxImageN]范围内
+func openAIImageCount(input map[string]interface{}) int {
+	n := getInputInt(input, "n", config.DefaultImageN)
+	if n < 1 {
+		return config.DefaultImageN
+	}
+	if n > config.MaxImageN {
+		return config.MaxImageN
+	}
+	return n
+}
+
+// getInputInt 从任务输入中读取整数参数，载荷经过JSON序列化后数字会变为float64
+func getInputInt(input map[string]interface{}, key string, defaultValue int) int {
+	switch v := input[key].(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	default:
+		return defaultValue
+	}
+}
+
+// getInputFloat 从任务输入中读取浮点数参数
+func getInputFloat(input map[string]interface{}, key string, defaultValue float64) float64 {
+	switch v := input[key].(type) {
+	case float64:
+		return v
+	case float32:
+		return float64(v)
+	case int:
+		return float64(v)
+	default:
+		return defaultValue
+	}
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"volcengine-go-server/config"
+)
+
+func TestOpenAIServiceRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer test-key" {
+			t.Errorf("Authorization = %q", got)
+		}
+		switch r.URL.Path {
+		case "/v1/images/generations":
+			var req openAIImageRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			if req.Size != config.OpenAIImageSizeLandscape {
+				t.Errorf("size = %s, 期望 %s", req.Size, config.OpenAIImageSizeLandscape)
+			}
+			w.Write([]byte(`{"data":[{"url":"https://cdn.example.com/a.png"}]}`))
+		case "/v1/chat/completions":
+			w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"你好"},"finish_reason":"stop"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	s := NewOpenAIService(config.AIConfig{OpenAIAPIKey: "test-key", OpenAIBaseURL: server.URL + "/v1/"}, nil)
+
+	urls, err := s.generateImage(context.Background(), &openAIImageRequest{
+		Model:  config.OpenAIImageModel,
+		Prompt: "cat",
+		N:      1,
+		Size:   openAIImageSize("16:9"),
+	})
+	if err != nil || len(urls) != 1 || urls[0] != "https://cdn.example.com/a.png" {
+		t.Fatalf("generateImage = %v, %v", urls, err)
+	}
+
+	text, err := s.generateText(context.Background(), &openAIChatRequest{Model: config.OpenAITextModel})
+	if err != nil || text != "你好" {
+		t.Fatalf("generateText = %q, %v", text, err)
+	}
+}
+
+func TestClassifyOpenAIError(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		expected string
+	}{
+		{"敏感内容", 400, `{"error":{"message":"rejected","type":"invalid_request_error","code":"content_policy_violation"}}`, config.TaskErrorContentPolicy},
+		{"参数错误", 400, `{"error":{"message":"bad size","type":"invalid_request_error","code":null}}`, config.TaskErrorInvalidInput},
+		{"鉴权失败", 401, `{"error":{"message":"invalid key","type":"invalid_request_error","code":"invalid_api_key"}}`, config.TaskErrorAuthFailed},
+		{"额度用尽", 429, `{"error":{"message":"quota","type":"insufficient_quota","code":"insufficient_quota"}}`, config.TaskErrorQuotaExceeded},
+		{"限流", 429, `{"error":{"message":"slow down","type":"requests","code":"rate_limit_exceeded"}}`, config.TaskErrorTransient},
+		{"服务端错误", 502, `bad gateway`, config.TaskErrorTransient},
+		{"数字错误码", 500, `{"error":{"message":"oops","code":500}}`, config.TaskErrorTransient},
+	}
+
+	for _, tt := range tests {
+		if code := ErrorCode(classifyOpenAIError(tt.status, []byte(tt.body))); code != tt.expected {
+			t.Errorf("%s: 错误码 = %s, 期望 %s", tt.name, code, tt.expected)
+		}
+	}
+}
+
+func TestGenerateImageByDALLEIncomplete(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		expected string
+	}{
+		{"临时错误整体重试", 502, `bad gateway`, config.TaskErrorTransient},
+		{"审核未通过保留错误码", 400, `{"error":{"message":"rejected","type":"invalid_request_error","code":"content_policy_violation"}}`, config.TaskErrorContentPolicy},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// 只有第一次调用成功，其余调用失败
+				if atomic.AddInt32(&calls, 1) > 1 {
+					w.WriteHeader(tt.status)
+					w.Write([]byte(tt.body))
+					return
+				}
+				w.Write([]byte(`{"data":[{"url":"https://cdn.example.com/a.png"}]}`))
+			}))
+			defer server.Close()
+
+			// 交付不足时不保存部分结果，未设置taskService也不会被调用
+			s := NewOpenAIService(config.AIConfig{OpenAIAPIKey: "test-key", OpenAIBaseURL: server.URL + "/v1/"}, nil)
+			err := s.GenerateImageByDALLE(context.Background(), "task-1", config.OpenAIImageModel,
+				map[string]interface{}{"prompt": "cat", "n": 3})
+			if err == nil {
+				t.Fatal("交付不足时应返回错误")
+			}
+			if code := ErrorCode(err); code != tt.expected {
+				t.Errorf("错误码 = %s, 期望 %s", code, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,269 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"volcengine-go-server/config"
+	"volcengine-go-server/internal/models"
+	"volcengine-go-server/internal/repository"
+)
+
+var (
+	// ErrPipelineNotFound 流水线不存在
+	ErrPipelineNotFound = errors.New("流水线不存在")
+	// ErrInvalidPipeline 流水线定义无效
+	ErrInvalidPipeline = errors.New("流水线定义无效")
+)
+
+// 步骤输入模板中的占位符，支持 {{prev.output}} 和 {{steps.N.output}}（N从0开始）
+var (
+	pipelinePlaceholderPattern = regexp.MustCompile(`\{\{\s*([^{}]*?)\s*\}\}`)
+	pipelineReferencePattern   = regexp.MustCompile(`^(?:prev|steps\.(\d+))\.output$`)
+)
+
+// 校验模型参数时代替步骤输出引用的示例值
+const (
+	pipelineOutputSampleText = "output"                          // prompt只包含引用时使用
+	pipelineOutputSampleURL  = "https://pipeline.invalid/output" // image_urls中含引用的元素使用
+)
+
+// PipelineService 流水线服务
+type PipelineService struct {
+	pipelineRepo repository.PipelineRepository
+}
+
+// NewPipelineService 创建流水线服务
+func NewPipelineService(db repository.Database) *PipelineService {
+	return &PipelineService{
+		pipelineRepo: db.PipelineRepository(),
+	}
+}
+
+// NewPipeline 校验步骤定义并构建流水线，计算各步骤和总积分，调用方扣除积分后再调用CreatePipeline
+func NewPipeline(userID string, steps []models.PipelineStep) (*models.Pipeline, error) {
+	if err := ValidatePipelineSteps(steps); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	pipeline := &models.Pipeline{
+		ID:      primitive.NewObjectID().Hex(),
+		UserID:  userID,
+		Status:  config.TaskStatusPending,
+		Steps:   steps,
+		Created: now,
+		Updated: now,
+	}
+	for i := range pipeline.Steps {
+		// 执行状态以服务端为准，忽略请求中携带的值
+		step := &pipeline.Steps[i]
+		step.Status = config.TaskStatusPending
+		step.TaskID, step.Output, step.Error = "", "", ""
+		step.StartedAt, step.FinishedAt = nil, nil
+		step.Cost = CalculateTaskCost(step.Type, step.Model, step.N)
+		pipeline.Cost += step.Cost
+	}
+	return pipeline, nil
+}
+
+// CreatePipeline 保存流水线
+func (s *PipelineService) CreatePipeline(ctx context.Context, pipeline *models.Pipeline) error {
+	return s.pipelineRepo.CreatePipeline(ctx, pipeline)
+}
+
+// GetPipeline 获取流水线
+func (s *PipelineService) GetPipeline(ctx context.Context, id string) (*models.Pipeline, error) {
+	pipeline, err := s.pipelineRepo.GetPipelineByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrPipelineNotFound
+		}
+		return nil, err
+	}
+	return pipeline, nil
+}
+
+// ListPipelines 分页获取用户的流水线
+func (s *PipelineService) ListPipelines(ctx context.Context, userID string, limit, offset int) ([]*models.Pipeline, error) {
+	return s.pipelineRepo.GetPipelinesByUserID(ctx, userID, limit, offset)
+}
+
+// StartStep 开始执行步骤并记录分配的任务ID，返回false表示流水线已结束或步骤已开始
+func (s *PipelineService) StartStep(ctx context.Context, id string, step int, taskID string) (bool, error) {
+	return s.pipelineRepo.StartStep(ctx, id, step, taskID)
+}
+
+// CompleteStep 记录步骤输出，返回false表示流水线已结束或步骤不在执行中
+func (s *PipelineService) CompleteStep(ctx context.Context, id string, step int, output string) (bool, error) {
+	return s.pipelineRepo.CompleteStep(ctx, id, step, output)
+}
+
+// FinishPipeline 结束流水线，step不小于0时同时更新该步骤的状态，返回false表示流水线已结束
+func (s *PipelineService) FinishPipeline(ctx context.Context, id, status, errorMsg string, step int, stepStatus string) (bool, error) {
+	return s.pipelineRepo.FinishPipeline(ctx, id, status, errorMsg, step, stepStatus)
+}
+
+// StepInput 使用之前步骤的输出渲染步骤模板，构建步骤任务的输入和图生视频的图片地址
+func StepInput(pipeline *models.Pipeline, index int) (*models.TaskInput, []string, error) {
+	step := pipeline.Steps[index]
+
+	prompt, err := renderStepTemplate(pipeline, index, step.Prompt)
+	if err != nil {
+		return nil, nil, err
+	}
+	imageURLs := make([]string, 0, len(step.ImageURLs))
+	for _, tmpl := range step.ImageURLs {
+		url, err := renderStepTemplate(pipeline, index, tmpl)
+		if err != nil {
+			return nil, nil, err
+		}
+		imageURLs = append(imageURLs, url)
+	}
+
+	input := &models.TaskInput{
+		ID:       step.TaskID,
+		Prompt:   prompt,
+		UserID:   pipeline.UserID,
+		Type:     step.Type,
+		Model:    step.Model,
+		Provider: step.Provider,
+
+		AspectRatio: step.AspectRatio,
+		N:           step.N,
+		Seed:        step.Seed,
+		MaxTokens:   step.MaxTokens,
+		Temperature: step.Temperature,
+
+		Cost:         step.Cost,
+		PipelineID:   pipeline.ID,
+		PipelineStep: index,
+	}
+	return input, imageURLs, nil
+}
+
+// StepValidationInput 构建用于模型参数校验的步骤输入，零值视为未填写
+// 创建时引用的步骤输出还没有实际内容：prompt按去掉引用后的文本校验，只包含引用时以示例文本代替，
+// image_urls中含引用的元素以示例地址代替，步骤执行时Worker会按渲染后的实际输入再次校验
+func StepValidationInput(step models.PipelineStep) map[string]interface{} {
+	prompt := pipelinePlaceholderPattern.ReplaceAllString(step.Prompt, "")
+	if prompt == "" && step.Prompt != "" {
+		prompt = pipelineOutputSampleText
+	}
+
+	input := map[string]interface{}{
+		"prompt":       prompt,
+		"aspect_ratio": step.AspectRatio,
+	}
+	if step.N != 0 {
+		input["n"] = step.N
+	}
+	if step.Seed != 0 {
+		input["seed"] = step.Seed
+	}
+	if step.MaxTokens != 0 {
+		input["max_tokens"] = step.MaxTokens
+	}
+	if step.Temperature != 0 {
+		input["temperature"] = step.Temperature
+	}
+	if len(step.ImageURLs) > 0 {
+		imageURLs := make([]string, len(step.ImageURLs))
+		for i, tmpl := range step.ImageURLs {
+			imageURLs[i] = tmpl
+			if pipelinePlaceholderPattern.MatchString(tmpl) {
+				imageURLs[i] = pipelineOutputSampleURL
+			}
+		}
+		input["image_urls"] = imageURLs
+	}
+	return input
+}
+
+// ValidatePipelineSteps 校验步骤定义和模板引用，步骤只能引用之前步骤的输出
+func ValidatePipelineSteps(steps []models.PipelineStep) error {
+	if len(steps) == 0 || len(steps) > config.MaxPipelineSteps {
+		return fmt.Errorf("%w: 步骤数量必须在1到%d之间", ErrInvalidPipeline, config.MaxPipelineSteps)
+	}
+
+	for i, step := range steps {
+		if err := validatePipelineStep(i, step); err != nil {
+			return fmt.Errorf("%w: 步骤%d: %v", ErrInvalidPipeline, i, err)
+		}
+	}
+	return nil
+}
+
+// validatePipelineStep 校验单个步骤的参数和模板引用
+// 步骤的模型参数由接口层通过StepValidationInput按服务商能力描述校验
+func validatePipelineStep(index int, step models.PipelineStep) error {
+	if step.Provider == "" || step.Model == "" {
+		return errors.New("provider和model不能为空")
+	}
+
+	switch step.Type {
+	case models.TaskTypeImage:
+		if step.N < 0 || step.N > config.MaxImageN {
+			return fmt.Errorf("单个任务最多生成%d张图片", config.MaxImageN)
+		}
+	case models.TaskTypeVideo, models.TaskTypeText:
+	default:
+		return fmt.Errorf("不支持的任务类型 %s", step.Type)
+	}
+
+	templates := append([]string{step.Prompt}, step.ImageURLs...)
+	for _, tmpl := range templates {
+		for _, match := range pipelinePlaceholderPattern.FindAllStringSubmatch(tmpl, -1) {
+			ref, err := parseStepReference(index, match[1])
+			if err != nil {
+				return err
+			}
+			if ref < 0 || ref >= index {
+				return fmt.Errorf("%s 只能引用之前步骤的输出", match[0])
+			}
+		}
+	}
+	return nil
+}
+
+// parseStepReference 解析占位符引用的步骤序号
+func parseStepReference(index int, ref string) (int, error) {
+	match := pipelineReferencePattern.FindStringSubmatch(ref)
+	if match == nil {
+		return 0, fmt.Errorf("不支持的占位符 {{%s}}，可使用 {{prev.output}} 或 {{steps.N.output}}", ref)
+	}
+	if match[1] == "" {
+		return index - 1, nil
+	}
+	return strconv.Atoi(match[1])
+}
+
+// renderStepTemplate 将模板中的占位符替换为对应步骤的输出
+func renderStepTemplate(pipeline *models.Pipeline, index int, tmpl string) (string, error) {
+	var renderErr error
+	result := pipelinePlaceholderPattern.ReplaceAllStringFunc(tmpl, func(placeholder string) string {
+		match := pipelinePlaceholderPattern.FindStringSubmatch(placeholder)
+		ref, err := parseStepReference(index, match[1])
+		if err == nil && (ref < 0 || ref >= index) {
+			err = fmt.Errorf("%s 只能引用之前步骤的输出", placeholder)
+		}
+		if err == nil && pipeline.Steps[ref].Output == "" {
+			err = fmt.Errorf("步骤%d没有输出", ref)
+		}
+		if err != nil {
+			if renderErr == nil {
+				renderErr = err
+			}
+			return ""
+		}
+		return pipeline.Steps[ref].Output
+	})
+	return result, renderErr
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"volcengine-go-server/config"
+)
+
+// ProviderError 服务商调用错误，Code为config.TaskError*错误码
+// 只有网络错误、限流和服务端错误可以重试，其余错误重试不会成功
+type ProviderError struct {
+	Code    string
+	Message string
+	Err     error
+}
+
+// NewProviderError 创建服务商调用错误，err为原始错误，可以为nil
+func NewProviderError(code, message string, err error) *ProviderError {
+	return &ProviderError{Code: code, Message: message, Err: err}
+}
+
+func (e *ProviderError) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
+	return e.Message + ": " + e.Err.Error()
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable 错误是否可以重试或切换到其他服务商
+func (e *ProviderError) Retryable() bool {
+	return e.Code == config.TaskErrorTransient
+}
+
+// ErrorCode 获取错误对应的任务错误码，未分类的错误返回config.TaskErrorInternal
+func ErrorCode(err error) string {
+	var providerErr *ProviderError
+	if errors.As(err, &providerErr) {
+		return providerErr.Code
+	}
+	return config.TaskErrorInternal
+}
+
+// NewIncompleteImagesError 并发生成的图片少于请求数量时返回的错误，已生成的部分不保存
+// 失败原因不可重试（如内容审核未通过）时保留原错误码，否则按临时错误由队列重新生成全部图片
+func NewIncompleteImagesError(requested, delivered int, lastErr error) error {
+	message := fmt.Sprintf("图像生成数量不足: 期望%d张，实际%d张", requested, delivered)
+	var providerErr *ProviderError
+	if errors.As(lastErr, &providerErr) && !providerErr.Retryable() {
+		return NewProviderError(providerErr.Code, message, lastErr)
+	}
+	return NewProviderError(config.TaskErrorTransient, message, lastErr)
+}
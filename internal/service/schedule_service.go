@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"volcengine-go-server/config"
+	"volcengine-go-server/internal/models"
+	"volcengine-go-server/internal/repository"
+)
+
+var (
+	// ErrScheduleNotFound 周期任务不存在或不属于当前用户
+	ErrScheduleNotFound = errors.New("周期任务不存在")
+	// ErrInvalidSchedule 周期任务配置无效
+	ErrInvalidSchedule = errors.New("周期任务配置无效")
+	// ErrScheduleLimitExceeded 用户的周期任务数量已达上限
+	ErrScheduleLimitExceeded = errors.New("周期任务数量已达上限")
+)
+
+// scheduleIntervalSamples 校验最小执行间隔时检查的执行次数
+const scheduleIntervalSamples = 100
+
+// ScheduleService 周期任务模板服务
+type ScheduleService struct {
+	scheduleRepo repository.ScheduleRepository
+}
+
+// NewScheduleService 创建周期任务模板服务
+func NewScheduleService(db repository.Database) *ScheduleService {
+	return &ScheduleService{
+		scheduleRepo: db.ScheduleRepository(),
+	}
+}
+
+// CreateSchedule 校验并创建周期任务模板
+func (s *ScheduleService) CreateSchedule(ctx context.Context, schedule *models.Schedule) error {
+	if err := ValidateSchedule(schedule); err != nil {
+		return err
+	}
+
+	count, err := s.scheduleRepo.CountSchedulesByUserID(ctx, schedule.UserID)
+	if err != nil {
+		return err
+	}
+	if count >= config.MaxSchedulesPerUser {
+		return fmt.Errorf("%w: 每个用户最多%d个", ErrScheduleLimitExceeded, config.MaxSchedulesPerUser)
+	}
+
+	now := time.Now()
+	schedule.ID = primitive.NewObjectID().Hex()
+	schedule.CreatedAt = now
+	schedule.UpdatedAt = now
+	return s.scheduleRepo.CreateSchedule(ctx, schedule)
+}
+
+// GetSchedule 获取用户的周期任务模板
+func (s *ScheduleService) GetSchedule(ctx context.Context, userID, id string) (*models.Schedule, error) {
+	schedule, err := s.GetScheduleByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if schedule.UserID != userID {
+		return nil, ErrScheduleNotFound
+	}
+	return schedule, nil
+}
+
+// GetScheduleByID 根据ID获取周期任务模板，不校验所属用户
+func (s *ScheduleService) GetScheduleByID(ctx context.Context, id string) (*models.Schedule, error) {
+	schedule, err := s.scheduleRepo.GetScheduleByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrScheduleNotFound
+		}
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// ListSchedules 获取用户的周期任务模板列表
+func (s *ScheduleService) ListSchedules(ctx context.Context, userID string) ([]*models.Schedule, error) {
+	return s.scheduleRepo.GetSchedulesByUserID(ctx, userID)
+}
+
+// GetEnabledSchedules 获取所有启用的周期任务模板
+func (s *ScheduleService) GetEnabledSchedules(ctx context.Context) ([]*models.Schedule, error) {
+	return s.scheduleRepo.GetEnabledSchedules(ctx)
+}
+
+// UpdateSchedule 校验并更新周期任务模板
+func (s *ScheduleService) UpdateSchedule(ctx context.Context, schedule *models.Schedule) error {
+	if err := ValidateSchedule(schedule); err != nil {
+		return err
+	}
+	schedule.UpdatedAt = time.Now()
+	return s.scheduleRepo.UpdateSchedule(ctx, schedule)
+}
+
+// DeleteSchedule 删除用户的周期任务模板，返回false表示模板不存在
+func (s *ScheduleService) DeleteSchedule(ctx context.Context, userID, id string) (bool, error) {
+	return s.scheduleRepo.DeleteSchedule(ctx, id, userID)
+}
+
+// ClaimRun 占用本次触发，同一周期任务在config.ScheduleDuplicateWindow内只会占用成功一次
+func (s *ScheduleService) ClaimRun(ctx context.Context, id string, runAt time.Time) (bool, error) {
+	return s.scheduleRepo.ClaimRun(ctx, id, runAt, runAt.Add(-config.ScheduleDuplicateWindow))
+}
+
+// RecordRunResult 记录本次触发创建的任务或失败原因
+func (s *ScheduleService) RecordRunResult(ctx context.Context, id, taskID string, runErr error) error {
+	errorMsg := ""
+	if runErr != nil {
+		errorMsg = runErr.Error()
+	}
+	return s.scheduleRepo.UpdateLastResult(ctx, id, taskID, errorMsg)
+}
+
+// ValidateSchedule 校验cron表达式、时区和任务模板
+// 模板中的模型参数由接口层按服务商能力描述校验
+func ValidateSchedule(schedule *models.Schedule) error {
+	if schedule.Timezone != "" {
+		if _, err := time.LoadLocation(schedule.Timezone); err != nil {
+			return fmt.Errorf("%w: 无效的时区 %s", ErrInvalidSchedule, schedule.Timezone)
+		}
+	}
+
+	spec, err := cron.ParseStandard(schedule.CronSpec())
+	if err != nil {
+		return fmt.Errorf("%w: 无效的cron表达式: %v", ErrInvalidSchedule, err)
+	}
+
+	// 检查接下来若干次执行的间隔，避免过于频繁地创建任务消耗积分
+	prev := spec.Next(time.Now())
+	for i := 0; i < scheduleIntervalSamples && !prev.IsZero(); i++ {
+		next := spec.Next(prev)
+		if !next.IsZero() && next.Sub(prev) < config.ScheduleMinInterval {
+			return fmt.Errorf("%w: 相邻两次执行的间隔不能小于%s", ErrInvalidSchedule, config.ScheduleMinInterval)
+		}
+		prev = next
+	}
+
+	switch schedule.Type {
+	case models.TaskTypeImage:
+		if schedule.N < 0 || schedule.N > config.MaxImageN {
+			return fmt.Errorf("%w: 单个任务最多生成%d张图片", ErrInvalidSchedule, config.MaxImageN)
+		}
+	case models.TaskTypeVideo, models.TaskTypeText:
+	default:
+		return fmt.Errorf("%w: 不支持的任务类型 %s", ErrInvalidSchedule, schedule.Type)
+	}
+
+	if schedule.Prompt == "" {
+		return fmt.Errorf("%w: 任务模板缺少prompt", ErrInvalidSchedule)
+	}
+	return nil
+}
@@ -2,39 +2,78 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 
 	"volcengine-go-server/config"
 	"volcengine-go-server/internal/models"
 	"volcengine-go-server/internal/repository"
+	"volcengine-go-server/pkg/logger"
 )
 
+// TaskNotifier 任务结束通知接口，由回调投递和流水线子系统实现
+// 任务完成、失败或取消时调用，实现方自行过滤不关心的任务
+type TaskNotifier interface {
+	NotifyTaskFinished(ctx context.Context, task *models.Task) error
+}
+
 // TaskService 统一任务服务 - 业务逻辑层
 type TaskService struct {
-	taskRepo repository.TaskRepository
+	taskRepo  repository.TaskRepository
+	credits   *CreditService
+	notifiers []TaskNotifier // 可选：任务结束时依次通知
+	media     *MediaService  // 可选：设置后图像和视频结果在完成前转存到自有存储
 }
 
 // NewTaskService 创建任务服务
 func NewTaskService(db repository.Database) *TaskService {
 	return &TaskService{
 		taskRepo: db.TaskRepository(),
+		credits:  NewCreditService(db),
 	}
 }
 
 // CreateTask 创建任务
 func (s *TaskService) CreateTask(ctx context.Context, input *models.TaskInput) (*models.Task, error) {
+	task := newTask(input)
+
+	err := s.taskRepo.CreateTask(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+// newTask 根据任务输入构建任务记录，补全各类型参数的默认值
+func newTask(input *models.TaskInput) *models.Task {
 	task := &models.Task{
-		ID:       primitive.NewObjectID().Hex(),
+		ID:       input.ID,
 		UserID:   input.UserID,
 		Type:     input.Type,
 		Prompt:   input.Prompt,
 		Model:    input.Model,
 		Provider: input.Provider,
 		Status:   config.TaskStatusPending,
+		Cost:     input.Cost,
 		Created:  time.Now(),
 		Updated:  time.Now(),
+
+		CallbackURL:    input.CallbackURL,
+		CallbackSecret: input.CallbackSecret,
+		ScheduleID:     input.ScheduleID,
+		BatchID:        input.BatchID,
+		PipelineID:     input.PipelineID,
+		PipelineStep:   input.PipelineStep,
+	}
+	if task.ID == "" {
+		task.ID = primitive.NewObjectID().Hex()
 	}
 
 	// 根据任务类型设置特有字段和默认值
@@ -43,7 +82,19 @@ func (s *TaskService) CreateTask(ctx context.Context, input *models.TaskInput) (
 		task.AspectRatio = input.AspectRatio
 		task.N = input.N
 		if task.N == 0 {
-			task.N = 1
+			task.N = config.DefaultImageN
+		}
+		// 图像编辑任务
+		if input.SourceImageURL != "" {
+			task.SourceImageURL = input.SourceImageURL
+			task.Strength = input.Strength
+			task.Seed = input.Seed
+			if task.Strength == 0 {
+				task.Strength = config.DefaultImageEditStrength
+			}
+			if task.Seed == 0 {
+				task.Seed = config.DefaultImageEditSeed
+			}
 		}
 	case models.TaskTypeVideo:
 		task.Seed = input.Seed
@@ -58,14 +109,59 @@ func (s *TaskService) CreateTask(ctx context.Context, input *models.TaskInput) (
 	case models.TaskTypeText:
 		task.MaxTokens = input.MaxTokens
 		task.Temperature = input.Temperature
+		// 设置默认值
+		if task.MaxTokens == 0 {
+			task.MaxTokens = config.DefaultTextMaxTokens
+		}
+		if task.Temperature == 0 {
+			task.Temperature = config.DefaultTextTemperature
+		}
 	}
 
-	err := s.taskRepo.CreateTask(ctx, task)
+	// 定时任务在到达执行时间前保持scheduled状态
+	if input.RunAt != nil {
+		task.Status = config.TaskStatusScheduled
+		task.RunAt = input.RunAt
+	}
+	task.History = []models.TaskStatusChange{{Status: task.Status, At: task.Created}}
+
+	return task
+}
+
+// AddNotifier 添加任务结束通知器
+func (s *TaskService) AddNotifier(notifier TaskNotifier) {
+	s.notifiers = append(s.notifiers, notifier)
+}
+
+// SetMediaService 设置媒体转存服务
+func (s *TaskService) SetMediaService(media *MediaService) {
+	s.media = media
+}
+
+// notifyTaskFinished 任务进入完成、失败或取消状态后依次通知回调和流水线子系统
+// 通知失败只记录日志，不影响任务结果的保存
+func (s *TaskService) notifyTaskFinished(ctx context.Context, taskID string) {
+	if len(s.notifiers) == 0 {
+		return
+	}
+
+	task, err := s.taskRepo.GetTaskByID(ctx, taskID)
 	if err != nil {
-		return nil, err
+		logger.GetLogger().Warnf("读取任务失败，跳过回调通知: %s, %v", taskID, err)
+		return
 	}
 
-	return task, nil
+	switch task.Status {
+	case config.TaskStatusCompleted, config.TaskStatusFailed, config.TaskStatusCancelled:
+	default:
+		return
+	}
+
+	for _, notifier := range s.notifiers {
+		if err := notifier.NotifyTaskFinished(ctx, task); err != nil {
+			logger.GetLogger().Errorf("任务结束通知失败: %s, %v", taskID, err)
+		}
+	}
 }
 
 // GetTask 获取任务
@@ -73,9 +169,78 @@ func (s *TaskService) GetTask(ctx context.Context, taskID string) (*models.Task,
 	return s.taskRepo.GetTaskByID(ctx, taskID)
 }
 
-// UpdateTaskStatus 更新任务状态
-func (s *TaskService) UpdateTaskStatus(ctx context.Context, taskID, status string) error {
-	return s.taskRepo.UpdateTaskStatus(ctx, taskID, status)
+// RecordProviderSubmission 记录任务提交到服务商后的任务ID、接口标识和原始响应
+func (s *TaskService) RecordProviderSubmission(ctx context.Context, taskID, providerTaskID, reqKey string, response interface{}) error {
+	if err := s.taskRepo.UpdateProviderSubmission(ctx, taskID, providerTaskID, reqKey, capProviderResponse(response), time.Now()); err != nil {
+		return err
+	}
+	return s.taskRepo.UpdateTaskProgress(ctx, taskID, config.TaskProgressSubmitted)
+}
+
+// RecordPollAttempt 记录异步任务的状态检查次数，按检查次数更新进度估算
+func (s *TaskService) RecordPollAttempt(ctx context.Context, taskID string, attempt int) error {
+	return s.taskRepo.UpdateTaskProgress(ctx, taskID, EstimateProgress(attempt))
+}
+
+// RecordProviderResponse 记录服务商最近一次原始响应，completed表示服务商已返回最终结果
+func (s *TaskService) RecordProviderResponse(ctx context.Context, taskID string, response interface{}, completed bool) error {
+	var completedAt *time.Time
+	if completed {
+		now := time.Now()
+		completedAt = &now
+	}
+	return s.taskRepo.UpdateProviderResponse(ctx, taskID, capProviderResponse(response), completedAt)
+}
+
+// RecordServedBy 记录执行任务的服务商和模型，故障转移时记录最近一次尝试的候选
+func (s *TaskService) RecordServedBy(ctx context.Context, taskID, provider, model string) error {
+	return s.taskRepo.UpdateServedBy(ctx, taskID, provider, model)
+}
+
+// capProviderResponse 序列化服务商原始响应，超过上限时截断
+func capProviderResponse(response interface{}) string {
+	data, err := json.Marshal(response)
+	if err != nil {
+		data = []byte(fmt.Sprintf("%v", response))
+	}
+	if len(data) > config.ProviderResponseMaxBytes {
+		// 截断可能切开多字节字符，去掉不完整的部分
+		return strings.ToValidUTF8(string(data[:config.ProviderResponseMaxBytes]), "")
+	}
+	return string(data)
+}
+
+// ActivateScheduledTask 定时任务开始执行时转为等待中状态，非定时任务不受影响
+func (s *TaskService) ActivateScheduledTask(ctx context.Context, taskID string) error {
+	change := &models.TaskStatusChange{From: config.TaskStatusScheduled, Status: config.TaskStatusPending, At: time.Now()}
+	_, err := s.taskRepo.TransitionTaskStatus(ctx, taskID, config.TaskStatusScheduled, change)
+	return err
+}
+
+// StartTask 队列开始执行任务时转为处理中状态，attempt为队列第几次执行，从1开始
+// 重试时再记录一次处理中状态；熔断延迟后重新执行同一次尝试时不重复记录
+func (s *TaskService) StartTask(ctx context.Context, taskID string, attempt int) error {
+	_, err := s.transitionTask(ctx, taskID, config.TaskStatusProcessing, "", func(task *models.Task, change *models.TaskStatusChange) (bool, error) {
+		if task.Status == config.TaskStatusProcessing && task.LastAttempt() == attempt {
+			return true, nil
+		}
+		change.Attempt = attempt
+		return s.taskRepo.TransitionTaskStatus(ctx, taskID, task.Status, change)
+	})
+	return err
+}
+
+// RequeueTask 失败的任务被管理员重新执行时转回等待中状态
+func (s *TaskService) RequeueTask(ctx context.Context, taskID string) error {
+	_, err := s.transitionTask(ctx, taskID, config.TaskStatusPending, "管理员重新执行", func(task *models.Task, change *models.TaskStatusChange) (bool, error) {
+		return s.taskRepo.TransitionTaskStatus(ctx, taskID, task.Status, change)
+	})
+	return err
+}
+
+// RescheduleTask 修改定时任务的计划执行时间，返回false表示任务已不是定时状态
+func (s *TaskService) RescheduleTask(ctx context.Context, taskID string, runAt time.Time) (bool, error) {
+	return s.taskRepo.RescheduleTask(ctx, taskID, runAt)
 }
 
 // UpdateTaskResult 更新任务结果
@@ -86,12 +251,118 @@ func (s *TaskService) UpdateTaskResult(ctx context.Context, taskID, resultURL st
 		return err
 	}
 
-	return s.taskRepo.UpdateTaskResult(ctx, taskID, task, resultURL)
+	// 图像和视频结果先转存，保存的是自有存储的稳定地址
+	var media []models.MediaAsset
+	if s.media != nil && (task.Type == models.TaskTypeImage || task.Type == models.TaskTypeVideo) {
+		media, err = s.rehostResults(ctx, taskID, []string{resultURL})
+		if err != nil {
+			return err
+		}
+		resultURL = media[0].URL
+	}
+
+	_, err = s.transitionTask(ctx, taskID, config.TaskStatusCompleted, "", func(task *models.Task, change *models.TaskStatusChange) (bool, error) {
+		return s.taskRepo.UpdateTaskResult(ctx, task, resultURL, media, change)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.credits.ReclaimTaskRefund(ctx, taskID)
+	s.notifyTaskFinished(ctx, taskID)
+	return nil
 }
 
-// UpdateTaskError 更新任务错误
+// UpdateTaskImageResults 更新图像任务的全部结果
+func (s *TaskService) UpdateTaskImageResults(ctx context.Context, taskID string, imageURLs []string) error {
+	var media []models.MediaAsset
+	if s.media != nil {
+		var err error
+		media, err = s.rehostResults(ctx, taskID, imageURLs)
+		if err != nil {
+			return err
+		}
+		imageURLs = make([]string, len(media))
+		for i, asset := range media {
+			imageURLs[i] = asset.URL
+		}
+	}
+
+	_, err := s.transitionTask(ctx, taskID, config.TaskStatusCompleted, "", func(task *models.Task, change *models.TaskStatusChange) (bool, error) {
+		return s.taskRepo.UpdateTaskImageResults(ctx, taskID, task.Status, imageURLs, media, change)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.credits.ReclaimTaskRefund(ctx, taskID)
+	s.notifyTaskFinished(ctx, taskID)
+	return nil
+}
+
+// rehostResults 转存生成结果，失败时返回错误由队列重试，避免对外暴露会过期的地址
+func (s *TaskService) rehostResults(ctx context.Context, taskID string, sources []string) ([]models.MediaAsset, error) {
+	media, err := s.media.RehostAll(ctx, taskID, sources)
+	if err != nil {
+		logger.GetLogger().Errorf("生成结果转存失败: %s, %v", taskID, err)
+		return nil, fmt.Errorf("生成结果转存失败: %w", err)
+	}
+	return media, nil
+}
+
+// UpdateTaskError 更新任务错误，同时退还任务扣除的积分
 func (s *TaskService) UpdateTaskError(ctx context.Context, taskID, errorMsg string) error {
-	return s.taskRepo.UpdateTaskError(ctx, taskID, errorMsg)
+	return s.updateTaskError(ctx, taskID, config.TaskErrorInternal, errorMsg)
+}
+
+// FailTask 按错误类型记录错误码并标记任务失败，同时退还任务扣除的积分
+func (s *TaskService) FailTask(ctx context.Context, taskID string, err error) error {
+	return s.updateTaskError(ctx, taskID, ErrorCode(err), err.Error())
+}
+
+// updateTaskError 标记任务失败，退还积分并通知任务结束
+func (s *TaskService) updateTaskError(ctx context.Context, taskID, errorCode, errorMsg string) error {
+	_, err := s.transitionTask(ctx, taskID, config.TaskStatusFailed, errorMsg, func(task *models.Task, change *models.TaskStatusChange) (bool, error) {
+		return s.taskRepo.UpdateTaskError(ctx, taskID, task.Status, errorCode, errorMsg, change)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.credits.RefundTask(ctx, taskID)
+	s.notifyTaskFinished(ctx, taskID)
+	return nil
+}
+
+// CancelTask 取消任务，仅定时、等待中或处理中的任务可以取消
+// 返回false表示任务已结束，无法取消
+func (s *TaskService) CancelTask(ctx context.Context, taskID string) (bool, error) {
+	_, err := s.transitionTask(ctx, taskID, config.TaskStatusCancelled, "", func(task *models.Task, change *models.TaskStatusChange) (bool, error) {
+		return s.taskRepo.CancelTask(ctx, taskID, task.Status, change)
+	})
+	if err != nil {
+		var transitionErr *TaskTransitionError
+		if errors.As(err, &transitionErr) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	s.credits.RefundTask(ctx, taskID)
+	s.notifyTaskFinished(ctx, taskID)
+	return true, nil
+}
+
+// IsTaskCancelled 检查任务是否已被取消或删除
+func (s *TaskService) IsTaskCancelled(ctx context.Context, taskID string) (bool, error) {
+	task, err := s.taskRepo.GetTaskByID(ctx, taskID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return true, nil
+		}
+		return false, err
+	}
+	return task.Status == config.TaskStatusCancelled, nil
 }
 
 // GetUserTasks 获取用户任务列表
@@ -99,7 +370,16 @@ func (s *TaskService) GetUserTasks(ctx context.Context, userID string, taskType
 	return s.taskRepo.GetTasksByUserID(ctx, userID, taskType, limit, offset)
 }
 
-// DeleteTask 删除任务
+// DeleteTask 删除任务，未结束的任务先退还积分
 func (s *TaskService) DeleteTask(ctx context.Context, taskID string) error {
+	task, err := s.taskRepo.GetTaskByID(ctx, taskID)
+	if err != nil {
+		return err
+	}
+	switch task.Status {
+	case config.TaskStatusScheduled, config.TaskStatusPending, config.TaskStatusProcessing:
+		s.credits.RefundTask(ctx, taskID)
+	}
+
 	return s.taskRepo.DeleteTask(ctx, taskID)
 }
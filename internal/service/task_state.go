@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"volcengine-go-server/config"
+	"volcengine-go-server/internal/models"
+)
+
+// ErrTaskStateConflict 任务状态被并发修改，多次比较交换均未成功
+var ErrTaskStateConflict = errors.New("任务状态并发修改冲突")
+
+// taskTransitions 任务状态机允许的状态转换
+var taskTransitions = map[string][]string{
+	config.TaskStatusScheduled: {config.TaskStatusPending, config.TaskStatusFailed, config.TaskStatusCancelled},
+	config.TaskStatusPending:   {config.TaskStatusProcessing, config.TaskStatusFailed, config.TaskStatusCancelled},
+	// 处理中的任务被队列重试时再记录一次processing，便于查看每次执行
+	config.TaskStatusProcessing: {config.TaskStatusProcessing, config.TaskStatusCompleted, config.TaskStatusFailed, config.TaskStatusCancelled},
+	// 管理员重新执行已归档的失败任务
+	config.TaskStatusFailed: {config.TaskStatusPending},
+}
+
+// CanTransition 检查任务状态是否允许从from变更为to
+func CanTransition(from, to string) bool {
+	for _, status := range taskTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TaskTransitionError 任务当前状态不允许变更为目标状态，如已取消的任务回写结果
+type TaskTransitionError struct {
+	TaskID string
+	From   string
+	To     string
+}
+
+func (e *TaskTransitionError) Error() string {
+	return fmt.Sprintf("任务状态不允许从%s变更为%s: %s", e.From, e.To, e.TaskID)
+}
+
+// Retryable 状态转换错误重试也不会成功
+func (e *TaskTransitionError) Retryable() bool {
+	return false
+}
+
+// transitionFunc 以比较交换的方式执行状态更新，返回任务是否仍处于读取时的状态并完成了更新
+type transitionFunc func(task *models.Task, change *models.TaskStatusChange) (bool, error)
+
+// transitionTask 按状态机校验并更新任务状态，状态在读取后被并发修改时重新读取再尝试
+func (s *TaskService) transitionTask(ctx context.Context, taskID, to, message string, apply transitionFunc) (*models.Task, error) {
+	for i := 0; i < config.TaskTransitionMaxAttempts; i++ {
+		task, err := s.taskRepo.GetTaskByID(ctx, taskID)
+		if err != nil {
+			return nil, err
+		}
+		if !CanTransition(task.Status, to) {
+			return nil, &TaskTransitionError{TaskID: taskID, From: task.Status, To: to}
+		}
+
+		change := &models.TaskStatusChange{
+			From:    task.Status,
+			Status:  to,
+			Attempt: task.LastAttempt(),
+			Message: message,
+			At:      time.Now(),
+		}
+		updated, err := apply(task, change)
+		if err != nil {
+			return nil, err
+		}
+		if updated {
+			task.Status = to
+			task.History = append(task.History, *change)
+			return task, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrTaskStateConflict, taskID)
+}
+
+// EstimateProgress 按异步任务的状态检查次数估算进度，完成前不超过config.TaskProgressMaxEstimate
+func EstimateProgress(attempt int) int {
+	span := config.TaskProgressMaxEstimate - config.TaskProgressSubmitted
+	progress := config.TaskProgressSubmitted + span*attempt/config.TaskProgressExpectedPolls
+	if progress > config.TaskProgressMaxEstimate {
+		return config.TaskProgressMaxEstimate
+	}
+	return progress
+}
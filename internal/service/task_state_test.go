@@ -0,0 +1,43 @@
+package service
+
+import (
+	"testing"
+
+	"volcengine-go-server/config"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		allowed  bool
+	}{
+		{config.TaskStatusPending, config.TaskStatusProcessing, true},
+		{config.TaskStatusProcessing, config.TaskStatusProcessing, true},
+		{config.TaskStatusProcessing, config.TaskStatusCompleted, true},
+		{config.TaskStatusScheduled, config.TaskStatusCancelled, true},
+		{config.TaskStatusPending, config.TaskStatusCompleted, false},
+		{config.TaskStatusFailed, config.TaskStatusCompleted, false},
+		{config.TaskStatusCancelled, config.TaskStatusFailed, false},
+		{config.TaskStatusCompleted, config.TaskStatusProcessing, false},
+	}
+
+	for _, tt := range tests {
+		if got := CanTransition(tt.from, tt.to); got != tt.allowed {
+			t.Errorf("CanTransition(%s, %s) = %v, 期望 %v", tt.from, tt.to, got, tt.allowed)
+		}
+	}
+}
+
+func TestEstimateProgress(t *testing.T) {
+	if got := EstimateProgress(0); got != config.TaskProgressSubmitted {
+		t.Errorf("EstimateProgress(0) = %d, 期望 %d", got, config.TaskProgressSubmitted)
+	}
+	previous := 0
+	for attempt := 0; attempt < config.StatusCheckMaxAttempts; attempt++ {
+		got := EstimateProgress(attempt)
+		if got < previous || got > config.TaskProgressMaxEstimate {
+			t.Fatalf("EstimateProgress(%d) = %d, 应单调递增且不超过%d", attempt, got, config.TaskProgressMaxEstimate)
+		}
+		previous = got
+	}
+}
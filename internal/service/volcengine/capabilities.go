@@ -0,0 +1,114 @@
+package volcengine
+
+import (
+	"volcengine-go-server/config"
+	"volcengine-go-server/internal/models"
+)
+
+// 请求参数限制
+const (
+	jimengVideoMaxPromptLength = 150       // 即梦AI视频prompt最大字符数
+	visualMaxSeed              = 1<<32 - 1 // 视觉接口随机种子最大值，-1表示随机
+	doubaoTextMaxTokens        = 12288     // 豆包文本单次最大输出token数
+)
+
+// Capabilities 火山引擎能力描述，API服务器不创建分发器，直接使用该描述构建模型目录和校验请求
+func Capabilities() models.ProviderCapability {
+	videoAspectRatios := []string{
+		config.VideoAspectRatio16x9, config.VideoAspectRatio9x16, config.VideoAspectRatio1x1,
+		config.VideoAspectRatio4x3, config.VideoAspectRatio3x4, config.VideoAspectRatio21x9,
+	}
+	videoSeed := models.ParamRule{
+		Field:   "seed",
+		Type:    models.ParamTypeInteger,
+		Default: config.DefaultVideoSeed,
+		Range:   &models.ParamRange{Min: -1, Max: visualMaxSeed},
+	}
+
+	return models.ProviderCapability{
+		Provider: ProviderName,
+		Models: []models.ModelCapability{
+			{
+				Model:    config.VolcengineImageModel,
+				TaskType: models.TaskTypeImage,
+				Name:     "豆包文生图",
+				Params: []models.ParamRule{
+					{Field: "prompt", Type: models.ParamTypeString, Required: true},
+					{Field: "aspect_ratio", Type: models.ParamTypeString, Default: "1:1", Enum: []string{"1:1", "3:4", "4:3", "16:9", "9:16", "2:3", "3:2", "21:9"}},
+					imageCountParam(),
+				},
+			},
+			{
+				Model:    config.VolcengineJimengImageModel,
+				TaskType: models.TaskTypeImage,
+				Name:     "即梦AI文生图",
+				Params: []models.ParamRule{
+					{Field: "prompt", Type: models.ParamTypeString, Required: true},
+					{Field: "aspect_ratio", Type: models.ParamTypeString, Default: "1:1", Enum: []string{"1:1", "4:3", "3:4", "3:2", "2:3", "16:9", "9:16", "21:9"}},
+					imageCountParam(),
+				},
+			},
+			{
+				Model:    config.VolcengineImageEditModel,
+				TaskType: models.TaskTypeImage,
+				Name:     "SeedEdit指令编辑",
+				Params: []models.ParamRule{
+					// prompt为编辑指令，如"把背景换成海边"
+					{Field: "prompt", Type: models.ParamTypeString, Required: true},
+					{Field: "source_image_url", Type: models.ParamTypeImage, Required: true},
+					{Field: "strength", Type: models.ParamTypeNumber, Default: config.DefaultImageEditStrength, Range: &models.ParamRange{Min: 0, Max: 1}},
+					{Field: "seed", Type: models.ParamTypeInteger, Default: config.DefaultImageEditSeed, Range: &models.ParamRange{Min: -1, Max: visualMaxSeed}},
+					// 每次编辑只产出一张图片
+					{Field: "n", Type: models.ParamTypeInteger, Default: 1, Range: &models.ParamRange{Min: 1, Max: 1}},
+				},
+			},
+			{
+				Model:    config.VolcengineTextModel,
+				TaskType: models.TaskTypeText,
+				Name:     "豆包文本生成",
+				Params: []models.ParamRule{
+					{Field: "prompt", Type: models.ParamTypeString, Required: true},
+					{Field: "max_tokens", Type: models.ParamTypeInteger, Default: config.DefaultTextMaxTokens, Range: &models.ParamRange{Min: 1, Max: doubaoTextMaxTokens}},
+					{Field: "temperature", Type: models.ParamTypeNumber, Default: config.DefaultTextTemperature, Range: &models.ParamRange{Min: 0, Max: 2}},
+				},
+			},
+			{
+				Model:    config.VolcengineJimengVideoModel,
+				TaskType: models.TaskTypeVideo,
+				Name:     "即梦AI文生视频",
+				Params: []models.ParamRule{
+					{Field: "prompt", Type: models.ParamTypeString, Required: true, MaxLength: jimengVideoMaxPromptLength},
+					{Field: "aspect_ratio", Type: models.ParamTypeString, Default: config.DefaultVideoAspectRatio, Enum: videoAspectRatios},
+					videoSeed,
+				},
+			},
+			{
+				Model:    config.VolcengineJimengI2VModel,
+				TaskType: models.TaskTypeVideo,
+				Name:     "即梦AI图生视频",
+				Params: []models.ParamRule{
+					{Field: "image_urls", Type: models.ParamTypeURLList, Required: true, MinItems: 1},
+					{Field: "prompt", Type: models.ParamTypeString, MaxLength: jimengVideoMaxPromptLength},
+					// 未填写时按第一张图片的尺寸检测
+					{Field: "aspect_ratio", Type: models.ParamTypeString, Enum: append(videoAspectRatios, "9:21")},
+					videoSeed,
+				},
+			},
+		},
+	}
+}
+
+// imageCountParam 图像生成数量参数，各图像模型一致
+func imageCountParam() models.ParamRule {
+	return models.ParamRule{
+		Field:   "n",
+		Type:    models.ParamTypeInteger,
+		Default: config.DefaultImageN,
+		Range:   &models.ParamRange{Min: 1, Max: config.MaxImageN},
+	}
+}
+
+// GetCapabilities 获取火山引擎能力描述
+func (p *Provider) GetCapabilities() models.ProviderCapability {
+	return Capabilities()
+}
@@ -0,0 +1,143 @@
+package volcengine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/volcengine/volcengine-go-sdk/service/arkruntime/model"
+
+	"volcengine-go-server/config"
+	"volcengine-go-server/internal/service"
+)
+
+// 即梦AI视觉接口的业务错误码
+const (
+	visualCodeSuccess           = 10000
+	visualCodeInputImageRisk    = 50411 // 输入图片审核未通过
+	visualCodeInputTextRisk     = 50412 // 输入文本审核未通过
+	visualCodeInputTextBlocked  = 50413 // 输入文本包含敏感词、版权词等
+	visualCodeOutputImageRisk   = 50511 // 输出图片审核未通过
+	visualCodeOutputTextRisk    = 50512 // 输出文本审核未通过
+	visualCodeClientErrorMin    = 50400
+	visualCodeClientErrorMax    = 50499
+	visualCodeRateLimited       = 50429 // 调用频率超过限制
+	visualCodeConcurrencyCapped = 50430 // 并发数超过限制
+	visualCodeServerErrorMin    = 50500
+	visualCodeServerErrorMax    = 50599
+)
+
+// invalidInputError 任务参数无效，重试不会成功
+func invalidInputError(format string, args ...interface{}) error {
+	return service.NewProviderError(config.TaskErrorInvalidInput, fmt.Sprintf(format, args...), nil)
+}
+
+// classifyArkError 按火山方舟接口返回的HTTP状态码和错误码对错误分类
+func classifyArkError(message string, err error) error {
+	if errors.Is(err, context.Canceled) {
+		return fmt.Errorf("%s: %w", message, err)
+	}
+
+	var apiErr *model.APIError
+	var reqErr *model.RequestError
+	switch {
+	case errors.As(err, &apiErr):
+		return service.NewProviderError(arkErrorCode(apiErr.HTTPStatusCode, apiErr.Code), message, err)
+	case errors.As(err, &reqErr):
+		return service.NewProviderError(arkErrorCode(reqErr.HTTPStatusCode, ""), message, err)
+	default:
+		// 网络错误或超时
+		return service.NewProviderError(config.TaskErrorTransient, message, err)
+	}
+}
+
+// arkErrorCode 火山方舟错误对应的任务错误码
+func arkErrorCode(status int, code string) string {
+	switch {
+	case strings.Contains(code, "SensitiveContentDetected"):
+		return config.TaskErrorContentPolicy
+	case code == "QuotaExceeded", code == "AccountOverdueError", strings.HasPrefix(code, "SetLimitExceeded"):
+		return config.TaskErrorQuotaExceeded
+	case code == "ModelNotOpen", status == http.StatusUnauthorized, status == http.StatusForbidden:
+		return config.TaskErrorAuthFailed
+	case status == http.StatusTooManyRequests, status >= http.StatusInternalServerError, status == 0:
+		return config.TaskErrorTransient
+	case status >= http.StatusBadRequest:
+		return config.TaskErrorInvalidInput
+	default:
+		return config.TaskErrorTransient
+	}
+}
+
+// checkVisualResponse 检查即梦AI视觉接口的调用结果，失败时返回分类后的错误
+// 服务商返回错误状态码时SDK不返回error，错误信息只体现在状态码和响应的code中
+func checkVisualResponse(message string, status int, resp map[string]interface{}, err error) error {
+	if err != nil && status == 0 {
+		if errors.Is(err, context.Canceled) {
+			return fmt.Errorf("%s: %w", message, err)
+		}
+		return service.NewProviderError(config.TaskErrorTransient, message, err)
+	}
+
+	code, hasCode := resp["code"].(float64)
+	if err == nil && status < http.StatusBadRequest && (!hasCode || int(code) == visualCodeSuccess) {
+		return nil
+	}
+
+	if err == nil {
+		err = fmt.Errorf("status=%d, code=%d, message=%v", status, int(code), visualErrorMessage(resp))
+	}
+	return service.NewProviderError(visualErrorCode(status, int(code), visualMetadataCode(resp)), message, err)
+}
+
+// visualErrorCode 即梦AI错误对应的任务错误码
+func visualErrorCode(status, code int, metadataCode string) string {
+	switch {
+	case code == visualCodeInputImageRisk, code == visualCodeInputTextRisk, code == visualCodeInputTextBlocked,
+		code == visualCodeOutputImageRisk, code == visualCodeOutputTextRisk:
+		return config.TaskErrorContentPolicy
+	case code == visualCodeRateLimited, code == visualCodeConcurrencyCapped:
+		return config.TaskErrorTransient
+	case strings.Contains(metadataCode, "Overdue"), strings.Contains(metadataCode, "Quota"):
+		return config.TaskErrorQuotaExceeded
+	case status == http.StatusUnauthorized, status == http.StatusForbidden,
+		strings.Contains(metadataCode, "AccessKey"), strings.Contains(metadataCode, "Signature"), metadataCode == "AccessDenied":
+		return config.TaskErrorAuthFailed
+	case code >= visualCodeServerErrorMin && code <= visualCodeServerErrorMax:
+		return config.TaskErrorTransient
+	case code >= visualCodeClientErrorMin && code <= visualCodeClientErrorMax:
+		return config.TaskErrorInvalidInput
+	case status == http.StatusTooManyRequests, status >= http.StatusInternalServerError:
+		return config.TaskErrorTransient
+	case status >= http.StatusBadRequest:
+		return config.TaskErrorInvalidInput
+	default:
+		return config.TaskErrorTransient
+	}
+}
+
+// visualErrorMessage 获取即梦AI响应中的错误信息
+func visualErrorMessage(resp map[string]interface{}) interface{} {
+	if message, ok := resp["message"]; ok {
+		return message
+	}
+	if metadata, ok := resp["ResponseMetadata"].(map[string]interface{}); ok {
+		if errInfo, ok := metadata["Error"].(map[string]interface{}); ok {
+			return errInfo["Message"]
+		}
+	}
+	return nil
+}
+
+// visualMetadataCode 获取即梦AI网关返回的错误码，如鉴权失败
+func visualMetadataCode(resp map[string]interface{}) string {
+	if metadata, ok := resp["ResponseMetadata"].(map[string]interface{}); ok {
+		if errInfo, ok := metadata["Error"].(map[string]interface{}); ok {
+			code, _ := errInfo["Code"].(string)
+			return code
+		}
+	}
+	return ""
+}
@@ -0,0 +1,61 @@
+package volcengine
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/volcengine/volcengine-go-sdk/service/arkruntime/model"
+
+	"volcengine-go-server/config"
+	"volcengine-go-server/internal/service"
+)
+
+func TestClassifyArkError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{"敏感内容", &model.APIError{Code: "OutputImageSensitiveContentDetected", HTTPStatusCode: 400}, config.TaskErrorContentPolicy},
+		{"参数错误", &model.APIError{Code: "InvalidParameter", HTTPStatusCode: 400}, config.TaskErrorInvalidInput},
+		{"鉴权失败", &model.APIError{Code: "AuthenticationError", HTTPStatusCode: 401}, config.TaskErrorAuthFailed},
+		{"额度用尽", &model.APIError{Code: "QuotaExceeded", HTTPStatusCode: 429}, config.TaskErrorQuotaExceeded},
+		{"限流", &model.APIError{Code: "RateLimitExceeded", HTTPStatusCode: 429}, config.TaskErrorTransient},
+		{"服务端错误", &model.RequestError{HTTPStatusCode: 502}, config.TaskErrorTransient},
+		{"网络错误", errors.New("connection reset by peer"), config.TaskErrorTransient},
+	}
+
+	for _, tt := range tests {
+		if code := service.ErrorCode(classifyArkError("图像生成失败", tt.err)); code != tt.expected {
+			t.Errorf("%s: 错误码 = %s, 期望 %s", tt.name, code, tt.expected)
+		}
+	}
+}
+
+func TestCheckVisualResponse(t *testing.T) {
+	success := map[string]interface{}{"code": float64(visualCodeSuccess)}
+	if err := checkVisualResponse("提交即梦AI任务失败", 200, success, nil); err != nil {
+		t.Fatalf("成功响应返回错误: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		status   int
+		resp     map[string]interface{}
+		expected string
+	}{
+		{"输入文本审核未通过", 400, map[string]interface{}{"code": float64(visualCodeInputTextRisk)}, config.TaskErrorContentPolicy},
+		{"并发超限", 429, map[string]interface{}{"code": float64(visualCodeConcurrencyCapped)}, config.TaskErrorTransient},
+		{"内部错误", 500, map[string]interface{}{"code": float64(50500)}, config.TaskErrorTransient},
+		{"签名错误", 401, map[string]interface{}{"ResponseMetadata": map[string]interface{}{
+			"Error": map[string]interface{}{"Code": "SignatureDoesNotMatch"},
+		}}, config.TaskErrorAuthFailed},
+	}
+
+	for _, tt := range tests {
+		err := checkVisualResponse("提交即梦AI任务失败", tt.status, tt.resp, nil)
+		if code := service.ErrorCode(err); code != tt.expected {
+			t.Errorf("%s: 错误码 = %s, 期望 %s", tt.name, code, tt.expected)
+		}
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/volcengine/volcengine-go-sdk/service/arkruntime/model"
 
 	"volcengine-go-server/config"
+	"volcengine-go-server/internal/service"
 )
 
 // GenerateImageByDoubao 豆包图像生成具体实现
@@ -19,9 +20,8 @@ func (s *VolcengineService) GenerateImageByDoubao(ctx context.Context, taskID st
 	// 从input参数中获取任务信息
 	prompt, ok := input["prompt"].(string)
 	if !ok {
-		err := fmt.Errorf("无效的prompt参数")
+		err := invalidInputError("无效的prompt参数")
 		s.logger.Errorf("获取任务输入失败: %v", err)
-		s.taskService.UpdateTaskError(ctx, taskID, err.Error())
 		return err
 	}
 
@@ -30,38 +30,37 @@ func (s *VolcengineService) GenerateImageByDoubao(ctx context.Context, taskID st
 		aspectRatio = "1:1" // 默认比例
 	}
 
+	n := getImageCount(input)
+
 	// 构建豆包图像生成请求参数
 	request := &VolcengineImageRequest{
 		Prompt: prompt,
 		Model:  config.VolcengineImageModel,
 		Size:   s.parseOptimalSizeString(aspectRatio),
-		N:      1, // 生成1张图片
+		N:      n,
 	}
 
-	// 调用豆包图像生成
-	result, err := s.generateImage(ctx, request)
+	// 豆包图像接口单次只返回1张图片，按N并发调用
+	imageURLs, err := s.collectImages(ctx, n, func(ctx context.Context) ([]string, error) {
+		result, err := s.generateImage(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		urls := make([]string, 0, len(result.Data))
+		for _, data := range result.Data {
+			urls = append(urls, data.URL)
+		}
+		return urls, nil
+	})
 	if err != nil {
 		s.logger.Errorf("豆包图像生成失败: %v", err)
-		s.taskService.UpdateTaskError(ctx, taskID, err.Error())
 		return err
 	}
 
-	s.logger.Infof("豆包图像生成成功: %s (比例: %s)", taskID, aspectRatio)
-
-	// 检查是否有生成的图像
-	if len(result.Data) == 0 {
-		errorMsg := "未生成任何图像"
-		s.logger.Errorf("图像生成失败: %s", errorMsg)
-		s.taskService.UpdateTaskError(ctx, taskID, errorMsg)
-		return errors.New(errorMsg)
-	}
-
-	// 获取第一张图片的URL
-	imageURL := result.Data[0].URL
-	s.logger.Infof("豆包图像生成任务完成: %s, 图像URL: %s", taskID, imageURL)
+	s.logger.Infof("豆包图像生成任务完成: %s (比例: %s), 图像数量: %d/%d", taskID, aspectRatio, len(imageURLs), n)
 
 	// 更新数据库中的任务状态
-	if err := s.taskService.UpdateTaskResult(ctx, taskID, imageURL); err != nil {
+	if err := s.taskService.UpdateTaskImageResults(ctx, taskID, imageURLs); err != nil {
 		s.logger.Errorf("更新任务状态失败: %v", err)
 		return err
 	}
@@ -77,9 +76,8 @@ func (s *VolcengineService) GenerateImageByJimeng(ctx context.Context, taskID st
 	// 从input参数中获取任务信息
 	prompt, ok := input["prompt"].(string)
 	if !ok {
-		err := fmt.Errorf("无效的prompt参数")
+		err := invalidInputError("无效的prompt参数")
 		s.logger.Errorf("获取任务输入失败: %v", err)
-		s.taskService.UpdateTaskError(ctx, taskID, err.Error())
 		return err
 	}
 
@@ -100,22 +98,25 @@ func (s *VolcengineService) GenerateImageByJimeng(ctx context.Context, taskID st
 		UseSr:     true,            // 开启超分
 	}
 
-	// 调用即梦AI图像生成
-	result, err := s.generateImageByJimeng(ctx, request)
+	n := getImageCount(input)
+
+	// 调用即梦AI图像生成，按N并发提交
+	imageURLs, err := s.collectImages(ctx, n, func(ctx context.Context) ([]string, error) {
+		result, err := s.generateImageByJimeng(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		return result.ImageURLs, nil
+	})
 	if err != nil {
 		s.logger.Errorf("即梦AI图像生成失败: %v", err)
-		s.taskService.UpdateTaskError(ctx, taskID, err.Error())
 		return err
 	}
 
-	s.logger.Infof("即梦AI图像生成成功: %s", taskID)
-
-	// 获取图片URL
-	imageURL := result.ImageURL
-	s.logger.Infof("即梦AI图像生成任务完成: %s, 图像URL: %s", taskID, imageURL)
+	s.logger.Infof("即梦AI图像生成任务完成: %s, 图像数量: %d/%d", taskID, len(imageURLs), n)
 
 	// 更新数据库中的任务状态
-	if err := s.taskService.UpdateTaskResult(ctx, taskID, imageURL); err != nil {
+	if err := s.taskService.UpdateTaskImageResults(ctx, taskID, imageURLs); err != nil {
 		s.logger.Errorf("更新任务状态失败: %v", err)
 		return err
 	}
@@ -149,6 +150,10 @@ func (s *VolcengineService) generateImage(ctx context.Context, request *Volcengi
 		Watermark: &watermark,
 	}
 
+	if err := s.acquire(ctx, modelID); err != nil {
+		return nil, err
+	}
+
 	// 记录详细的API调用信息
 	s.logger.WithFields(logrus.Fields{
 		"api_endpoint": "GenerateImages",
@@ -162,6 +167,7 @@ func (s *VolcengineService) generateImage(ctx context.Context, request *Volcengi
 	startTime := time.Now()
 	imagesResponse, err := s.client.GenerateImages(ctx, generateReq)
 	duration := time.Since(startTime)
+	s.recordArkResult(ctx, modelID, err)
 
 	if err != nil {
 		s.logger.WithFields(logrus.Fields{
@@ -169,7 +175,7 @@ func (s *VolcengineService) generateImage(ctx context.Context, request *Volcengi
 			"duration_ms":  duration.Milliseconds(),
 			"error":        err.Error(),
 		}).Error("火山方舟API调用失败")
-		return nil, fmt.Errorf("图像生成失败: %v", err)
+		return nil, classifyArkError("图像生成失败", err)
 	}
 
 	// 记录成功的API调用
@@ -211,6 +217,10 @@ func (s *VolcengineService) generateImageByJimeng(ctx context.Context, request *
 		"return_url":  true,                    // 返回图片链接
 	}
 
+	if err := s.acquire(ctx, config.VolcengineJimengImageModel); err != nil {
+		return nil, err
+	}
+
 	// 记录详细的API调用信息
 	s.logger.WithFields(logrus.Fields{
 		"api_endpoint": "CVProcess",
@@ -227,15 +237,16 @@ func (s *VolcengineService) generateImageByJimeng(ctx context.Context, request *
 	startTime := time.Now()
 	resp, status, err := s.visualClient.CVProcess(taskParams)
 	duration := time.Since(startTime)
+	s.recordVisualResult(ctx, config.VolcengineJimengImageModel, status, resp, err)
 
-	if err != nil {
+	if err := checkVisualResponse("提交即梦AI任务失败", status, resp, err); err != nil {
 		s.logger.WithFields(logrus.Fields{
 			"api_endpoint": "CVProcess",
 			"duration_ms":  duration.Milliseconds(),
 			"status_code":  status,
 			"error":        err.Error(),
 		}).Error("即梦AI API调用失败")
-		return nil, fmt.Errorf("提交即梦AI任务失败: %v", err)
+		return nil, err
 	}
 
 	// 记录成功的API调用
@@ -275,24 +286,32 @@ func (s *VolcengineService) parseJimengResponse(resp map[string]interface{}) (*J
 
 	// 优先尝试解析image_urls
 	if imageUrls, exists := dataMap["image_urls"]; exists {
-		if urlArray, ok := imageUrls.([]interface{}); ok && len(urlArray) > 0 {
-			if imageUrl, ok := urlArray[0].(string); ok && imageUrl != "" {
-				s.logger.Infof("成功解析图片URL: %s", imageUrl)
-				return &JimengImageResult{
-					ImageURL: imageUrl,
-				}, nil
+		if urlArray, ok := imageUrls.([]interface{}); ok {
+			result := &JimengImageResult{}
+			for _, item := range urlArray {
+				if imageUrl, ok := item.(string); ok && imageUrl != "" {
+					result.ImageURLs = append(result.ImageURLs, imageUrl)
+				}
+			}
+			if len(result.ImageURLs) > 0 {
+				s.logger.Infof("成功解析图片URL: %d 张", len(result.ImageURLs))
+				return result, nil
 			}
 		}
 	}
 
 	// 如果没有image_urls，尝试解析binary_data_base64
 	if binaryData, exists := dataMap["binary_data_base64"]; exists {
-		if base64Array, ok := binaryData.([]interface{}); ok && len(base64Array) > 0 {
-			if imageBase64, ok := base64Array[0].(string); ok && imageBase64 != "" {
-				s.logger.Infof("成功解析图片Base64数据，长度: %d", len(imageBase64))
-				return &JimengImageResult{
-					ImageURL: "data:image/jpeg;base64," + imageBase64,
-				}, nil
+		if base64Array, ok := binaryData.([]interface{}); ok {
+			result := &JimengImageResult{}
+			for _, item := range base64Array {
+				if imageBase64, ok := item.(string); ok && imageBase64 != "" {
+					result.ImageURLs = append(result.ImageURLs, "data:image/jpeg;base64,"+imageBase64)
+				}
+			}
+			if len(result.ImageURLs) > 0 {
+				s.logger.Infof("成功解析图片Base64数据: %d 张", len(result.ImageURLs))
+				return result, nil
 			}
 		}
 	}
@@ -305,3 +324,56 @@ func (s *VolcengineService) parseJimengResponse(resp map[string]interface{}) (*J
 	s.logger.Warnf("响应中未找到有效的图片数据，可用字段: %v", availableKeys)
 	return nil, fmt.Errorf("响应中未找到有效的图片数据")
 }
+
+// collectImages 并发调用单次生成接口，直到凑齐n张图片
+// 任一调用失败导致图片数量不足时返回错误，不保存部分结果，避免按n张计费却只交付部分图片
+func (s *VolcengineService) collectImages(ctx context.Context, n int, generate func(ctx context.Context) ([]string, error)) ([]string, error) {
+	type batchResult struct {
+		urls []string
+		err  error
+	}
+
+	results := make(chan batchResult, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			urls, err := generate(ctx)
+			results <- batchResult{urls: urls, err: err}
+		}()
+	}
+
+	var imageURLs []string
+	var lastErr error
+	for i := 0; i < n; i++ {
+		result := <-results
+		if result.err != nil {
+			lastErr = result.err
+			s.logger.Warnf("第%d次图像生成调用失败: %v", i+1, result.err)
+			continue
+		}
+		imageURLs = append(imageURLs, result.urls...)
+	}
+
+	if len(imageURLs) == 0 {
+		if lastErr != nil {
+			return nil, lastErr
+		}
+		return nil, errors.New("未生成任何图像")
+	}
+
+	if len(imageURLs) < n {
+		return nil, service.NewIncompleteImagesError(n, len(imageURLs), lastErr)
+	}
+	return imageURLs[:n], nil
+}
+
+// getImageCount 获取图像生成数量，限制在[1, MaxImageN]范围内
+func getImageCount(input map[string]interface{}) int {
+	n := getIntFromInput(input, "n", config.DefaultImageN)
+	if n < 1 {
+		return config.DefaultImageN
+	}
+	if n > config.MaxImageN {
+		return config.MaxImageN
+	}
+	return n
+}
@@ -0,0 +1,112 @@
+package volcengine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"volcengine-go-server/config"
+)
+
+// EditImageBySeedEdit SeedEdit指令编辑具体实现，按编辑指令修改原图
+func (s *VolcengineService) EditImageBySeedEdit(ctx context.Context, taskID string, input map[string]interface{}) error {
+	s.logger.Infof("SeedEdit图像编辑开始: taskID=%s", taskID)
+
+	// 从input参数中获取编辑指令和原图
+	prompt, ok := input["prompt"].(string)
+	if !ok || prompt == "" {
+		err := invalidInputError("无效的prompt参数")
+		s.logger.Errorf("获取任务输入失败: %v", err)
+		return err
+	}
+
+	sourceImageURL, _ := input["source_image_url"].(string)
+	if sourceImageURL == "" {
+		err := invalidInputError("缺少source_image_url参数")
+		s.logger.Errorf("获取任务输入失败: %v", err)
+		return err
+	}
+
+	request := &SeedEditImageRequest{
+		ImageURL: sourceImageURL,
+		Prompt:   prompt,
+		Scale:    getFloatFromInput(input, "strength", config.DefaultImageEditStrength),
+		Seed:     getIntFromInput(input, "seed", config.DefaultImageEditSeed),
+	}
+
+	result, err := s.editImageBySeedEdit(ctx, request)
+	if err != nil {
+		s.logger.Errorf("SeedEdit图像编辑失败: %v", err)
+		return err
+	}
+
+	s.logger.Infof("SeedEdit图像编辑任务完成: %s (强度: %.2f, 种子: %d)", taskID, request.Scale, request.Seed)
+
+	// 编辑结果只有一张图片，与其他单结果任务一样通过UpdateTaskResult转存并完成任务
+	if err := s.taskService.UpdateTaskResult(ctx, taskID, result.ImageURLs[0]); err != nil {
+		s.logger.Errorf("更新任务状态失败: %v", err)
+		return err
+	}
+
+	s.logger.Infof("SeedEdit任务状态已更新为完成: %s", taskID)
+	return nil
+}
+
+// editImageBySeedEdit 调用SeedEdit图像编辑 - 内部方法
+func (s *VolcengineService) editImageBySeedEdit(ctx context.Context, request *SeedEditImageRequest) (*JimengImageResult, error) {
+	s.logger.Infof("开始调用SeedEdit图像编辑API: prompt=%s", request.Prompt)
+
+	taskParams := map[string]interface{}{
+		"req_key":    config.VolcengineImageEditModel,
+		"image_urls": []string{request.ImageURL},
+		"prompt":     request.Prompt,
+		"scale":      request.Scale,
+		"seed":       request.Seed,
+		"return_url": true, // 返回图片链接
+	}
+
+	if err := s.acquire(ctx, config.VolcengineImageEditModel); err != nil {
+		return nil, err
+	}
+
+	// 记录详细的API调用信息
+	s.logger.WithFields(logrus.Fields{
+		"api_endpoint": "CVProcess",
+		"req_key":      taskParams["req_key"],
+		"prompt":       taskParams["prompt"],
+		"image_url":    request.ImageURL,
+		"scale":        taskParams["scale"],
+		"seed":         taskParams["seed"],
+	}).Info("SeedEdit API调用开始")
+
+	startTime := time.Now()
+	resp, status, err := s.visualClient.CVProcess(taskParams)
+	duration := time.Since(startTime)
+	s.recordVisualResult(ctx, config.VolcengineImageEditModel, status, resp, err)
+
+	if err := checkVisualResponse("提交SeedEdit任务失败", status, resp, err); err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"api_endpoint": "CVProcess",
+			"duration_ms":  duration.Milliseconds(),
+			"status_code":  status,
+			"error":        err.Error(),
+		}).Error("SeedEdit API调用失败")
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"api_endpoint": "CVProcess",
+		"duration_ms":  duration.Milliseconds(),
+		"status_code":  status,
+	}).Info("SeedEdit API调用成功")
+
+	// 响应格式与即梦AI图像生成一致
+	result, err := s.parseJimengResponse(resp)
+	if err != nil {
+		s.logger.Errorf("解析SeedEdit响应失败: %v", err)
+		return nil, fmt.Errorf("解析响应失败: %v", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,76 @@
+package volcengine
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/volcengine/volcengine-go-sdk/service/arkruntime/model"
+)
+
+// CallLimiter 服务商接口调用限流和熔断接口，避免依赖core包
+type CallLimiter interface {
+	// 调用前获取令牌，熔断中或限流时返回需要延迟重试的错误
+	Acquire(ctx context.Context, provider, model string) error
+	// 记录调用结果，overloaded表示服务商限流、服务端错误或网络错误
+	RecordResult(ctx context.Context, provider, model string, overloaded bool)
+}
+
+// SetCallLimiter 设置服务商接口调用限流器
+func (s *VolcengineService) SetCallLimiter(limiter CallLimiter) {
+	s.limiter = limiter
+}
+
+// acquire 调用服务商接口前获取令牌，未设置限流器时直接放行
+func (s *VolcengineService) acquire(ctx context.Context, model string) error {
+	if s.limiter == nil {
+		return nil
+	}
+	return s.limiter.Acquire(ctx, ProviderName, model)
+}
+
+// recordArkResult 记录火山方舟接口的调用结果
+func (s *VolcengineService) recordArkResult(ctx context.Context, modelID string, err error) {
+	if s.limiter == nil {
+		return
+	}
+
+	overloaded := false
+	if err != nil && !errors.Is(err, context.Canceled) {
+		var apiErr *model.APIError
+		var reqErr *model.RequestError
+		switch {
+		case errors.As(err, &apiErr):
+			overloaded = isOverloadedStatus(apiErr.HTTPStatusCode)
+		case errors.As(err, &reqErr):
+			overloaded = isOverloadedStatus(reqErr.HTTPStatusCode)
+		default:
+			// 网络错误
+			overloaded = true
+		}
+	}
+	s.limiter.RecordResult(ctx, ProviderName, modelID, overloaded)
+}
+
+// recordVisualResult 记录即梦AI视觉接口的调用结果，限流等业务错误可能只体现在响应的code中
+func (s *VolcengineService) recordVisualResult(ctx context.Context, reqKey string, status int, resp map[string]interface{}, err error) {
+	if s.limiter == nil {
+		return
+	}
+
+	overloaded := isOverloadedStatus(status) || (err != nil && status == 0 && !errors.Is(err, context.Canceled))
+	if code, ok := resp["code"].(float64); ok {
+		switch c := int(code); {
+		case c == visualCodeRateLimited, c == visualCodeConcurrencyCapped:
+			overloaded = true
+		case c >= visualCodeServerErrorMin && c <= visualCodeServerErrorMax:
+			overloaded = true
+		}
+	}
+	s.limiter.RecordResult(ctx, ProviderName, reqKey, overloaded)
+}
+
+// isOverloadedStatus 限流或服务端错误的HTTP状态码
+func isOverloadedStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
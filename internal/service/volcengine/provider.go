@@ -2,12 +2,14 @@ package volcengine
 
 import (
 	"context"
-	"fmt"
 
 	"volcengine-go-server/config"
 	"volcengine-go-server/pkg/logger"
 )
 
+// ProviderName 火山引擎分发器名称
+const ProviderName = "volcengine"
+
 // Provider 火山引擎任务分发器 - Provider层
 // 只负责根据模型参数决定调用VolcengineService的哪个具体方法
 type Provider struct {
@@ -25,7 +27,7 @@ func NewProvider(service *VolcengineService, taskService TaskService) *Provider
 
 // GetProviderName 获取分发器名称
 func (p *Provider) GetProviderName() string {
-	return "volcengine"
+	return ProviderName
 }
 
 // DispatchImageTask 分发图像生成任务
@@ -43,10 +45,12 @@ func (p *Provider) DispatchImageTask(ctx context.Context, taskID string, model s
 		log.Infof("分发到豆包图像生成服务: %s", taskID)
 		return p.service.GenerateImageByDoubao(ctx, taskID, input)
 
+	case config.VolcengineImageEditModel: // byteedit_v2.0
+		log.Infof("分发到SeedEdit图像编辑服务: %s", taskID)
+		return p.service.EditImageBySeedEdit(ctx, taskID, input)
+
 	default:
-		// 默认使用豆包模型
-		log.Warnf("未知模型 %s，使用默认豆包模型", model)
-		return p.service.GenerateImageByDoubao(ctx, taskID, input)
+		return invalidInputError("不支持的图像生成模型: %s", model)
 	}
 }
 
@@ -60,7 +64,7 @@ func (p *Provider) DispatchTextTask(ctx context.Context, taskID string, model st
 	case config.VolcengineTextModel:
 		return p.service.GenerateTextByDoubao(ctx, taskID, input)
 	default:
-		return fmt.Errorf("不支持的文本生成模型: %s", model)
+		return invalidInputError("不支持的文本生成模型: %s", model)
 	}
 }
 
@@ -76,6 +80,17 @@ func (p *Provider) DispatchVideoTask(ctx context.Context, taskID string, model s
 	case config.VolcengineJimengI2VModel:
 		return p.service.GenerateI2VByJimeng(ctx, taskID, input)
 	default:
-		return fmt.Errorf("不支持的视频生成模型: %s", model)
+		return invalidInputError("不支持的视频生成模型: %s", model)
+	}
+}
+
+// CheckVideoTask 查询一次视频任务的生成状态，返回任务是否已结束
+func (p *Provider) CheckVideoTask(ctx context.Context, taskID, model, providerTaskID string) (bool, error) {
+	switch model {
+	case config.VolcengineJimengVideoModel, config.VolcengineJimengI2VModel:
+		// 即梦AI的模型名即查询结果所需的req_key
+		return p.service.CheckJimengVideoTask(ctx, taskID, model, providerTaskID)
+	default:
+		return false, invalidInputError("不支持状态检查的视频生成模型: %s", model)
 	}
 }
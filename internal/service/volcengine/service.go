@@ -14,8 +14,22 @@ import (
 
 // TaskService 接口定义，避免循环依赖
 type TaskService interface {
-	UpdateTaskError(ctx context.Context, taskID string, errorMsg string) error
 	UpdateTaskResult(ctx context.Context, taskID string, result string) error
+	UpdateTaskImageResults(ctx context.Context, taskID string, imageURLs []string) error
+	RecordProviderSubmission(ctx context.Context, taskID, providerTaskID, reqKey string, response interface{}) error
+	RecordProviderResponse(ctx context.Context, taskID string, response interface{}, completed bool) error
+}
+
+// StatusCheckScheduler 异步任务状态检查调度接口，避免依赖core包
+type StatusCheckScheduler interface {
+	ScheduleStatusCheck(ctx context.Context, provider, model, taskID, providerTaskID string, attempt int) error
+}
+
+// StreamPublisher 流式输出发布接口，避免依赖core包
+type StreamPublisher interface {
+	PublishTextDelta(ctx context.Context, taskID, delta string) error
+	PublishTextDone(ctx context.Context, taskID, text string) error
+	ResetText(ctx context.Context, taskID string) error
 }
 
 // VolcengineService 火山引擎AI服务 - Service层，负责具体的API调用实现
@@ -25,6 +39,12 @@ type VolcengineService struct {
 	logger       *logrus.Logger
 	visualClient *visual.Visual
 	taskService  TaskService
+	// 可选：设置后文本生成使用流式接口并实时发布增量结果
+	streamPublisher StreamPublisher
+	// 可选：设置后视频任务提交即返回，由队列中的状态检查任务查询结果
+	statusScheduler StatusCheckScheduler
+	// 可选：设置后调用服务商接口前进行限流和熔断检查
+	limiter CallLimiter
 }
 
 // NewVolcengineService 创建火山引擎AI服务实例
@@ -53,6 +73,16 @@ func NewVolcengineService(cfg config.AIConfig, taskService TaskService) *Volceng
 	}
 }
 
+// SetStreamPublisher 设置流式输出发布器
+func (s *VolcengineService) SetStreamPublisher(publisher StreamPublisher) {
+	s.streamPublisher = publisher
+}
+
+// SetStatusCheckScheduler 设置异步任务状态检查调度器
+func (s *VolcengineService) SetStatusCheckScheduler(scheduler StatusCheckScheduler) {
+	s.statusScheduler = scheduler
+}
+
 // HealthCheck 健康检查
 func (s *VolcengineService) HealthCheck(ctx context.Context) error {
 	// 简单的健康检查
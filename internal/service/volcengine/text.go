@@ -0,0 +1,246 @@
+package volcengine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/volcengine/volcengine-go-sdk/service/arkruntime/model"
+
+	"volcengine-go-server/config"
+)
+
+// GenerateTextByDoubao 豆包文本生成具体实现
+func (s *VolcengineService) GenerateTextByDoubao(ctx context.Context, taskID string, input map[string]interface{}) error {
+	s.logger.Infof("豆包文本生成开始: taskID=%s", taskID)
+
+	// 从input参数中获取任务信息
+	prompt, ok := input["prompt"].(string)
+	if !ok || prompt == "" {
+		err := invalidInputError("无效的prompt参数")
+		s.logger.Errorf("获取任务输入失败: %v", err)
+		return err
+	}
+
+	// 构建豆包文本生成请求参数
+	request := &VolcengineTextRequest{
+		Prompt:      prompt,
+		Model:       config.VolcengineTextModel,
+		MaxTokens:   getIntFromInput(input, "max_tokens", config.DefaultTextMaxTokens),
+		Temperature: getFloatFromInput(input, "temperature", config.DefaultTextTemperature),
+	}
+
+	// 调用豆包文本生成，配置了流式发布器时使用流式接口
+	var result *VolcengineTextResponse
+	var err error
+	if s.streamPublisher != nil {
+		// 重试或故障转移时清空之前执行留下的部分文本
+		if resetErr := s.streamPublisher.ResetText(ctx, taskID); resetErr != nil {
+			s.logger.Warnf("重置流式输出失败: taskID=%s, err=%v", taskID, resetErr)
+		}
+		result, err = s.generateTextStream(ctx, request, func(delta string) {
+			if pubErr := s.streamPublisher.PublishTextDelta(ctx, taskID, delta); pubErr != nil {
+				s.logger.Warnf("发布流式增量失败: taskID=%s, err=%v", taskID, pubErr)
+			}
+		})
+	} else {
+		result, err = s.generateText(ctx, request)
+	}
+	if err != nil {
+		s.logger.Errorf("豆包文本生成失败: %v", err)
+		return err
+	}
+
+	// 检查是否有生成的文本
+	if result.Content == "" {
+		errorMsg := "未生成任何文本"
+		s.logger.Errorf("文本生成失败: %s", errorMsg)
+		return errors.New(errorMsg)
+	}
+
+	s.logger.Infof("豆包文本生成任务完成: %s, 文本长度: %d, 结束原因: %s", taskID, len([]rune(result.Content)), result.FinishReason)
+
+	// 更新数据库中的任务状态
+	if err := s.taskService.UpdateTaskResult(ctx, taskID, result.Content); err != nil {
+		s.logger.Errorf("更新任务状态失败: %v", err)
+		return err
+	}
+
+	if s.streamPublisher != nil {
+		if err := s.streamPublisher.PublishTextDone(ctx, taskID, result.Content); err != nil {
+			s.logger.Warnf("发布流式完成事件失败: taskID=%s, err=%v", taskID, err)
+		}
+	}
+
+	s.logger.Infof("豆包文本任务状态已更新为完成: %s", taskID)
+	return nil
+}
+
+// generateText 生成文本（同步）- 内部方法
+func (s *VolcengineService) generateText(ctx context.Context, request *VolcengineTextRequest) (*VolcengineTextResponse, error) {
+	// 设置默认模型
+	modelID := request.Model
+	if modelID == "" {
+		modelID = config.VolcengineTextModel
+	}
+
+	chatReq := s.buildChatRequest(modelID, request)
+
+	if err := s.acquire(ctx, modelID); err != nil {
+		return nil, err
+	}
+
+	// 记录详细的API调用信息
+	s.logger.WithFields(logrus.Fields{
+		"api_endpoint": "CreateChatCompletion",
+		"model":        modelID,
+		"prompt":       request.Prompt,
+		"max_tokens":   request.MaxTokens,
+		"temperature":  request.Temperature,
+	}).Info("火山方舟API调用开始")
+
+	// 调用火山方舟对话API
+	startTime := time.Now()
+	resp, err := s.client.CreateChatCompletion(ctx, chatReq)
+	duration := time.Since(startTime)
+	s.recordArkResult(ctx, modelID, err)
+
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"api_endpoint": "CreateChatCompletion",
+			"duration_ms":  duration.Milliseconds(),
+			"error":        err.Error(),
+		}).Error("火山方舟API调用失败")
+		return nil, classifyArkError("文本生成失败", err)
+	}
+
+	// 记录成功的API调用
+	s.logger.WithFields(logrus.Fields{
+		"api_endpoint":  "CreateChatCompletion",
+		"duration_ms":   duration.Milliseconds(),
+		"choices_count": len(resp.Choices),
+		"total_tokens":  resp.Usage.TotalTokens,
+	}).Info("火山方舟API调用成功")
+
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("响应中未包含任何候选结果")
+	}
+
+	// 转换响应格式
+	choice := resp.Choices[0]
+	response := &VolcengineTextResponse{
+		FinishReason: string(choice.FinishReason),
+		TotalTokens:  resp.Usage.TotalTokens,
+	}
+	if choice.Message.Content != nil && choice.Message.Content.StringValue != nil {
+		response.Content = *choice.Message.Content.StringValue
+	}
+
+	return response, nil
+}
+
+// generateTextStream 流式生成文本 - 内部方法
+// 每收到一段增量文本即调用onDelta，结束后返回累积的完整文本
+func (s *VolcengineService) generateTextStream(ctx context.Context, request *VolcengineTextRequest, onDelta func(delta string)) (*VolcengineTextResponse, error) {
+	// 设置默认模型
+	modelID := request.Model
+	if modelID == "" {
+		modelID = config.VolcengineTextModel
+	}
+
+	chatReq := s.buildChatRequest(modelID, request)
+	chatReq.StreamOptions = &model.StreamOptions{IncludeUsage: true}
+
+	if err := s.acquire(ctx, modelID); err != nil {
+		return nil, err
+	}
+
+	// 记录详细的API调用信息
+	s.logger.WithFields(logrus.Fields{
+		"api_endpoint": "CreateChatCompletionStream",
+		"model":        modelID,
+		"prompt":       request.Prompt,
+		"max_tokens":   request.MaxTokens,
+		"temperature":  request.Temperature,
+	}).Info("火山方舟流式API调用开始")
+
+	startTime := time.Now()
+	stream, err := s.client.CreateChatCompletionStream(ctx, chatReq)
+	if err != nil {
+		s.recordArkResult(ctx, modelID, err)
+		s.logger.WithFields(logrus.Fields{
+			"api_endpoint": "CreateChatCompletionStream",
+			"duration_ms":  time.Since(startTime).Milliseconds(),
+			"error":        err.Error(),
+		}).Error("火山方舟流式API调用失败")
+		return nil, classifyArkError("文本生成失败", err)
+	}
+	defer stream.Close()
+
+	var builder strings.Builder
+	response := &VolcengineTextResponse{}
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			// 流式响应中途断开同样计入熔断统计
+			s.recordArkResult(ctx, modelID, err)
+			s.logger.WithFields(logrus.Fields{
+				"api_endpoint": "CreateChatCompletionStream",
+				"duration_ms":  time.Since(startTime).Milliseconds(),
+				"received":     builder.Len(),
+				"error":        err.Error(),
+			}).Error("火山方舟流式响应读取失败")
+			return nil, classifyArkError("文本生成失败", err)
+		}
+
+		if chunk.Usage != nil {
+			response.TotalTokens = chunk.Usage.TotalTokens
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content != "" {
+				builder.WriteString(choice.Delta.Content)
+				onDelta(choice.Delta.Content)
+			}
+			if choice.FinishReason != "" {
+				response.FinishReason = string(choice.FinishReason)
+			}
+		}
+	}
+	response.Content = builder.String()
+	s.recordArkResult(ctx, modelID, nil)
+
+	// 记录成功的API调用
+	s.logger.WithFields(logrus.Fields{
+		"api_endpoint": "CreateChatCompletionStream",
+		"duration_ms":  time.Since(startTime).Milliseconds(),
+		"total_tokens": response.TotalTokens,
+	}).Info("火山方舟流式API调用成功")
+
+	return response, nil
+}
+
+// buildChatRequest 构建方舟对话请求
+func (s *VolcengineService) buildChatRequest(modelID string, request *VolcengineTextRequest) model.CreateChatCompletionRequest {
+	maxTokens := request.MaxTokens
+	temperature := float32(request.Temperature)
+	return model.CreateChatCompletionRequest{
+		Model: modelID,
+		Messages: []*model.ChatCompletionMessage{
+			{
+				Role: model.ChatMessageRoleUser,
+				Content: &model.ChatCompletionMessageContent{
+					StringValue: &request.Prompt,
+				},
+			},
+		},
+		MaxTokens:   &maxTokens,
+		Temperature: &temperature,
+	}
+}
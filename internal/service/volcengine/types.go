@@ -24,9 +24,24 @@ type ImageData struct {
 	URL string `json:"url"` // 图片URL
 }
 
+// 豆包文本生成请求结构
+type VolcengineTextRequest struct {
+	Prompt      string  `json:"prompt"`                // 必填：用户输入
+	Model       string  `json:"model,omitempty"`       // 模型ID，默认使用豆包文本生成模型
+	MaxTokens   int     `json:"max_tokens,omitempty"`  // 最大输出token数
+	Temperature float64 `json:"temperature,omitempty"` // 温度参数
+}
+
+// 豆包文本生成响应结构
+type VolcengineTextResponse struct {
+	Content      string `json:"content"`       // 生成的文本
+	FinishReason string `json:"finish_reason"` // 结束原因
+	TotalTokens  int    `json:"total_tokens"`  // 消耗的token总数
+}
+
 // 即梦AI图像生成响应结构
 type JimengImageResult struct {
-	ImageURL string `json:"image_url"` // 图片URL
+	ImageURLs []string `json:"image_urls"` // 图片URL列表
 }
 
 type VolcJimentImageRequest struct {
@@ -57,3 +72,11 @@ type JimengVideoResult struct {
 	VideoURL string `json:"video_url"` // 视频URL
 	Status   string `json:"status"`    // 任务状态
 }
+
+// SeedEdit图像编辑请求结构
+type SeedEditImageRequest struct {
+	ImageURL string  `json:"image_url"` // 必填：原图链接
+	Prompt   string  `json:"prompt"`    // 必填：编辑指令
+	Scale    float64 `json:"scale"`     // 可选：编辑强度，取值[0, 1]，默认0.5
+	Seed     int     `json:"seed"`      // 可选：随机种子，默认-1（随机）
+}
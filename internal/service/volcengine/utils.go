@@ -202,3 +202,32 @@ func (s *VolcengineService) isValidAspectRatio(aspectRatio string) bool {
 	}
 	return false
 }
+
+// getIntFromInput 从任务输入中读取整数参数
+// 载荷经过JSON序列化后数字会变为float64，这里统一兼容处理
+func getIntFromInput(input map[string]interface{}, key string, defaultValue int) int {
+	switch v := input[key].(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	default:
+		return defaultValue
+	}
+}
+
+// getFloatFromInput 从任务输入中读取浮点数参数
+func getFloatFromInput(input map[string]interface{}, key string, defaultValue float64) float64 {
+	switch v := input[key].(type) {
+	case float64:
+		return v
+	case float32:
+		return float64(v)
+	case int:
+		return float64(v)
+	default:
+		return defaultValue
+	}
+}
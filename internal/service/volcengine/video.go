@@ -7,21 +7,10 @@ import (
 
 	"github.com/sirupsen/logrus"
 
+	"volcengine-go-server/config"
 	"volcengine-go-server/internal/util"
 )
 
-// GenerateTextByDoubao 豆包文本生成具体实现
-func (s *VolcengineService) GenerateTextByDoubao(ctx context.Context, taskID string, input map[string]interface{}) error {
-	// TODO: 实现豆包文本生成逻辑
-	s.logger.Infof("豆包文本生成任务处理中: %s", taskID)
-
-	// 模拟处理时间
-	time.Sleep(2 * time.Second)
-
-	s.logger.Infof("豆包文本生成任务完成: %s", taskID)
-	return nil
-}
-
 // GenerateVideoByJimeng 即梦AI视频生成具体实现
 func (s *VolcengineService) GenerateVideoByJimeng(ctx context.Context, taskID string, input map[string]interface{}) error {
 	s.logger.Infof("即梦AI视频生成开始: taskID=%s", taskID)
@@ -29,17 +18,8 @@ func (s *VolcengineService) GenerateVideoByJimeng(ctx context.Context, taskID st
 	// 从input参数中获取任务信息
 	prompt, ok := input["prompt"].(string)
 	if !ok {
-		err := fmt.Errorf("无效的prompt参数")
+		err := invalidInputError("无效的prompt参数")
 		s.logger.Errorf("获取任务输入失败: %v", err)
-		s.taskService.UpdateTaskError(ctx, taskID, err.Error())
-		return err
-	}
-
-	// 检查prompt长度限制
-	if len(prompt) > 150 {
-		err := fmt.Errorf("prompt长度超过150字符限制，当前长度: %d", len(prompt))
-		s.logger.Errorf("prompt长度检查失败: %v", err)
-		s.taskService.UpdateTaskError(ctx, taskID, err.Error())
 		return err
 	}
 
@@ -63,33 +43,16 @@ func (s *VolcengineService) GenerateVideoByJimeng(ctx context.Context, taskID st
 	}
 
 	// 提交视频生成任务
-	externalTaskID, err := s.submitJimengVideoTask(ctx, request)
+	externalTaskID, resp, err := s.submitJimengVideoTask(ctx, request)
 	if err != nil {
 		s.logger.Errorf("提交即梦AI视频任务失败: %v", err)
-		s.taskService.UpdateTaskError(ctx, taskID, err.Error())
+		s.recordProviderResponse(ctx, taskID, resp, false)
 		return err
 	}
 
 	s.logger.Infof("即梦AI视频任务已提交，外部任务ID: %s", externalTaskID)
 
-	// 轮询任务结果
-	result, err := s.pollJimengVideoResult(ctx, externalTaskID)
-	if err != nil {
-		s.logger.Errorf("轮询即梦AI视频任务结果失败: %v", err)
-		s.taskService.UpdateTaskError(ctx, taskID, err.Error())
-		return err
-	}
-
-	s.logger.Infof("即梦AI视频生成成功: %s, 视频URL: %s", externalTaskID, result.VideoURL)
-
-	// 更新数据库中的任务状态
-	if err := s.taskService.UpdateTaskResult(ctx, taskID, result.VideoURL); err != nil {
-		s.logger.Errorf("更新任务状态失败: %v", err)
-		return err
-	}
-
-	s.logger.Infof("即梦AI视频任务状态已更新为完成: %s", taskID)
-	return nil
+	return s.awaitJimengVideoResult(ctx, taskID, config.VolcengineJimengVideoModel, externalTaskID, resp)
 }
 
 // GenerateI2VByJimeng 即梦AI图生视频具体实现
@@ -99,9 +62,8 @@ func (s *VolcengineService) GenerateI2VByJimeng(ctx context.Context, taskID stri
 	// 从input参数中获取图片URLs
 	imageURLsInterface, ok := input["image_urls"]
 	if !ok {
-		err := fmt.Errorf("缺少image_urls参数")
+		err := invalidInputError("缺少image_urls参数")
 		s.logger.Errorf("获取任务输入失败: %v", err)
-		s.taskService.UpdateTaskError(ctx, taskID, err.Error())
 		return err
 	}
 
@@ -117,27 +79,19 @@ func (s *VolcengineService) GenerateI2VByJimeng(ctx context.Context, taskID stri
 			}
 		}
 	default:
-		err := fmt.Errorf("image_urls参数格式错误")
+		err := invalidInputError("image_urls参数格式错误")
 		s.logger.Errorf("获取任务输入失败: %v", err)
-		s.taskService.UpdateTaskError(ctx, taskID, err.Error())
 		return err
 	}
 
 	if len(imageURLs) == 0 {
-		err := fmt.Errorf("image_urls不能为空")
+		err := invalidInputError("image_urls不能为空")
 		s.logger.Errorf("获取任务输入失败: %v", err)
-		s.taskService.UpdateTaskError(ctx, taskID, err.Error())
 		return err
 	}
 
-	// 获取prompt（可选）
+	// 获取prompt（可选），长度等参数限制由队列按模型能力描述在分发前校验
 	prompt, _ := input["prompt"].(string)
-	if prompt != "" && len(prompt) > 150 {
-		err := fmt.Errorf("prompt长度超过150字符限制，当前长度: %d", len(prompt))
-		s.logger.Errorf("prompt长度检查失败: %v", err)
-		s.taskService.UpdateTaskError(ctx, taskID, err.Error())
-		return err
-	}
 
 	// 获取aspect_ratio，如果没有提供则通过图片检测
 	aspectRatio, _ := input["aspect_ratio"].(string)
@@ -155,9 +109,8 @@ func (s *VolcengineService) GenerateI2VByJimeng(ctx context.Context, taskID stri
 
 	// 验证aspect_ratio是否在支持的范围内
 	if !s.isValidAspectRatio(aspectRatio) {
-		err := fmt.Errorf("不支持的aspect_ratio: %s，支持的比例: 16:9, 4:3, 1:1, 3:4, 9:16, 21:9, 9:21", aspectRatio)
+		err := invalidInputError("不支持的aspect_ratio: %s，支持的比例: 16:9, 4:3, 1:1, 3:4, 9:16, 21:9, 9:21", aspectRatio)
 		s.logger.Errorf("aspect_ratio验证失败: %v", err)
-		s.taskService.UpdateTaskError(ctx, taskID, err.Error())
 		return err
 	}
 
@@ -177,24 +130,79 @@ func (s *VolcengineService) GenerateI2VByJimeng(ctx context.Context, taskID stri
 	}
 
 	// 提交图生视频任务
-	externalTaskID, err := s.submitJimengI2VTask(ctx, request)
+	externalTaskID, resp, err := s.submitJimengI2VTask(ctx, request)
 	if err != nil {
 		s.logger.Errorf("提交即梦AI图生视频任务失败: %v", err)
-		s.taskService.UpdateTaskError(ctx, taskID, err.Error())
+		s.recordProviderResponse(ctx, taskID, resp, false)
 		return err
 	}
 
 	s.logger.Infof("即梦AI图生视频任务已提交，外部任务ID: %s", externalTaskID)
 
-	// 轮询任务结果（复用文生视频的轮询逻辑）
-	result, err := s.pollJimengVideoResult(ctx, externalTaskID)
+	// 复用文生视频的结果查询逻辑
+	return s.awaitJimengVideoResult(ctx, taskID, config.VolcengineJimengI2VModel, externalTaskID, resp)
+}
+
+// awaitJimengVideoResult 记录外部任务信息并安排状态检查任务，提交后立即释放Worker
+// 即梦AI的模型名即接口的req_key；未设置调度器时退回到阻塞轮询
+func (s *VolcengineService) awaitJimengVideoResult(ctx context.Context, taskID, model, externalTaskID string, submitResp map[string]interface{}) error {
+	if err := s.taskService.RecordProviderSubmission(ctx, taskID, externalTaskID, model, submitResp); err != nil {
+		s.logger.Errorf("保存外部任务信息失败: %v", err)
+		return err
+	}
+
+	if s.statusScheduler != nil {
+		if err := s.statusScheduler.ScheduleStatusCheck(ctx, ProviderName, model, taskID, externalTaskID, 0); err != nil {
+			s.logger.Errorf("安排即梦AI视频状态检查失败: %v", err)
+			return err
+		}
+		s.logger.Infof("即梦AI视频状态检查已入队: taskID=%s, 外部任务ID=%s", taskID, externalTaskID)
+		return nil
+	}
+
+	// 轮询任务结果
+	result, err := s.pollJimengVideoResult(ctx, model, externalTaskID)
 	if err != nil {
-		s.logger.Errorf("轮询即梦AI图生视频任务结果失败: %v", err)
-		s.taskService.UpdateTaskError(ctx, taskID, err.Error())
+		s.logger.Errorf("轮询即梦AI视频任务结果失败: %v", err)
 		return err
 	}
 
-	s.logger.Infof("即梦AI图生视频生成成功: %s, 视频URL: %s", externalTaskID, result.VideoURL)
+	// 阻塞轮询不保留每次查询的原始响应，只记录最终结果
+	s.recordProviderResponse(ctx, taskID, map[string]interface{}{
+		"status":    result.Status,
+		"video_url": result.VideoURL,
+	}, true)
+	return s.completeJimengVideoTask(ctx, taskID, externalTaskID, result)
+}
+
+// CheckJimengVideoTask 查询一次即梦AI视频任务结果，完成时更新任务，返回任务是否已结束
+func (s *VolcengineService) CheckJimengVideoTask(ctx context.Context, taskID, reqKey, externalTaskID string) (bool, error) {
+	result, resp, err := s.queryJimengVideoResult(ctx, reqKey, externalTaskID)
+	s.recordProviderResponse(ctx, taskID, resp, err == nil && result.Status == "done")
+	if err != nil {
+		return false, err
+	}
+	if result.Status != "done" {
+		s.logger.Debugf("即梦AI视频任务进行中: taskID=%s, status=%s", taskID, result.Status)
+		return false, nil
+	}
+
+	return true, s.completeJimengVideoTask(ctx, taskID, externalTaskID, result)
+}
+
+// recordProviderResponse 记录服务商原始响应，失败只记录日志
+func (s *VolcengineService) recordProviderResponse(ctx context.Context, taskID string, resp map[string]interface{}, completed bool) {
+	if resp == nil {
+		return
+	}
+	if err := s.taskService.RecordProviderResponse(ctx, taskID, resp, completed); err != nil {
+		s.logger.Warnf("保存服务商原始响应失败: taskID=%s, %v", taskID, err)
+	}
+}
+
+// completeJimengVideoTask 保存即梦AI视频生成结果
+func (s *VolcengineService) completeJimengVideoTask(ctx context.Context, taskID, externalTaskID string, result *JimengVideoResult) error {
+	s.logger.Infof("即梦AI视频生成成功: %s, 视频URL: %s", externalTaskID, result.VideoURL)
 
 	// 更新数据库中的任务状态
 	if err := s.taskService.UpdateTaskResult(ctx, taskID, result.VideoURL); err != nil {
@@ -202,12 +210,12 @@ func (s *VolcengineService) GenerateI2VByJimeng(ctx context.Context, taskID stri
 		return err
 	}
 
-	s.logger.Infof("即梦AI图生视频任务状态已更新为完成: %s", taskID)
+	s.logger.Infof("即梦AI视频任务状态已更新为完成: %s", taskID)
 	return nil
 }
 
 // submitJimengVideoTask 提交即梦AI视频生成任务
-func (s *VolcengineService) submitJimengVideoTask(ctx context.Context, request *JimengVideoRequest) (string, error) {
+func (s *VolcengineService) submitJimengVideoTask(ctx context.Context, request *JimengVideoRequest) (string, map[string]interface{}, error) {
 	s.logger.Infof("开始调用即梦AI视频生成API: prompt=%s", request.Prompt)
 
 	// 构建即梦AI视频任务参数
@@ -222,6 +230,10 @@ func (s *VolcengineService) submitJimengVideoTask(ctx context.Context, request *
 		taskParams["seed"] = request.Seed
 	}
 
+	if err := s.acquire(ctx, config.VolcengineJimengVideoModel); err != nil {
+		return "", nil, err
+	}
+
 	// 记录详细的API调用信息
 	s.logger.WithFields(logrus.Fields{
 		"api_endpoint": "cvSync2AsyncSubmitTask",
@@ -235,15 +247,16 @@ func (s *VolcengineService) submitJimengVideoTask(ctx context.Context, request *
 	startTime := time.Now()
 	resp, status, err := s.visualClient.CVSync2AsyncSubmitTask(taskParams)
 	duration := time.Since(startTime)
+	s.recordVisualResult(ctx, config.VolcengineJimengVideoModel, status, resp, err)
 
-	if err != nil {
+	if err := checkVisualResponse("提交即梦AI视频任务失败", status, resp, err); err != nil {
 		s.logger.WithFields(logrus.Fields{
 			"api_endpoint": "cvSync2AsyncSubmitTask",
 			"duration_ms":  duration.Milliseconds(),
 			"status_code":  status,
 			"error":        err.Error(),
 		}).Error("即梦AI视频API调用失败")
-		return "", fmt.Errorf("提交即梦AI视频任务失败: %v", err)
+		return "", resp, err
 	}
 
 	// 记录成功的API调用
@@ -257,7 +270,7 @@ func (s *VolcengineService) submitJimengVideoTask(ctx context.Context, request *
 	// 检查响应是否包含task_id（异步任务）
 	if taskID, ok := resp["task_id"].(string); ok && taskID != "" {
 		s.logger.Infof("即梦AI视频任务提交成功，获得task_id: %s", taskID)
-		return taskID, nil
+		return taskID, resp, nil
 	}
 
 	// 如果没有task_id，检查是否有其他标识符
@@ -267,17 +280,17 @@ func (s *VolcengineService) submitJimengVideoTask(ctx context.Context, request *
 			if taskID, exists := dataMap["task_id"]; exists {
 				if taskIDStr, ok := taskID.(string); ok && taskIDStr != "" {
 					s.logger.Infof("即梦AI视频任务提交成功，从data中获得task_id: %s", taskIDStr)
-					return taskIDStr, nil
+					return taskIDStr, resp, nil
 				}
 			}
 		}
 	}
 
-	return "", fmt.Errorf("响应中未找到有效的task_id")
+	return "", resp, fmt.Errorf("响应中未找到有效的task_id")
 }
 
 // submitJimengI2VTask 提交即梦AI图生视频任务
-func (s *VolcengineService) submitJimengI2VTask(ctx context.Context, request *JimengI2VRequest) (string, error) {
+func (s *VolcengineService) submitJimengI2VTask(ctx context.Context, request *JimengI2VRequest) (string, map[string]interface{}, error) {
 	s.logger.Infof("开始调用即梦AI图生视频API: image_count=%d", len(request.ImageURLs))
 
 	// 构建即梦AI图生视频任务参数
@@ -297,6 +310,10 @@ func (s *VolcengineService) submitJimengI2VTask(ctx context.Context, request *Ji
 		taskParams["seed"] = request.Seed
 	}
 
+	if err := s.acquire(ctx, config.VolcengineJimengI2VModel); err != nil {
+		return "", nil, err
+	}
+
 	// 记录详细的API调用信息
 	s.logger.WithFields(logrus.Fields{
 		"api_endpoint": "cvSync2AsyncSubmitTask",
@@ -311,15 +328,16 @@ func (s *VolcengineService) submitJimengI2VTask(ctx context.Context, request *Ji
 	startTime := time.Now()
 	resp, status, err := s.visualClient.CVSync2AsyncSubmitTask(taskParams)
 	duration := time.Since(startTime)
+	s.recordVisualResult(ctx, config.VolcengineJimengI2VModel, status, resp, err)
 
-	if err != nil {
+	if err := checkVisualResponse("提交即梦AI图生视频任务失败", status, resp, err); err != nil {
 		s.logger.WithFields(logrus.Fields{
 			"api_endpoint": "cvSync2AsyncSubmitTask",
 			"duration_ms":  duration.Milliseconds(),
 			"status_code":  status,
 			"error":        err.Error(),
 		}).Error("即梦AI图生视频API调用失败")
-		return "", fmt.Errorf("提交即梦AI图生视频任务失败: %v", err)
+		return "", resp, err
 	}
 
 	// 记录成功的API调用
@@ -333,7 +351,7 @@ func (s *VolcengineService) submitJimengI2VTask(ctx context.Context, request *Ji
 	// 检查响应是否包含task_id（异步任务）
 	if taskID, ok := resp["task_id"].(string); ok && taskID != "" {
 		s.logger.Infof("即梦AI图生视频任务提交成功，获得task_id: %s", taskID)
-		return taskID, nil
+		return taskID, resp, nil
 	}
 
 	// 如果没有task_id，检查是否有其他标识符
@@ -343,19 +361,19 @@ func (s *VolcengineService) submitJimengI2VTask(ctx context.Context, request *Ji
 			if taskID, exists := dataMap["task_id"]; exists {
 				if taskIDStr, ok := taskID.(string); ok && taskIDStr != "" {
 					s.logger.Infof("即梦AI图生视频任务提交成功，从data中获得task_id: %s", taskIDStr)
-					return taskIDStr, nil
+					return taskIDStr, resp, nil
 				}
 			}
 		}
 	}
 
-	return "", fmt.Errorf("响应中未找到有效的task_id")
+	return "", resp, fmt.Errorf("响应中未找到有效的task_id")
 }
 
 // pollJimengVideoResult 轮询即梦AI视频生成结果
-func (s *VolcengineService) pollJimengVideoResult(ctx context.Context, taskID string) (*JimengVideoResult, error) {
+func (s *VolcengineService) pollJimengVideoResult(ctx context.Context, reqKey, taskID string) (*JimengVideoResult, error) {
 	// 创建即梦AI视频结果检查器
-	checker := &JimengVideoResultChecker{service: s}
+	checker := &JimengVideoResultChecker{service: s, reqKey: reqKey}
 
 	// 使用默认的自适应轮询配置
 	config := util.DefaultPollConfig("即梦AI视频").WithLogger(s.logger)
@@ -375,14 +393,19 @@ func (s *VolcengineService) pollJimengVideoResult(ctx context.Context, taskID st
 	return videoResult, nil
 }
 
-// queryJimengVideoResult 查询即梦AI视频任务结果
-func (s *VolcengineService) queryJimengVideoResult(ctx context.Context, taskID string) (*JimengVideoResult, error) {
+// queryJimengVideoResult 查询即梦AI视频任务结果，同时返回原始响应
+// reqKey需与提交任务时一致，文生视频和图生视频使用不同的服务标识
+func (s *VolcengineService) queryJimengVideoResult(ctx context.Context, reqKey, taskID string) (*JimengVideoResult, map[string]interface{}, error) {
 	// 构建查询参数
 	queryParams := map[string]interface{}{
-		"req_key": "jimeng_vgfm_t2v_l20", // 即梦AI视频服务标识
+		"req_key": reqKey,
 		"task_id": taskID,
 	}
 
+	if err := s.acquire(ctx, reqKey); err != nil {
+		return nil, nil, err
+	}
+
 	// 记录详细的API调用信息
 	s.logger.WithFields(logrus.Fields{
 		"api_endpoint": "CVGetResult",
@@ -394,8 +417,9 @@ func (s *VolcengineService) queryJimengVideoResult(ctx context.Context, taskID s
 	startTime := time.Now()
 	resp, status, err := s.visualClient.CVGetResult(queryParams)
 	duration := time.Since(startTime)
+	s.recordVisualResult(ctx, reqKey, status, resp, err)
 
-	if err != nil {
+	if err := checkVisualResponse("查询即梦AI视频任务结果失败", status, resp, err); err != nil {
 		s.logger.WithFields(logrus.Fields{
 			"api_endpoint": "CVGetResult",
 			"duration_ms":  duration.Milliseconds(),
@@ -403,7 +427,7 @@ func (s *VolcengineService) queryJimengVideoResult(ctx context.Context, taskID s
 			"task_id":      taskID,
 			"error":        err.Error(),
 		}).Error("即梦AI视频结果查询API调用失败")
-		return nil, fmt.Errorf("查询即梦AI视频任务结果失败: %v", err)
+		return nil, resp, err
 	}
 
 	// 记录成功的API调用
@@ -419,10 +443,10 @@ func (s *VolcengineService) queryJimengVideoResult(ctx context.Context, taskID s
 	result, err := s.parseJimengVideoResultResponse(resp)
 	if err != nil {
 		s.logger.Errorf("解析即梦AI视频结果响应失败: %v", err)
-		return nil, fmt.Errorf("解析结果响应失败: %v", err)
+		return nil, resp, fmt.Errorf("解析结果响应失败: %v", err)
 	}
 
-	return result, nil
+	return result, resp, nil
 }
 
 // parseJimengVideoResultResponse 解析即梦AI视频结果响应
@@ -474,11 +498,12 @@ func (s *VolcengineService) parseJimengVideoResultResponse(resp map[string]inter
 // JimengVideoResultChecker 即梦AI视频结果检查器
 type JimengVideoResultChecker struct {
 	service *VolcengineService
+	reqKey  string
 }
 
 // CheckResult 实现util.TaskResultChecker接口
 func (c *JimengVideoResultChecker) CheckResult(ctx context.Context, taskID string) (interface{}, bool, error) {
-	result, err := c.service.queryJimengVideoResult(ctx, taskID)
+	result, _, err := c.service.queryJimengVideoResult(ctx, c.reqKey, taskID)
 	if err != nil {
 		return nil, false, err
 	}
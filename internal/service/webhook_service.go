@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+
+	"volcengine-go-server/internal/models"
+	"volcengine-go-server/internal/repository"
+)
+
+// ErrInvalidCallbackURL 回调地址无效或指向内网地址
+var ErrInvalidCallbackURL = errors.New("无效的回调地址")
+
+// callbackMaxRedirects 回调请求最多跟随的重定向次数
+const callbackMaxRedirects = 5
+
+// cgnatNetwork 运营商级NAT地址段，部分云厂商的元数据服务位于该网段
+var cgnatNetwork = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// WebhookService 任务回调投递记录服务
+type WebhookService struct {
+	deliveryRepo repository.WebhookDeliveryRepository
+}
+
+// NewWebhookService 创建回调投递记录服务
+func NewWebhookService(db repository.Database) *WebhookService {
+	return &WebhookService{
+		deliveryRepo: db.WebhookDeliveryRepository(),
+	}
+}
+
+// RecordDelivery 记录一次投递尝试
+func (s *WebhookService) RecordDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return s.deliveryRepo.CreateDelivery(ctx, delivery)
+}
+
+// GetTaskDeliveries 获取任务的投递历史
+func (s *WebhookService) GetTaskDeliveries(ctx context.Context, taskID string) ([]*models.WebhookDelivery, error) {
+	return s.deliveryRepo.GetDeliveriesByTaskID(ctx, taskID)
+}
+
+// ValidateCallbackURL 校验回调地址：只允许https，且主机解析出的所有地址都必须是公网地址
+// 创建任务时校验一次，投递时拨号和跟随重定向前会再次校验，防止DNS重绑定绕过
+func ValidateCallbackURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return fmt.Errorf("%w: 地址格式错误", ErrInvalidCallbackURL)
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("%w: 只支持https地址", ErrInvalidCallbackURL)
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil || len(addrs) == 0 {
+		return fmt.Errorf("%w: 无法解析主机 %s", ErrInvalidCallbackURL, u.Hostname())
+	}
+	for _, addr := range addrs {
+		if !IsPublicIP(addr.IP) {
+			return fmt.Errorf("%w: %s 指向内网地址", ErrInvalidCallbackURL, u.Hostname())
+		}
+	}
+	return nil
+}
+
+// IsPublicIP 判断是否为公网地址，回环、私有、链路本地（含云元数据地址169.254.169.254）、组播等地址均视为内网
+func IsPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || cgnatNetwork.Contains(ip))
+}
+
+// NewCallbackHTTPClient 创建投递回调使用的HTTP客户端
+// 拨号时校验实际连接的IP，跟随重定向前校验目标地址，不经过环境变量配置的代理
+func NewCallbackHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: callbackDialControl}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil // 经代理转发时拨号的是代理地址，无法校验实际目标
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= callbackMaxRedirects {
+				return fmt.Errorf("%w: 重定向次数过多", ErrInvalidCallbackURL)
+			}
+			return ValidateCallbackURL(req.Context(), req.URL.String())
+		},
+	}
+}
+
+// callbackDialControl 在建立连接前校验解析后的目标IP
+func callbackDialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCallbackURL, err)
+	}
+	if ip := net.ParseIP(host); ip == nil || !IsPublicIP(ip) {
+		return fmt.Errorf("%w: 禁止连接内网地址 %s", ErrInvalidCallbackURL, host)
+	}
+	return nil
+}
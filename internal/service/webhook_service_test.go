@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestValidateCallbackURL(t *testing.T) {
+	tests := []struct {
+		url   string
+		valid bool
+	}{
+		{"https://8.8.8.8/hook", true},
+		{"http://8.8.8.8/hook", false},
+		{"https://127.0.0.1/hook", false},
+		{"https://10.0.0.8/hook", false},
+		{"https://192.168.1.1/hook", false},
+		{"https://169.254.169.254/latest/meta-data", false},
+		{"https://100.100.100.200/hook", false},
+		{"https://[::1]/hook", false},
+		{"https://[::ffff:127.0.0.1]/hook", false},
+		{"https:///hook", false},
+	}
+
+	for _, tt := range tests {
+		err := ValidateCallbackURL(context.Background(), tt.url)
+		if tt.valid && err != nil {
+			t.Errorf("%s: 期望通过, 错误 = %v", tt.url, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrInvalidCallbackURL) {
+			t.Errorf("%s: 期望ErrInvalidCallbackURL, 实际 %v", tt.url, err)
+		}
+	}
+
+	if err := callbackDialControl("tcp", "127.0.0.1:443", nil); !errors.Is(err, ErrInvalidCallbackURL) {
+		t.Errorf("拨号内网地址应被拒绝, 实际 %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStorage 本地文件系统存储，文件由API服务器以静态资源方式对外提供
+// API服务器与Worker需要挂载同一个目录
+type LocalStorage struct {
+	baseDir string
+	baseURL string
+}
+
+// NewLocalStorage 创建本地文件系统存储
+func NewLocalStorage(baseDir, baseURL string) (*LocalStorage, error) {
+	if baseURL == "" {
+		return nil, fmt.Errorf("本地存储需要配置访问地址前缀")
+	}
+	if err := os.MkdirAll(baseDir, 0o755); err != nil {
+		return nil, fmt.Errorf("创建存储目录失败: %v", err)
+	}
+
+	return &LocalStorage{
+		baseDir: baseDir,
+		baseURL: baseURL,
+	}, nil
+}
+
+// Put 写入文件，先写临时文件再重命名，避免读到写了一半的文件
+func (s *LocalStorage) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) (string, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("创建存储目录失败: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return "", fmt.Errorf("创建临时文件失败: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("写入文件失败: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("写入文件失败: %v", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return "", fmt.Errorf("设置文件权限失败: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("保存文件失败: %v", err)
+	}
+
+	return joinURL(s.baseURL, key), nil
+}
+
+// path 将对象key转换为本地路径，拒绝跳出存储目录的key
+func (s *LocalStorage) path(key string) (string, error) {
+	cleaned := filepath.Clean("/" + key)
+	if cleaned == "/" || strings.Contains(key, "..") {
+		return "", fmt.Errorf("无效的存储key: %s", key)
+	}
+	return filepath.Join(s.baseDir, filepath.FromSlash(cleaned)), nil
+}
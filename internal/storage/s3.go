@@ -0,0 +1,182 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// s3UnsignedPayload 不对请求体签名，允许流式上传而无需预先计算哈希
+const s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+// S3Options S3兼容对象存储配置
+type S3Options struct {
+	Endpoint      string // 如 https://tos-s3-cn-beijing.volces.com 或 http://localhost:9000
+	Region        string
+	Bucket        string
+	AccessKey     string
+	SecretKey     string
+	UsePathStyle  bool
+	PublicBaseURL string // 为空时使用对象存储地址
+	HTTPClient    *http.Client
+}
+
+// S3Storage S3兼容对象存储，使用AWS Signature V4签名
+// 适用于AWS S3、火山引擎TOS、MinIO等兼容服务
+type S3Storage struct {
+	endpoint   *url.URL
+	opts       S3Options
+	httpClient *http.Client
+}
+
+// NewS3Storage 创建S3兼容对象存储
+func NewS3Storage(opts S3Options) (*S3Storage, error) {
+	if opts.Endpoint == "" || opts.Bucket == "" {
+		return nil, fmt.Errorf("S3存储需要配置endpoint和bucket")
+	}
+	endpoint, err := url.Parse(opts.Endpoint)
+	if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("无效的S3 endpoint: %s", opts.Endpoint)
+	}
+	if opts.Region == "" {
+		opts.Region = "us-east-1"
+	}
+
+	httpClient := opts.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 5 * time.Minute}
+	}
+
+	return &S3Storage{
+		endpoint:   endpoint,
+		opts:       opts,
+		httpClient: httpClient,
+	}, nil
+}
+
+// Put 上传对象
+func (s *S3Storage) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) (string, error) {
+	objectURL := s.objectURL(key)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, objectURL.String(), body)
+	if err != nil {
+		return "", fmt.Errorf("创建上传请求失败: %v", err)
+	}
+	req.ContentLength = size
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.sign(req, time.Now().UTC())
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("上传对象失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("上传对象失败: 状态码 %d, %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	if s.opts.PublicBaseURL != "" {
+		return joinURL(s.opts.PublicBaseURL, key), nil
+	}
+	return objectURL.String(), nil
+}
+
+// objectURL 构建对象访问地址
+func (s *S3Storage) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	escapedKey := escapeS3Path(strings.TrimLeft(key, "/"))
+	basePath := strings.TrimRight(u.Path, "/")
+
+	if s.opts.UsePathStyle {
+		u.Path = basePath + "/" + s.opts.Bucket + "/" + strings.TrimLeft(key, "/")
+		u.RawPath = basePath + "/" + escapeS3Path(s.opts.Bucket) + "/" + escapedKey
+	} else {
+		u.Host = s.opts.Bucket + "." + u.Host
+		u.Path = basePath + "/" + strings.TrimLeft(key, "/")
+		u.RawPath = basePath + "/" + escapedKey
+	}
+	return &u
+}
+
+// sign 为请求添加AWS Signature V4签名头
+func (s *S3Storage) sign(req *http.Request, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	shortDate := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", s3UnsignedPayload)
+
+	// 参与签名的请求头，按名称排序
+	signedHeaders := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	if req.Header.Get("Content-Type") != "" {
+		signedHeaders = append([]string{"content-type"}, signedHeaders...)
+	}
+
+	var canonicalHeaders strings.Builder
+	for _, name := range signedHeaders {
+		value := req.Header.Get(name)
+		if name == "host" {
+			value = req.URL.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		strings.Join(signedHeaders, ";"),
+		s3UnsignedPayload,
+	}, "\n")
+
+	scope := shortDate + "/" + s.opts.Region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+s.opts.SecretKey), shortDate)
+	signingKey = hmacSHA256(signingKey, s.opts.Region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.opts.AccessKey, scope, strings.Join(signedHeaders, ";"), signature,
+	))
+}
+
+// escapeS3Path 按S3签名规范对路径逐段编码，保留分隔符/
+func escapeS3Path(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = strings.ReplaceAll(url.QueryEscape(segment), "+", "%20")
+	}
+	return strings.Join(segments, "/")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"volcengine-go-server/config"
+)
+
+// Storage 生成结果存储接口
+// 实现需保证相同key重复写入时覆盖旧对象，以便任务重试时结果保持一致
+type Storage interface {
+	// Put 写入对象并返回可长期访问的URL
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) (string, error)
+}
+
+// New 根据配置创建存储实现
+func New(cfg config.StorageConfig) (Storage, error) {
+	switch cfg.Driver {
+	case config.StorageDriverLocal:
+		return NewLocalStorage(cfg.LocalDir, cfg.PublicBaseURL)
+	case config.StorageDriverS3:
+		return NewS3Storage(S3Options{
+			Endpoint:      cfg.S3Endpoint,
+			Region:        cfg.S3Region,
+			Bucket:        cfg.S3Bucket,
+			AccessKey:     cfg.S3AccessKey,
+			SecretKey:     cfg.S3SecretKey,
+			UsePathStyle:  cfg.S3UsePathStyle,
+			PublicBaseURL: cfg.PublicBaseURL,
+		})
+	default:
+		return nil, fmt.Errorf("不支持的存储驱动: %s", cfg.Driver)
+	}
+}
+
+// joinURL 拼接访问地址前缀与对象key
+func joinURL(baseURL, key string) string {
+	return strings.TrimRight(baseURL, "/") + "/" + strings.TrimLeft(key, "/")
+}
@@ -0,0 +1,208 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeS3Server 模拟MinIO的最小S3服务：校验签名后将对象保存在内存中
+type fakeS3Server struct {
+	accessKey string
+	secretKey string
+	region    string
+
+	mu      sync.Mutex
+	objects map[string][]byte
+	types   map[string]string
+}
+
+func newFakeS3Server(accessKey, secretKey, region string) *fakeS3Server {
+	return &fakeS3Server{
+		accessKey: accessKey,
+		secretKey: secretKey,
+		region:    region,
+		objects:   make(map[string][]byte),
+		types:     make(map[string]string),
+	}
+}
+
+func (f *fakeS3Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !f.verify(r) {
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, "SignatureDoesNotMatch")
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	f.mu.Lock()
+	f.objects[r.URL.Path] = body
+	f.types[r.URL.Path] = r.Header.Get("Content-Type")
+	f.mu.Unlock()
+	w.WriteHeader(http.StatusOK)
+}
+
+// verify 按服务端视角重新计算签名
+func (f *fakeS3Server) verify(r *http.Request) bool {
+	auth := r.Header.Get("Authorization")
+	amzDate := r.Header.Get("X-Amz-Date")
+	if len(amzDate) < 8 || !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 ") {
+		return false
+	}
+
+	var signedHeaders []string
+	for _, part := range strings.Split(strings.TrimPrefix(auth, "AWS4-HMAC-SHA256 "), ", ") {
+		if v, ok := strings.CutPrefix(part, "SignedHeaders="); ok {
+			signedHeaders = strings.Split(v, ";")
+		}
+	}
+
+	var canonicalHeaders strings.Builder
+	for _, name := range signedHeaders {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + value + "\n")
+	}
+
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		r.URL.RawQuery,
+		canonicalHeaders.String(),
+		strings.Join(signedHeaders, ";"),
+		r.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+
+	scope := amzDate[:8] + "/" + f.region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+f.secretKey), amzDate[:8])
+	key = hmacSHA256(key, f.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	expected := "AWS4-HMAC-SHA256 Credential=" + f.accessKey + "/" + scope +
+		", SignedHeaders=" + strings.Join(signedHeaders, ";") +
+		", Signature=" + hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	return auth == expected
+}
+
+func TestS3StoragePut(t *testing.T) {
+	fake := newFakeS3Server("minio", "minio-secret", "us-east-1")
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	store, err := NewS3Storage(S3Options{
+		Endpoint:     server.URL,
+		Region:       "us-east-1",
+		Bucket:       "media",
+		AccessKey:    "minio",
+		SecretKey:    "minio-secret",
+		UsePathStyle: true,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Storage() error = %v", err)
+	}
+
+	data := []byte("fake png data")
+	url, err := store.Put(context.Background(), "tasks/abc/0 final.png", bytes.NewReader(data), int64(len(data)), "image/png")
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	if want := server.URL + "/media/tasks/abc/0%20final.png"; url != want {
+		t.Errorf("Put() url = %s, 期望 %s", url, want)
+	}
+	if got := fake.objects["/media/tasks/abc/0 final.png"]; !bytes.Equal(got, data) {
+		t.Errorf("存储的对象内容 = %q, 期望 %q", got, data)
+	}
+	if got := fake.types["/media/tasks/abc/0 final.png"]; got != "image/png" {
+		t.Errorf("存储的Content-Type = %s, 期望 image/png", got)
+	}
+}
+
+func TestS3StoragePutRejected(t *testing.T) {
+	fake := newFakeS3Server("minio", "minio-secret", "us-east-1")
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	store, err := NewS3Storage(S3Options{
+		Endpoint:     server.URL,
+		Bucket:       "media",
+		AccessKey:    "minio",
+		SecretKey:    "wrong-secret",
+		UsePathStyle: true,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Storage() error = %v", err)
+	}
+
+	if _, err := store.Put(context.Background(), "tasks/abc/0.png", strings.NewReader("x"), 1, "image/png"); err == nil {
+		t.Error("签名错误时Put()应返回错误")
+	}
+}
+
+func TestS3StoragePublicBaseURL(t *testing.T) {
+	server := httptest.NewServer(newFakeS3Server("ak", "sk", "cn-beijing"))
+	defer server.Close()
+
+	store, err := NewS3Storage(S3Options{
+		Endpoint:      server.URL,
+		Region:        "cn-beijing",
+		Bucket:        "media",
+		AccessKey:     "ak",
+		SecretKey:     "sk",
+		UsePathStyle:  true,
+		PublicBaseURL: "https://cdn.example.com/",
+	})
+	if err != nil {
+		t.Fatalf("NewS3Storage() error = %v", err)
+	}
+
+	url, err := store.Put(context.Background(), "tasks/abc/0.mp4", strings.NewReader("v"), 1, "video/mp4")
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if want := "https://cdn.example.com/tasks/abc/0.mp4"; url != want {
+		t.Errorf("Put() url = %s, 期望 %s", url, want)
+	}
+}
+
+func TestLocalStoragePut(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewLocalStorage(dir, "http://localhost:8080/media/")
+	if err != nil {
+		t.Fatalf("NewLocalStorage() error = %v", err)
+	}
+
+	url, err := store.Put(context.Background(), "tasks/abc/0.png", strings.NewReader("png"), 3, "image/png")
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if want := "http://localhost:8080/media/tasks/abc/0.png"; url != want {
+		t.Errorf("Put() url = %s, 期望 %s", url, want)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dir, "tasks", "abc", "0.png"))
+	if err != nil || string(got) != "png" {
+		t.Errorf("读取存储文件 = %q, %v", got, err)
+	}
+
+	if _, err := store.Put(context.Background(), "../escape.png", strings.NewReader("x"), 1, "image/png"); err == nil {
+		t.Error("跳出存储目录的key应返回错误")
+	}
+}
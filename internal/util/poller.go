@@ -98,6 +98,11 @@ func calculateWaitInterval(attempt int) time.Duration {
 	return interval
 }
 
+// NextPollInterval 获取第attempt次查询后的等待间隔，供按次重新入队的状态检查任务使用
+func NextPollInterval(attempt int) time.Duration {
+	return calculateWaitInterval(attempt)
+}
+
 // PollTaskResult 通用任务轮询方法
 // 使用自适应轮询策略：前期快速轮询，后期逐渐增加间隔
 func PollTaskResult(ctx context.Context, taskID string, checker TaskResultChecker, config *PollConfig) (interface{}, error) {
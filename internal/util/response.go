@@ -59,6 +59,24 @@ func BadRequestResponse(c *gin.Context, error string, message string) {
 	})
 }
 
+// UnauthorizedResponse 未认证响应 (401)
+func UnauthorizedResponse(c *gin.Context, error string, message string) {
+	c.JSON(http.StatusUnauthorized, Response{
+		Success: false,
+		Error:   error,
+		Message: message,
+	})
+}
+
+// ForbiddenResponse 无权限响应 (403)
+func ForbiddenResponse(c *gin.Context, error string, message string) {
+	c.JSON(http.StatusForbidden, Response{
+		Success: false,
+		Error:   error,
+		Message: message,
+	})
+}
+
 // NotFoundResponse 未找到响应 (404)
 func NotFoundResponse(c *gin.Context, error string, message string) {
 	c.JSON(http.StatusNotFound, Response{
@@ -2,6 +2,7 @@ package util
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
@@ -56,6 +57,8 @@ func getValidationMessage(fe validator.FieldError) string {
 		return fmt.Sprintf("%s 长度不能超过 %s 个字符", field, fe.Param())
 	case "len":
 		return fmt.Sprintf("%s 长度必须为 %s 个字符", field, fe.Param())
+	case "url":
+		return fmt.Sprintf("%s 必须是有效的URL", field)
 	case "numeric":
 		return fmt.Sprintf("%s 必须是数字", field)
 	case "alpha":
@@ -66,3 +69,12 @@ func getValidationMessage(fe validator.FieldError) string {
 		return fmt.Sprintf("%s 验证失败", field)
 	}
 }
+
+// JoinValidationErrors 将验证错误合并为一条信息，用于无法返回字段详情的场景
+func JoinValidationErrors(errs []ValidationError) string {
+	messages := make([]string, len(errs))
+	for i, e := range errs {
+		messages[i] = e.Message
+	}
+	return strings.Join(messages, "; ")
+}
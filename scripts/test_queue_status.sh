@@ -14,6 +14,9 @@ NC='\033[0m' # No Color
 
 API_BASE="http://localhost:8080/api/v1"
 
+# 管理员密钥，用于创建测试用户和签发API密钥
+ADMIN_API_KEY="${ADMIN_API_KEY:?请设置ADMIN_API_KEY环境变量}"
+
 echo -e "${BLUE}🧪 测试队列状态处理${NC}"
 echo "================================"
 
@@ -35,6 +38,7 @@ create_test_user() {
     echo -e "${BLUE}👤 创建测试用户...${NC}"
     
     USER_RESPONSE=$(curl -s -X POST "$API_BASE/users" \
+        -H "Authorization: Bearer $ADMIN_API_KEY" \
         -H "Content-Type: application/json" \
         -d '{
             "email": "test@queue-status.com",
@@ -50,6 +54,21 @@ create_test_user() {
     fi
     
     echo -e "${GREEN}✅ 用户创建成功: $USER_ID${NC}"
+
+    KEY_RESPONSE=$(curl -s -X POST "$API_BASE/users/$USER_ID/api-keys" \
+        -H "Authorization: Bearer $ADMIN_API_KEY" \
+        -H "Content-Type: application/json" \
+        -d '{"name": "queue-status-test"}')
+
+    USER_API_KEY=$(echo "$KEY_RESPONSE" | grep -o '"api_key":"[^"]*"' | cut -d'"' -f4)
+
+    if [ -z "$USER_API_KEY" ]; then
+        echo -e "${RED}❌ 创建API密钥失败${NC}"
+        echo "响应: $KEY_RESPONSE"
+        exit 1
+    fi
+
+    echo -e "${GREEN}✅ API密钥创建成功${NC}"
 }
 
 # 测试正常任务
@@ -57,10 +76,10 @@ test_normal_task() {
     echo -e "${BLUE}✅ 测试正常任务处理...${NC}"
     
     TASK_RESPONSE=$(curl -s -X POST "$API_BASE/ai/image/task" \
+        -H "Authorization: Bearer $USER_API_KEY" \
         -H "Content-Type: application/json" \
         -d '{
             "prompt": "一只可爱的小猫咪",
-            "user_id": "'$USER_ID'",
             "model": "doubao-seedream-3.0-t2i",
             "size": "1024x1024"
         }')
@@ -82,10 +101,10 @@ test_invalid_provider_task() {
     
     # 创建一个使用无效提供商的任务
     TASK_RESPONSE=$(curl -s -X POST "$API_BASE/ai/tasks" \
+        -H "Authorization: Bearer $USER_API_KEY" \
         -H "Content-Type: application/json" \
         -d '{
             "prompt": "测试无效提供商",
-            "user_id": "'$USER_ID'",
             "type": "image",
             "provider": "invalid_provider",
             "model": "test-model"
@@ -112,7 +131,16 @@ wait_for_processing() {
 check_queue_status() {
     echo -e "${BLUE}📊 检查队列状态...${NC}"
     
-    ./scripts/clear_redis_queue.sh --show-only
+    curl -s "$API_BASE/admin/queues" \
+        -H "Authorization: Bearer $ADMIN_API_KEY"
+    echo ""
+
+    echo -e "${BLUE}📦 已归档任务...${NC}"
+    for queue in critical default low; do
+        curl -s "$API_BASE/admin/queues/$queue/tasks?state=archived" \
+            -H "Authorization: Bearer $ADMIN_API_KEY"
+        echo ""
+    done
 }
 
 # 清理测试数据
@@ -120,7 +148,8 @@ cleanup() {
     echo -e "${BLUE}🧹 清理测试数据...${NC}"
     
     if [ -n "$USER_ID" ]; then
-        curl -s -X DELETE "$API_BASE/users/$USER_ID" > /dev/null || true
+        curl -s -X DELETE "$API_BASE/users/$USER_ID" \
+            -H "Authorization: Bearer $ADMIN_API_KEY" > /dev/null || true
         echo -e "${GREEN}✅ 测试用户已删除${NC}"
     fi
 }